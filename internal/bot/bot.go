@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/llm"
+)
+
+type Role string
+
+const (
+	RoleUser   Role = "user"
+	RoleBot    Role = "bot"
+	RoleAgent  Role = "agent"
+	RoleSystem Role = "system"
+)
+
+type Message struct {
+	Role    Role
+	Content string
+}
+
+// Conversation is the context handed to a Responder: the conversation being
+// answered and its most recent messages, oldest first, not including the
+// message being replied to.
+type Conversation struct {
+	ID      int64
+	UserID  int64
+	History []Message
+}
+
+type Reply struct {
+	Content string
+	Usage   *llm.Usage
+}
+
+type Responder interface {
+	Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error)
+}
+
+type Options struct {
+	Responder string
+	RulesFile string
+	LLM       LLMResponder
+}
+
+func New(opts Options) (Responder, error) {
+	switch opts.Responder {
+	case "echo":
+		return EchoResponder{}, nil
+	case "llm":
+		if opts.LLM.Provider == nil {
+			return nil, errors.New("Responder llm requer um provedor de LLM configurado")
+		}
+		return opts.LLM, nil
+	case "rules":
+		if opts.RulesFile == "" {
+			return NewRuleResponder(DefaultRules, DefaultFallback), nil
+		}
+
+		file, err := os.Open(opts.RulesFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		return LoadRules(file)
+	default:
+		return nil, fmt.Errorf("Responder de bot desconhecido: %s", opts.Responder)
+	}
+}
@@ -0,0 +1,12 @@
+package bot
+
+import "context"
+
+// EchoResponder replies with the user's message unchanged. It is
+// deterministic and has no dependencies, which makes it useful in tests and
+// local development.
+type EchoResponder struct{}
+
+func (EchoResponder) Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error) {
+	return []Reply{{Content: message}}, nil
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+)
+
+// Article is a knowledge base entry that answers a question, scored by how
+// well it matches the message being answered.
+type Article struct {
+	ID       int64
+	Question string
+	Answer   string
+	Score    float64
+}
+
+// KnowledgeBase finds the articles that best answer a message, best first.
+type KnowledgeBase interface {
+	Search(ctx context.Context, message string, limit int) ([]Article, error)
+}
+
+// FAQResponder answers with the best matching knowledge base article when its
+// score reaches Threshold, and hands the message to Fallback otherwise.
+type FAQResponder struct {
+	KnowledgeBase KnowledgeBase
+	Threshold     float64
+	Fallback      Responder
+}
+
+func (fr FAQResponder) Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error) {
+	article, ok, err := fr.best(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return []Reply{{Content: article.Answer}}, nil
+	}
+	return fr.Fallback.Respond(ctx, conversation, message)
+}
+
+func (fr FAQResponder) RespondStream(ctx context.Context, conversation Conversation, message string, onChunk func(index int, chunk string) error) ([]Reply, error) {
+	article, ok, err := fr.best(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if err := onChunk(0, article.Answer); err != nil {
+			return nil, err
+		}
+		return []Reply{{Content: article.Answer}}, nil
+	}
+	return Stream(ctx, fr.Fallback, conversation, message, onChunk)
+}
+
+func (fr FAQResponder) best(ctx context.Context, message string) (Article, bool, error) {
+	articles, err := fr.KnowledgeBase.Search(ctx, message, 1)
+	if err != nil {
+		return Article{}, false, err
+	}
+	if len(articles) == 0 || articles[0].Score < fr.Threshold {
+		return Article{}, false, nil
+	}
+	return articles[0], true, nil
+}
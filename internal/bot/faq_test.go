@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeKnowledgeBase struct {
+	articles []Article
+	err      error
+}
+
+func (kb fakeKnowledgeBase) Search(ctx context.Context, message string, limit int) ([]Article, error) {
+	if len(kb.articles) > limit {
+		return kb.articles[:limit], kb.err
+	}
+	return kb.articles, kb.err
+}
+
+func TestFAQResponder(t *testing.T) {
+	article := Article{ID: 1, Question: "Como trocar a senha?", Answer: "Acesse o seu perfil.", Score: 0.25}
+
+	tests := []struct {
+		name     string
+		articles []Article
+		want     string
+	}{
+		{"score above the threshold", []Article{article}, "Acesse o seu perfil."},
+		{"score at the threshold", []Article{{Answer: "No limite.", Score: 0.2}}, "No limite."},
+		{"score below the threshold", []Article{{Answer: "Fraca.", Score: 0.19}}, "trocar senha"},
+		{"no article", nil, "trocar senha"},
+		{"only the best article counts", []Article{{Answer: "Fraca.", Score: 0.1}, article}, "trocar senha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fr := FAQResponder{
+				KnowledgeBase: fakeKnowledgeBase{articles: tt.articles},
+				Threshold:     0.2,
+				Fallback:      EchoResponder{},
+			}
+
+			replies, err := fr.Respond(context.Background(), Conversation{}, "trocar senha")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(replies) != 1 || replies[0].Content != tt.want {
+				t.Errorf("Respond = %+v, want %q", replies, tt.want)
+			}
+
+			chunks := ""
+			replies, err = fr.RespondStream(context.Background(), Conversation{}, "trocar senha", func(index int, chunk string) error {
+				chunks += chunk
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(replies) != 1 || replies[0].Content != tt.want || chunks != tt.want {
+				t.Errorf("RespondStream = %+v streaming %q, want %q", replies, chunks, tt.want)
+			}
+		})
+	}
+}
+
+func TestFAQResponderSearchError(t *testing.T) {
+	failure := errors.New("busca indisponível")
+	fr := FAQResponder{
+		KnowledgeBase: fakeKnowledgeBase{err: failure},
+		Fallback:      EchoResponder{},
+	}
+
+	if _, err := fr.Respond(context.Background(), Conversation{}, "oi"); !errors.Is(err, failure) {
+		t.Errorf("error = %v, want %v", err, failure)
+	}
+}
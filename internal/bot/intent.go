@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+)
+
+// Intent is the intent recognized in a message, with the confidence of the
+// classifier and the replies configured for it.
+type Intent struct {
+	Name       string
+	Confidence float64
+	Replies    []string
+}
+
+// IntentClassifier recognizes the intent of a message. ok is false when no
+// intent can be told, such as before a model is trained or when the message
+// has no known word.
+type IntentClassifier interface {
+	Classify(ctx context.Context, message string) (intent Intent, ok bool, err error)
+}
+
+// IntentResponder answers with the replies of the recognized intent when its
+// confidence reaches Threshold, and hands the message to Fallback otherwise
+// or when the intent has no replies.
+type IntentResponder struct {
+	Classifier IntentClassifier
+	Threshold  float64
+	Fallback   Responder
+}
+
+func (ir IntentResponder) Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error) {
+	intent, ok, err := ir.recognize(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return textReplies(intent.Replies), nil
+	}
+	return ir.Fallback.Respond(ctx, conversation, message)
+}
+
+func (ir IntentResponder) RespondStream(ctx context.Context, conversation Conversation, message string, onChunk func(index int, chunk string) error) ([]Reply, error) {
+	intent, ok, err := ir.recognize(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for i, reply := range intent.Replies {
+			if err := onChunk(i, reply); err != nil {
+				return nil, err
+			}
+		}
+		return textReplies(intent.Replies), nil
+	}
+	return Stream(ctx, ir.Fallback, conversation, message, onChunk)
+}
+
+func (ir IntentResponder) recognize(ctx context.Context, message string) (Intent, bool, error) {
+	intent, ok, err := ir.Classifier.Classify(ctx, message)
+	if err != nil || !ok {
+		return Intent{}, false, err
+	}
+	if intent.Confidence < ir.Threshold || len(intent.Replies) == 0 {
+		return Intent{}, false, nil
+	}
+	return intent, true, nil
+}
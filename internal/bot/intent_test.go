@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeIntentClassifier struct {
+	intent Intent
+	ok     bool
+	err    error
+}
+
+func (fc fakeIntentClassifier) Classify(ctx context.Context, message string) (Intent, bool, error) {
+	return fc.intent, fc.ok, fc.err
+}
+
+func TestIntentResponder(t *testing.T) {
+	replies := []string{"Para cancelar, acesse Meus pedidos.", "Posso ajudar em algo mais?"}
+
+	tests := []struct {
+		name   string
+		intent Intent
+		ok     bool
+		want   []string
+	}{
+		{"confidence above the threshold", Intent{"cancelar", 0.9, replies}, true, replies},
+		{"confidence at the threshold", Intent{"cancelar", 0.6, replies}, true, replies},
+		{"confidence below the threshold", Intent{"cancelar", 0.59, replies}, true, []string{"cancelar"}},
+		{"intent without replies", Intent{"cancelar", 0.9, nil}, true, []string{"cancelar"}},
+		{"no intent", Intent{}, false, []string{"cancelar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ir := IntentResponder{
+				Classifier: fakeIntentClassifier{intent: tt.intent, ok: tt.ok},
+				Threshold:  0.6,
+				Fallback:   EchoResponder{},
+			}
+
+			got, err := ir.Respond(context.Background(), Conversation{}, "cancelar")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(contents(got), tt.want) {
+				t.Errorf("Respond = %q, want %q", contents(got), tt.want)
+			}
+
+			chunks := []string{}
+			got, err = ir.RespondStream(context.Background(), Conversation{}, "cancelar", func(index int, chunk string) error {
+				chunks = append(chunks, chunk)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(contents(got), tt.want) || !reflect.DeepEqual(chunks, tt.want) {
+				t.Errorf("RespondStream = %q with chunks %q, want %q", contents(got), chunks, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntentResponderClassifierError(t *testing.T) {
+	ir := IntentResponder{
+		Classifier: fakeIntentClassifier{err: errors.New("falhou")},
+		Fallback:   EchoResponder{},
+	}
+
+	if _, err := ir.Respond(context.Background(), Conversation{}, "oi"); err == nil {
+		t.Error("expected the classifier error")
+	}
+}
+
+func contents(replies []Reply) []string {
+	texts := []string{}
+	for _, reply := range replies {
+		texts = append(texts, reply.Content)
+	}
+	return texts
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/llm"
+)
+
+// LLMResponder answers with a completion from a large language model, sending
+// the conversation history so the model can keep track of the dialog.
+type LLMResponder struct {
+	Provider     llm.Provider
+	Model        string
+	SystemPrompt string
+	MaxTokens    int
+	Temperature  float64
+}
+
+func (lr LLMResponder) Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error) {
+	response, err := lr.Provider.Complete(ctx, lr.request(conversation, message))
+	if err != nil {
+		return nil, err
+	}
+
+	usage := response.Usage
+	return []Reply{{Content: response.Content, Usage: &usage}}, nil
+}
+
+func (lr LLMResponder) request(conversation Conversation, message string) llm.Request {
+	messages := make([]llm.Message, 0, len(conversation.History)+1)
+	for _, m := range conversation.History {
+		switch m.Role {
+		case RoleUser:
+			messages = append(messages, llm.Message{Role: llm.RoleUser, Content: m.Content})
+		case RoleBot, RoleAgent:
+			messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: m.Content})
+		}
+	}
+	messages = append(messages, llm.Message{Role: llm.RoleUser, Content: message})
+
+	return llm.Request{
+		Model:        lr.Model,
+		SystemPrompt: lr.SystemPrompt,
+		Messages:     messages,
+		MaxTokens:    lr.MaxTokens,
+		Temperature:  lr.Temperature,
+	}
+}
@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type Rule struct {
+	Name     string   `json:"name"`
+	Keywords []string `json:"keywords,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+	Priority int      `json:"priority"`
+	Replies  []string `json:"replies"`
+
+	rx *regexp.Regexp
+}
+
+// RuleResponder answers with the replies of the highest priority rule that
+// matches the message. A rule matches when any of its keywords appears as a
+// whole word (case insensitive) or when its regular expression matches. Rules
+// with the same priority are tried in the order they were declared.
+type RuleResponder struct {
+	rules    []Rule
+	fallback []string
+}
+
+func NewRuleResponder(rules []Rule, fallback []string) *RuleResponder {
+	sorted := make([]Rule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+
+	for i := range sorted {
+		if sorted[i].Pattern != "" && sorted[i].rx == nil {
+			sorted[i].rx = regexp.MustCompile(sorted[i].Pattern)
+		}
+	}
+
+	return &RuleResponder{rules: sorted, fallback: fallback}
+}
+
+type rulesFile struct {
+	Rules    []Rule   `json:"rules"`
+	Fallback []string `json:"fallback"`
+}
+
+func LoadRules(r io.Reader) (*RuleResponder, error) {
+	file := rulesFile{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("Arquivo de regras inválido: %v", err)
+	}
+
+	for i, rule := range file.Rules {
+		if len(rule.Replies) == 0 {
+			return nil, fmt.Errorf("Regra %q não possui respostas", rule.Name)
+		}
+		if len(rule.Keywords) == 0 && rule.Pattern == "" {
+			return nil, fmt.Errorf("Regra %q não possui palavras-chave nem padrão", rule.Name)
+		}
+		if rule.Pattern != "" {
+			rx, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("Regra %q possui padrão inválido: %v", rule.Name, err)
+			}
+			file.Rules[i].rx = rx
+		}
+	}
+
+	if len(file.Fallback) == 0 {
+		return nil, errors.New("Arquivo de regras deve definir ao menos uma resposta padrão")
+	}
+
+	return NewRuleResponder(file.Rules, file.Fallback), nil
+}
+
+func (rr *RuleResponder) Respond(ctx context.Context, conversation Conversation, message string) ([]Reply, error) {
+	rule, ok := rr.Match(message)
+	if !ok {
+		return textReplies(rr.fallback), nil
+	}
+	return textReplies(rule.Replies), nil
+}
+
+func (rr *RuleResponder) Match(message string) (Rule, bool) {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(message), isSeparator) {
+		words[word] = true
+	}
+	lower := strings.ToLower(message)
+
+	for _, rule := range rr.rules {
+		if rule.rx != nil && rule.rx.MatchString(message) {
+			return rule, true
+		}
+		for _, keyword := range rule.Keywords {
+			keyword = strings.ToLower(keyword)
+			if strings.ContainsFunc(keyword, isSeparator) {
+				if strings.Contains(lower, keyword) {
+					return rule, true
+				}
+			} else if words[keyword] {
+				return rule, true
+			}
+		}
+	}
+
+	return Rule{}, false
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func textReplies(texts []string) []Reply {
+	replies := make([]Reply, 0, len(texts))
+	for _, text := range texts {
+		replies = append(replies, Reply{Content: text})
+	}
+	return replies
+}
+
+var DefaultFallback = []string{
+	"Desculpe, não entendi. Pode reformular a sua pergunta?",
+}
+
+var DefaultRules = []Rule{
+	{
+		Name:     "saudacao",
+		Keywords: []string{"oi", "olá", "ola", "bom dia", "boa tarde", "boa noite"},
+		Priority: 10,
+		Replies:  []string{"Olá! Como posso ajudar?"},
+	},
+	{
+		Name:     "despedida",
+		Keywords: []string{"tchau", "até logo", "ate logo", "adeus"},
+		Priority: 10,
+		Replies:  []string{"Até logo! Se precisar, é só chamar."},
+	},
+	{
+		Name:     "agradecimento",
+		Keywords: []string{"obrigado", "obrigada", "valeu"},
+		Priority: 5,
+		Replies:  []string{"Por nada! Posso ajudar com mais alguma coisa?"},
+	},
+	{
+		Name:     "atendente",
+		Pattern:  `(?i)\b(atendente|humano|pessoa)\b`,
+		Priority: 20,
+		Replies:  []string{"Entendi, vou encaminhar você para um de nossos atendentes."},
+	},
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRuleResponderMatch(t *testing.T) {
+	rules := []Rule{
+		{Name: "saudacao", Keywords: []string{"oi", "bom dia"}, Priority: 10, Replies: []string{"Olá!"}},
+		{Name: "pedido", Keywords: []string{"pedido"}, Priority: 5, Replies: []string{"Qual o número do pedido?"}},
+		{Name: "rastreio", Pattern: `(?i)\brastre(ar|io)\b`, Priority: 5, Replies: []string{"Informe o código de rastreio."}},
+		{Name: "atendente", Pattern: `(?i)\batendente\b`, Priority: 20, Replies: []string{"Vou chamar um atendente."}},
+		{Name: "pedido-duplicado", Keywords: []string{"pedido"}, Priority: 5, Replies: []string{"Nunca escolhida."}},
+	}
+	rr := NewRuleResponder(rules, []string{"Não entendi."})
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{"keyword as a whole word", "Oi, tudo bem?", "saudacao"},
+		{"keyword inside another word", "oitenta reais", ""},
+		{"multi-word keyword", "BOM DIA!", "saudacao"},
+		{"regular expression", "quero rastrear a encomenda", "rastreio"},
+		{"regular expression without match", "rastreamento", ""},
+		{"higher priority wins", "oi, quero falar com um atendente", "atendente"},
+		{"higher priority wins over declaration order", "oi, meu pedido", "saudacao"},
+		{"same priority keeps declaration order", "pedido para rastrear", "pedido"},
+		{"no match", "qual a previsão do tempo?", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := rr.Match(tt.message)
+			if ok != (tt.want != "") || rule.Name != tt.want {
+				t.Errorf("Match(%q) = %q, %v; want %q", tt.message, rule.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleResponderRespond(t *testing.T) {
+	rr := NewRuleResponder(DefaultRules, DefaultFallback)
+
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"olá", []string{"Olá! Como posso ajudar?"}},
+		{"obrigado, tchau", []string{"Até logo! Se precisar, é só chamar."}},
+		{"quero falar com um humano, obrigado", []string{"Entendi, vou encaminhar você para um de nossos atendentes."}},
+		{"xyz", DefaultFallback},
+	}
+
+	for _, tt := range tests {
+		replies, err := rr.Respond(context.Background(), Conversation{}, tt.message)
+		if err != nil {
+			t.Fatalf("Respond(%q): %v", tt.message, err)
+		}
+
+		got := make([]string, len(replies))
+		for i, reply := range replies {
+			got[i] = reply.Content
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Respond(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{"valid", `{"rules":[{"name":"a","keywords":["x"],"replies":["y"]}],"fallback":["?"]}`, ""},
+		{"unknown field", `{"rules":[],"fallback":["?"],"extra":1}`, "Arquivo de regras inválido"},
+		{"rule without replies", `{"rules":[{"name":"a","keywords":["x"]}],"fallback":["?"]}`, "não possui respostas"},
+		{"rule without keywords or pattern", `{"rules":[{"name":"a","replies":["y"]}],"fallback":["?"]}`, "nem padrão"},
+		{"invalid pattern", `{"rules":[{"name":"a","pattern":"(","replies":["y"]}],"fallback":["?"]}`, "padrão inválido"},
+		{"no fallback", `{"rules":[{"name":"a","keywords":["x"],"replies":["y"]}]}`, "resposta padrão"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRules(strings.NewReader(tt.file))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/llm"
+)
+
+// StreamResponder is implemented by responders able to produce their replies
+// incrementally. onChunk receives the index of the reply being produced and
+// the next piece of its text.
+type StreamResponder interface {
+	Responder
+	RespondStream(ctx context.Context, conversation Conversation, message string, onChunk func(index int, chunk string) error) ([]Reply, error)
+}
+
+// Stream generates the replies to message, reporting them through onChunk as
+// they are produced. Responders that cannot stream have each complete reply
+// reported as a single chunk.
+func Stream(ctx context.Context, r Responder, conversation Conversation, message string, onChunk func(index int, chunk string) error) ([]Reply, error) {
+	if sr, ok := r.(StreamResponder); ok {
+		return sr.RespondStream(ctx, conversation, message, onChunk)
+	}
+
+	replies, err := r.Respond(ctx, conversation, message)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, reply := range replies {
+		if err := onChunk(i, reply.Content); err != nil {
+			return nil, err
+		}
+	}
+	return replies, nil
+}
+
+func (lr LLMResponder) RespondStream(ctx context.Context, conversation Conversation, message string, onChunk func(index int, chunk string) error) ([]Reply, error) {
+	sp, ok := lr.Provider.(llm.StreamingProvider)
+	if !ok {
+		return Stream(ctx, nonStreaming{lr}, conversation, message, onChunk)
+	}
+
+	response, err := sp.Stream(ctx, lr.request(conversation, message), func(delta string) error {
+		return onChunk(0, delta)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := response.Usage
+	return []Reply{{Content: response.Content, Usage: &usage}}, nil
+}
+
+// nonStreaming hides the RespondStream method of the wrapped responder.
+type nonStreaming struct {
+	Responder
+}
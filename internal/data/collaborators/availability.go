@@ -0,0 +1,157 @@
+package collaborators
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pedro-git-projects/chatbot-back/internal/routing"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type Status string
+
+const (
+	StatusOnline  Status = "online"
+	StatusAway    Status = "away"
+	StatusOffline Status = "offline"
+)
+
+const DefaultCapacity = 5
+
+type Availability struct {
+	UserID    int64     `json:"user_id,string"`
+	Status    Status    `json:"status"`
+	Capacity  int       `json:"capacity"`
+	Skills    []string  `json:"skills"`
+	Open      int       `json:"open_conversations"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateAvailabilityDTO struct {
+	Status   Status    `json:"status"`
+	Capacity *int      `json:"capacity,omitempty"`
+	Skills   *[]string `json:"skills,omitempty"`
+}
+
+func (dto UpdateAvailabilityDTO) Validate(v *validator.Validator) {
+	v.Check(validator.In(string(dto.Status), string(StatusOnline), string(StatusAway), string(StatusOffline)),
+		"status", "deve ser uma das opções (online|away|offline)")
+
+	if dto.Capacity != nil {
+		v.Check(*dto.Capacity >= 0, "capacity", "não deve ser negativa")
+		v.Check(*dto.Capacity <= 100, "capacity", "deve ser no máximo 100")
+	}
+
+	if dto.Skills != nil {
+		v.Check(len(*dto.Skills) <= 20, "skills", "não deve ter mais de 20 itens")
+		v.Check(validator.Unique(*dto.Skills), "skills", "não deve conter valores duplicados")
+	}
+}
+
+type AvailabilityModel struct {
+	DB *sql.DB
+}
+
+// Get returns the availability of the collaborator. Collaborators who never
+// set it are reported offline with the default capacity.
+func (m AvailabilityModel) Get(userID int64) (*Availability, error) {
+	query := `
+		SELECT a.status, a.capacity, a.skills, a.updated_at,
+			(SELECT count(*) FROM assignments WHERE user_id = $1 AND released_at IS NULL)
+		FROM (SELECT $1::integer AS user_id) u
+		LEFT JOIN collaborator_availability a ON a.user_id = u.user_id
+	`
+
+	a := Availability{UserID: userID}
+	var (
+		status    sql.NullString
+		capacity  sql.NullInt64
+		updatedAt sql.NullTime
+		skills    pq.StringArray
+	)
+
+	err := m.DB.QueryRow(query, userID).Scan(&status, &capacity, &skills, &updatedAt, &a.Open)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	a.Status = StatusOffline
+	a.Capacity = DefaultCapacity
+	a.Skills = []string{}
+	if status.Valid {
+		a.Status = Status(status.String)
+		a.Capacity = int(capacity.Int64)
+		a.Skills = skills
+		a.UpdatedAt = updatedAt.Time
+	}
+
+	return &a, nil
+}
+
+func (m AvailabilityModel) Update(userID int64, dto UpdateAvailabilityDTO) (*Availability, error) {
+	current, err := m.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := current.Capacity
+	if dto.Capacity != nil {
+		capacity = *dto.Capacity
+	}
+	skills := current.Skills
+	if dto.Skills != nil {
+		skills = *dto.Skills
+	}
+
+	query := `
+		INSERT INTO collaborator_availability (user_id, status, capacity, skills)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET status = EXCLUDED.status, capacity = EXCLUDED.capacity, skills = EXCLUDED.skills, updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err = m.DB.Exec(query, userID, dto.Status, capacity, pq.Array(skills))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao atualizar disponibilidade: %v", err))
+	}
+
+	return m.Get(userID)
+}
+
+// Candidates returns every online collaborator whose account is active,
+// together with their open assignment count.
+func (m AvailabilityModel) Candidates() ([]routing.Candidate, error) {
+	query := `
+		SELECT a.user_id, a.capacity, a.skills, a.last_assigned_at,
+			(SELECT count(*) FROM assignments s WHERE s.user_id = a.user_id AND s.released_at IS NULL)
+		FROM collaborator_availability a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.status = 'online' AND u.disabled_at IS NULL AND u.role IN ('collaborator', 'admin')
+	`
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	candidates := []routing.Candidate{}
+	for rows.Next() {
+		c := routing.Candidate{}
+		var skills pq.StringArray
+		var lastAssignedAt sql.NullTime
+
+		err := rows.Scan(&c.UserID, &c.Capacity, &skills, &lastAssignedAt, &c.Open)
+		if err != nil {
+			return nil, err
+		}
+		c.Skills = skills
+		c.LastAssignedAt = lastAssignedAt.Time
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
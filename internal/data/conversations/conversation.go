@@ -0,0 +1,47 @@
+package conversations
+
+import "time"
+
+type Status string
+
+const (
+	StatusOpen   Status = "open"
+	StatusClosed Status = "closed"
+)
+
+type Sender string
+
+const (
+	SenderUser   Sender = "user"
+	SenderBot    Sender = "bot"
+	SenderAgent  Sender = "agent"
+	SenderSystem Sender = "system"
+)
+
+type Conversation struct {
+	ID        int64     `json:"id,string"`
+	UserID    int64     `json:"user_id,string"`
+	Title     string    `json:"title"`
+	Status    Status    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Message struct {
+	ID             int64     `json:"id,string"`
+	ConversationID int64     `json:"conversation_id,string"`
+	Sender         Sender    `json:"sender"`
+	UserID         *int64    `json:"user_id,omitempty,string"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+	Usage          *Usage    `json:"usage,omitempty"`
+}
+
+// Usage records the model, token counts and cost of a message generated by a
+// large language model.
+type Usage struct {
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
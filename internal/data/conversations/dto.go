@@ -0,0 +1,35 @@
+package conversations
+
+import (
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type CreateConversationDTO struct {
+	Title string `json:"title,omitempty"`
+}
+
+func (dto CreateConversationDTO) Validate(v *validator.Validator) {
+	v.Check(utf8.RuneCountInString(dto.Title) <= 255, "title", "não deve ter mais de 255 caracteres")
+}
+
+type CreateMessageDTO struct {
+	Content string `json:"content"`
+}
+
+func (dto CreateMessageDTO) Validate(v *validator.Validator) {
+	v.Check(dto.Content != "", "content", "é obrigatório")
+	v.Check(utf8.RuneCountInString(dto.Content) <= 4000, "content", "não deve ter mais de 4000 caracteres")
+}
+
+type BotReplyDTO struct {
+	ConversationID int64  `json:"conversationId,string"`
+	Message        string `json:"message"`
+}
+
+func (dto BotReplyDTO) Validate(v *validator.Validator) {
+	v.Check(dto.ConversationID > 0, "conversationId", "é obrigatório")
+	v.Check(dto.Message != "", "message", "é obrigatória")
+	v.Check(utf8.RuneCountInString(dto.Message) <= 4000, "message", "não deve ter mais de 4000 caracteres")
+}
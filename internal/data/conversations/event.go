@@ -0,0 +1,84 @@
+package conversations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventMessage    EventType = "message"
+	EventReplyChunk EventType = "reply.chunk"
+	EventReplyError EventType = "reply.error"
+	EventRead       EventType = "read"
+
+	EventHandoffQueued EventType = "handoff.queued"
+	EventHandoffClosed EventType = "handoff.closed"
+	EventAgentJoined   EventType = "agent.joined"
+	EventAgentLeft     EventType = "agent.left"
+)
+
+// Event is an entry of a conversation's event log. The log is what real-time
+// clients consume, and its IDs are what they use to resume after a
+// disconnection.
+type Event struct {
+	ID             int64           `json:"id,string"`
+	ConversationID int64           `json:"conversation_id,string"`
+	Type           EventType       `json:"type"`
+	Data           json.RawMessage `json:"data"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type EventModel struct {
+	DB *sql.DB
+}
+
+func (m EventModel) Insert(event *Event) error {
+	query := `
+		INSERT INTO conversation_events (conversation_id, type, data)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := m.DB.QueryRow(query, event.ConversationID, event.Type, []byte(event.Data)).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao inserir evento: %v", err))
+	}
+	return nil
+}
+
+// ListAfter returns up to limit events of the conversation with an ID greater
+// than after, oldest first.
+func (m EventModel) ListAfter(conversationID, after int64, limit int) ([]*Event, error) {
+	query := `
+		SELECT id, conversation_id, type, data, created_at
+		FROM conversation_events
+		WHERE conversation_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
+	`
+
+	rows, err := m.DB.Query(query, conversationID, after, limit)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Event{}
+	for rows.Next() {
+		event := Event{}
+		var data []byte
+		err := rows.Scan(&event.ID, &event.ConversationID, &event.Type, &data, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		event.Data = data
+		list = append(list, &event)
+	}
+
+	return list, rows.Err()
+}
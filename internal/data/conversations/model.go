@@ -0,0 +1,223 @@
+package conversations
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrConversationNotFound = errors.New("Conversa não encontrada")
+	ErrMessageNotFound      = errors.New("Mensagem não encontrada")
+)
+
+type ConversationModel struct {
+	DB *sql.DB
+}
+
+func (m ConversationModel) Insert(conversation *Conversation) error {
+	query := `
+		INSERT INTO conversations (user_id, title)
+		VALUES ($1, $2)
+		RETURNING id, status, created_at, updated_at
+	`
+
+	return m.DB.QueryRow(query, conversation.UserID, conversation.Title).
+		Scan(&conversation.ID, &conversation.Status, &conversation.CreatedAt, &conversation.UpdatedAt)
+}
+
+func (m ConversationModel) Get(id int64) (*Conversation, error) {
+	query := `
+		SELECT id, user_id, title, status, created_at, updated_at
+		FROM conversations
+		WHERE id = $1
+	`
+
+	conversation := Conversation{}
+	err := m.DB.QueryRow(query, id).Scan(
+		&conversation.ID,
+		&conversation.UserID,
+		&conversation.Title,
+		&conversation.Status,
+		&conversation.CreatedAt,
+		&conversation.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrConversationNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	return &conversation, nil
+}
+
+func (m ConversationModel) ListForUser(userID int64) ([]*Conversation, error) {
+	query := `
+		SELECT id, user_id, title, status, created_at, updated_at
+		FROM conversations
+		WHERE user_id = $1
+		ORDER BY updated_at DESC, id DESC
+	`
+
+	rows, err := m.DB.Query(query, userID)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Conversation{}
+	for rows.Next() {
+		conversation := Conversation{}
+		err := rows.Scan(
+			&conversation.ID,
+			&conversation.UserID,
+			&conversation.Title,
+			&conversation.Status,
+			&conversation.CreatedAt,
+			&conversation.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, &conversation)
+	}
+
+	return list, rows.Err()
+}
+
+type MessageModel struct {
+	DB *sql.DB
+}
+
+func (m MessageModel) Insert(message *Message) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO messages (conversation_id, sender, user_id, content, model, prompt_tokens, completion_tokens, cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+
+	args := []any{message.ConversationID, message.Sender, message.UserID, message.Content, nil, nil, nil, nil}
+	if message.Usage != nil {
+		args[4] = message.Usage.Model
+		args[5] = message.Usage.PromptTokens
+		args[6] = message.Usage.CompletionTokens
+		args[7] = message.Usage.Cost
+	}
+
+	err = tx.QueryRow(query, args...).Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao inserir mensagem: %v", err))
+	}
+
+	_, err = tx.Exec(`UPDATE conversations SET updated_at = $1 WHERE id = $2`, message.CreatedAt, message.ConversationID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao atualizar conversa: %v", err))
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the message with the given ID if it belongs to the
+// conversation.
+func (m MessageModel) Get(conversationID, id int64) (*Message, error) {
+	query := `
+		SELECT id, conversation_id, sender, user_id, content, created_at
+		FROM messages
+		WHERE conversation_id = $1 AND id = $2
+	`
+
+	message := Message{}
+	err := m.DB.QueryRow(query, conversationID, id).Scan(
+		&message.ID,
+		&message.ConversationID,
+		&message.Sender,
+		&message.UserID,
+		&message.Content,
+		&message.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrMessageNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	return &message, nil
+}
+
+// List returns up to limit messages older than the message identified by
+// before (or the newest ones when before is zero), newest first. The second
+// return value is the ID to pass as before to fetch the next page, or zero
+// when there are no older messages.
+func (m MessageModel) List(conversationID, before int64, limit int) ([]*Message, int64, error) {
+	query := `
+		SELECT id, conversation_id, sender, user_id, content, created_at,
+			model, prompt_tokens, completion_tokens, cost
+		FROM messages
+		WHERE conversation_id = $1 AND (id < $2 OR $2 = 0)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	rows, err := m.DB.Query(query, conversationID, before, limit+1)
+	if err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Message{}
+	for rows.Next() {
+		message := Message{}
+		var (
+			model            sql.NullString
+			promptTokens     sql.NullInt64
+			completionTokens sql.NullInt64
+			cost             sql.NullFloat64
+		)
+
+		err := rows.Scan(
+			&message.ID,
+			&message.ConversationID,
+			&message.Sender,
+			&message.UserID,
+			&message.Content,
+			&message.CreatedAt,
+			&model,
+			&promptTokens,
+			&completionTokens,
+			&cost,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if model.Valid {
+			message.Usage = &Usage{
+				Model:            model.String,
+				PromptTokens:     int(promptTokens.Int64),
+				CompletionTokens: int(completionTokens.Int64),
+				Cost:             cost.Float64,
+			}
+		}
+		list = append(list, &message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(list) > limit {
+		list = list[:limit]
+		next = list[limit-1].ID
+	}
+
+	return list, next, nil
+}
@@ -0,0 +1,88 @@
+package faq
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+var Languages = []string{"pt", "en", "es"}
+
+type Article struct {
+	ID        int64     `json:"id,string"`
+	Question  string    `json:"question"`
+	Answer    string    `json:"answer"`
+	Tags      []string  `json:"tags"`
+	Language  string    `json:"language"`
+	CreatedBy *int64    `json:"created_by,omitempty,string"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// SearchResult is an article matching a search along with its ts_rank score,
+// normalized to the [0, 1) range so it can be compared against a threshold.
+type SearchResult struct {
+	Article
+	Rank float64 `json:"rank"`
+}
+
+type ArticleDTO struct {
+	Question string   `json:"question"`
+	Answer   string   `json:"answer"`
+	Tags     []string `json:"tags,omitempty"`
+	Language string   `json:"language,omitempty"`
+}
+
+func (dto *ArticleDTO) Normalize() {
+	dto.Question = strings.TrimSpace(dto.Question)
+	dto.Answer = strings.TrimSpace(dto.Answer)
+	if dto.Language == "" {
+		dto.Language = "pt"
+	}
+	if dto.Tags == nil {
+		dto.Tags = []string{}
+	}
+	for i := range dto.Tags {
+		dto.Tags[i] = strings.ToLower(strings.TrimSpace(dto.Tags[i]))
+	}
+}
+
+func (dto ArticleDTO) Validate(v *validator.Validator) {
+	v.Check(dto.Question != "", "question", "é obrigatória")
+	v.Check(utf8.RuneCountInString(dto.Question) <= 500, "question", "não deve ter mais de 500 caracteres")
+	v.Check(dto.Answer != "", "answer", "é obrigatória")
+	v.Check(utf8.RuneCountInString(dto.Answer) <= 10000, "answer", "não deve ter mais de 10000 caracteres")
+	v.Check(validator.In(dto.Language, Languages...), "language", "deve ser uma das opções (pt|en|es)")
+	v.Check(len(dto.Tags) <= 20, "tags", "não deve ter mais de 20 itens")
+	v.Check(validator.Unique(dto.Tags), "tags", "não deve conter valores duplicados")
+	for _, tag := range dto.Tags {
+		v.Check(tag != "", "tags", "não deve conter valores vazios")
+		v.Check(!strings.Contains(tag, TagSeparator), "tags", "não deve conter o caractere "+TagSeparator)
+	}
+}
+
+type SearchFilter struct {
+	Query    string
+	Language string
+	Tag      string
+	Limit    int
+	// MatchAny ranks articles matching any of the words of the query instead
+	// of requiring all of them, which suits free text typed by end users.
+	MatchAny bool
+}
+
+// ImportResult counts the articles created and updated by an import.
+type ImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// RowError holds the validation errors of one row of an import, numbered
+// from 1 in the order the rows appear in the file.
+type RowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
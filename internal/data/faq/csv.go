@@ -0,0 +1,159 @@
+package faq
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVColumns are the columns of the CSV format, in export order. Imports
+// accept them in any order, but question and answer are required.
+var CSVColumns = []string{"question", "answer", "tags", "language"}
+
+// TagSeparator joins the tags of an article in a single CSV cell. Commas and
+// semicolons are avoided since spreadsheets use either as the delimiter.
+const TagSeparator = "|"
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// ReadCSV parses articles from a CSV file with a header row. The delimiter
+// may be a comma or a semicolon, as exported by spreadsheets configured for
+// Brazilian Portuguese, and is detected from the header.
+//
+// Rows with more or fewer cells than the header are reported as row errors,
+// with an empty article in their place so that rows keep their numbers.
+func ReadCSV(r io.Reader) ([]ArticleDTO, []RowError, error) {
+	br := bufio.NewReader(r)
+
+	// Spreadsheets often prepend a UTF-8 byte order mark.
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+
+	header, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+
+	delimiter := ','
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		delimiter = ';'
+	}
+
+	reader := csv.NewReader(io.MultiReader(strings.NewReader(header), br))
+	reader.Comma = delimiter
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	columns, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("O arquivo CSV está vazio")
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("CSV malformado: %v", err)
+	}
+
+	index := map[string]int{}
+	for i, column := range columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		known := false
+		for _, c := range CSVColumns {
+			if column == c {
+				known = true
+			}
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("Coluna desconhecida no CSV: %q", column)
+		}
+		if _, exists := index[column]; exists {
+			return nil, nil, fmt.Errorf("Coluna duplicada no CSV: %q", column)
+		}
+		index[column] = i
+	}
+
+	for _, required := range []string{"question", "answer"} {
+		if _, exists := index[required]; !exists {
+			return nil, nil, fmt.Errorf("Coluna obrigatória ausente no CSV: %q", required)
+		}
+	}
+
+	cell := func(record []string, column string) string {
+		if i, exists := index[column]; exists {
+			return unescapeFormula(record[i])
+		}
+		return ""
+	}
+
+	rows := []ArticleDTO{}
+	rowErrors := []RowError{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("CSV malformado: %v", err)
+		}
+
+		if len(record) != len(columns) {
+			rows = append(rows, ArticleDTO{Tags: []string{}})
+			rowErrors = append(rowErrors, RowError{
+				Row:    len(rows),
+				Errors: map[string]string{"columns": fmt.Sprintf("possui %d colunas, mas o cabeçalho tem %d", len(record), len(columns))},
+			})
+			continue
+		}
+
+		dto := ArticleDTO{
+			Question: cell(record, "question"),
+			Answer:   cell(record, "answer"),
+			Language: strings.TrimSpace(cell(record, "language")),
+			Tags:     []string{},
+		}
+		if tags := strings.TrimSpace(cell(record, "tags")); tags != "" {
+			dto.Tags = strings.Split(tags, TagSeparator)
+		}
+		rows = append(rows, dto)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// WriteCSV exports the articles. Cells that a spreadsheet would evaluate as
+// a formula are prefixed with an apostrophe, which ReadCSV removes again.
+func WriteCSV(w io.Writer, articles []*Article) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	for _, a := range articles {
+		record := []string{a.Question, a.Answer, strings.Join(a.Tags, TagSeparator), a.Language}
+		for i := range record {
+			record[i] = escapeFormula(record[i])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
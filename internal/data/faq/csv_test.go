@@ -0,0 +1,95 @@
+package faq
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	file := "\xef\xbb\xbfpergunta;x\n"
+	_, _, err := ReadCSV(strings.NewReader(file))
+	if err == nil || !strings.Contains(err.Error(), "Coluna desconhecida") {
+		t.Fatalf("error = %v, want an unknown column error", err)
+	}
+
+	file = "\xef\xbb\xbfQuestion;Answer;Tags\n" +
+		"Como trocar a senha?;Acesse o perfil.;conta|senha\n" +
+		"\"Qual o horário; de atendimento?\";Das 8h às 18h.;\n"
+	rows, rowErrors, err := ReadCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrors) != 0 {
+		t.Fatalf("row errors = %+v", rowErrors)
+	}
+
+	want := []ArticleDTO{
+		{Question: "Como trocar a senha?", Answer: "Acesse o perfil.", Tags: []string{"conta", "senha"}},
+		{Question: "Qual o horário; de atendimento?", Answer: "Das 8h às 18h.", Tags: []string{}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+}
+
+func TestReadCSVReportsRowsWithWrongCellCount(t *testing.T) {
+	file := "question,answer,language\n" +
+		"Pergunta 1,Resposta 1,pt\n" +
+		"Pergunta 2,Resposta 2\n" +
+		"Pergunta 3,Resposta 3,pt,extra\n" +
+		"Pergunta 4,Resposta 4,en\n"
+
+	rows, rowErrors, err := ReadCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want every row to keep its number", len(rows))
+	}
+	if rows[3].Question != "Pergunta 4" || rows[3].Language != "en" {
+		t.Errorf("row 4 = %+v", rows[3])
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 2 || rowErrors[1].Row != 3 {
+		t.Fatalf("row errors = %+v, want rows 2 and 3", rowErrors)
+	}
+	if !strings.Contains(rowErrors[0].Errors["columns"], "2 colunas") || !strings.Contains(rowErrors[1].Errors["columns"], "4 colunas") {
+		t.Errorf("row errors = %+v", rowErrors)
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	articles := []*Article{
+		{Question: "=HYPERLINK(\"http://exemplo.com\")", Answer: "+1", Tags: []string{"-a", "b"}, Language: "pt"},
+		{Question: "@SUM(A1)", Answer: "\tcmd", Tags: []string{}, Language: "pt"},
+		{Question: "Quanto custa?", Answer: "R$ 10 - à vista", Tags: []string{"preço"}, Language: "pt"},
+	}
+
+	buf := bytes.Buffer{}
+	if err := WriteCSV(&buf, articles); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "question,answer,tags,language\n" +
+		"\"'=HYPERLINK(\"\"http://exemplo.com\"\")\",'+1,'-a|b,pt\n" +
+		"'@SUM(A1),'\tcmd,,pt\n" +
+		"Quanto custa?,R$ 10 - à vista,preço,pt\n"
+	if buf.String() != want {
+		t.Errorf("WriteCSV =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// Exported files import back to the same articles.
+	rows, rowErrors, err := ReadCSV(&buf)
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("ReadCSV: %v %+v", err, rowErrors)
+	}
+	for i, a := range articles {
+		got := rows[i]
+		if got.Question != a.Question || got.Answer != a.Answer || strings.Join(got.Tags, TagSeparator) != strings.Join(a.Tags, TagSeparator) {
+			t.Errorf("row %d = %+v, want %+v", i+1, got, a)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package faq
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/filters"
+)
+
+var (
+	ErrArticleNotFound   = errors.New("Artigo não encontrado")
+	ErrEditConflict      = errors.New("O artigo foi alterado por outra requisição, tente novamente")
+	ErrDuplicateQuestion = errors.New("Já existe um artigo com esta pergunta neste idioma")
+)
+
+const duplicateQuestion = `pq: duplicate key value violates unique constraint "faq_articles_language_question_key"`
+
+type ArticleModel struct {
+	DB *sql.DB
+}
+
+const articleColumns = `id, question, answer, tags, language, created_by, created_at, updated_at, version`
+
+func scanArticle(row interface{ Scan(...any) error }, a *Article, extra ...any) error {
+	dest := append(extra,
+		&a.ID,
+		&a.Question,
+		&a.Answer,
+		pq.Array(&a.Tags),
+		&a.Language,
+		&a.CreatedBy,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.Version,
+	)
+	return row.Scan(dest...)
+}
+
+func (m ArticleModel) Insert(a *Article) error {
+	query := `
+		INSERT INTO faq_articles (question, answer, tags, language, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + articleColumns
+
+	err := scanArticle(m.DB.QueryRow(query, a.Question, a.Answer, pq.Array(a.Tags), a.Language, a.CreatedBy), a)
+	if err != nil && err.Error() == duplicateQuestion {
+		return ErrDuplicateQuestion
+	}
+	return err
+}
+
+func (m ArticleModel) Get(id int64) (*Article, error) {
+	query := `SELECT ` + articleColumns + ` FROM faq_articles WHERE id = $1`
+
+	a := Article{}
+	err := scanArticle(m.DB.QueryRow(query, id), &a)
+	if err == sql.ErrNoRows {
+		return nil, ErrArticleNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &a, nil
+}
+
+// Update saves the article only if it still has the version that was read,
+// so concurrent edits do not silently overwrite each other.
+func (m ArticleModel) Update(a *Article) error {
+	query := `
+		UPDATE faq_articles
+		SET question = $1, answer = $2, tags = $3, language = $4, updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING ` + articleColumns
+
+	err := scanArticle(m.DB.QueryRow(query, a.Question, a.Answer, pq.Array(a.Tags), a.Language, a.ID, a.Version), a)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	} else if err != nil && err.Error() == duplicateQuestion {
+		return ErrDuplicateQuestion
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+	return nil
+}
+
+func (m ArticleModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM faq_articles WHERE id = $1`, id)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Remoção falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func (m ArticleModel) List(language, tag string, f filters.Filters) ([]*Article, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), `+articleColumns+`
+		FROM faq_articles
+		WHERE (language = $1 OR $1 = '')
+		AND ($2 = ANY(tags) OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4
+	`, f.SortColumn(), f.SortDirection())
+
+	rows, err := m.DB.Query(query, language, tag, f.Limit(), f.Offset())
+	if err != nil {
+		return nil, filters.Metadata{}, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	list := []*Article{}
+	for rows.Next() {
+		a := Article{}
+		if err := scanArticle(rows, &a, &totalRecords); err != nil {
+			return nil, filters.Metadata{}, err
+		}
+		list = append(list, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return list, filters.CalculateMetadata(totalRecords, f.Page, f.PageSize), nil
+}
+
+// Search ranks articles against the query with ts_rank. Normalization 32
+// maps the rank to rank/(rank+1), giving scores between 0 and 1. An empty
+// language searches every article using the Portuguese configuration.
+//
+// ts_rank averages over the terms of the query: a term found once in the
+// question (weight A) adds about 0.61, in the tags 0.24 and in the answer
+// 0.12. With MatchAny, a message whose terms all appear in the question
+// therefore scores about 0.38, and one with half of them 0.23. Scores above
+// 0.4 need terms repeated in the question and tags.
+func (m ArticleModel) Search(filter SearchFilter) ([]*SearchResult, error) {
+	tsquery := `websearch_to_tsquery(faq_search_config($2), $1)`
+	if filter.MatchAny {
+		tsquery = `NULLIF(replace(plainto_tsquery(faq_search_config($2), $1)::text, '&', '|'), '')::tsquery`
+	}
+
+	query := `
+		SELECT ts_rank(search, s.q, 32), ` + articleColumns + `
+		FROM faq_articles, (SELECT ` + tsquery + ` AS q) s
+		WHERE search @@ s.q
+		AND (language = $2 OR $2 = '')
+		AND ($3 = ANY(tags) OR $3 = '')
+		ORDER BY 1 DESC, id ASC
+		LIMIT $4
+	`
+
+	rows, err := m.DB.Query(query, filter.Query, filter.Language, filter.Tag, filter.Limit)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+	for rows.Next() {
+		r := SearchResult{}
+		if err := scanArticle(rows, &r.Article, &r.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, &r)
+	}
+
+	return results, rows.Err()
+}
+
+// All returns every article in id order, for exports.
+func (m ArticleModel) All() ([]*Article, error) {
+	rows, err := m.DB.Query(`SELECT ` + articleColumns + ` FROM faq_articles ORDER BY id ASC`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Article{}
+	for rows.Next() {
+		a := Article{}
+		if err := scanArticle(rows, &a); err != nil {
+			return nil, err
+		}
+		list = append(list, &a)
+	}
+
+	return list, rows.Err()
+}
+
+// Import upserts the articles in a single transaction, matching existing
+// articles by language and question. Either every article is saved or none
+// is.
+func (m ArticleModel) Import(articles []*Article) (ImportResult, error) {
+	result := ImportResult{}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO faq_articles (question, answer, tags, language, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (language, question) DO UPDATE
+		SET answer = EXCLUDED.answer, tags = EXCLUDED.tags, updated_at = CURRENT_TIMESTAMP, version = faq_articles.version + 1
+		RETURNING xmax = 0
+	`)
+	if err != nil {
+		return result, err
+	}
+	defer stmt.Close()
+
+	for _, a := range articles {
+		var inserted bool
+		err := stmt.QueryRow(a.Question, a.Answer, pq.Array(a.Tags), a.Language, a.CreatedBy).Scan(&inserted)
+		if err != nil {
+			return ImportResult{}, errors.New(fmt.Sprintf("Falha ao importar artigo %q: %v", a.Question, err))
+		}
+
+		if inserted {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
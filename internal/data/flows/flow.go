@@ -0,0 +1,93 @@
+package flows
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/entities"
+	"github.com/pedro-git-projects/chatbot-back/internal/flow"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type Flow struct {
+	ID          int64           `json:"id,string"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Definition  flow.Definition `json:"definition"`
+	CreatedBy   *int64          `json:"created_by,omitempty,string"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Version     int             `json:"version"`
+}
+
+type SessionStatus string
+
+const (
+	SessionActive    SessionStatus = "active"
+	SessionCompleted SessionStatus = "completed"
+	SessionCancelled SessionStatus = "cancelled"
+)
+
+// Session is a user's progress through a flow. A user has at most one
+// active session at a time.
+//
+// Sessions started while a bot version is published are pinned to it in
+// BotVersionID and keep running from its snapshot until they finish.
+type Session struct {
+	ID           int64             `json:"id,string"`
+	UserID       int64             `json:"user_id,string"`
+	FlowID       int64             `json:"flow_id,string"`
+	BotVersionID *int64            `json:"bot_version_id,omitempty,string"`
+	Node         string            `json:"node"`
+	Variables    map[string]string `json:"variables"`
+	Status       SessionStatus     `json:"status"`
+	StartedAt    time.Time         `json:"started_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	FinishedAt   *time.Time        `json:"finished_at,omitempty"`
+	Attempts     int               `json:"attempts"`
+	Version      int               `json:"-"`
+}
+
+func (s *Session) State() flow.State {
+	return flow.State{Node: s.Node, Variables: s.Variables, Attempts: s.Attempts, Done: s.Status != SessionActive}
+}
+
+func (s *Session) SetState(state flow.State) {
+	s.Node = state.Node
+	s.Variables = state.Variables
+	s.Attempts = state.Attempts
+	if state.Done {
+		s.Status = SessionCompleted
+	}
+}
+
+type FlowDTO struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Definition  flow.Definition `json:"definition"`
+}
+
+func (dto FlowDTO) Validate(v *validator.Validator) {
+	v.Check(dto.Name != "", "name", "é obrigatório")
+	v.Check(utf8.RuneCountInString(dto.Name) <= 100, "name", "não deve ter mais de 100 caracteres")
+	v.Check(utf8.RuneCountInString(dto.Description) <= 1000, "description", "não deve ter mais de 1000 caracteres")
+
+	dto.Definition.Validate(v)
+}
+
+type FlowInputDTO struct {
+	Input string `json:"input"`
+}
+
+func (dto FlowInputDTO) Validate(v *validator.Validator) {
+	v.Check(utf8.RuneCountInString(dto.Input) <= 4000, "input", "não deve ter mais de 4000 caracteres")
+}
+
+// Slot is a value collected by a slot of a flow during a session.
+type Slot struct {
+	Name     string        `json:"name"`
+	Entity   entities.Type `json:"entity"`
+	Value    string        `json:"value"`
+	Text     string        `json:"text"`
+	FilledAt time.Time     `json:"filled_at"`
+}
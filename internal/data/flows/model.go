@@ -0,0 +1,307 @@
+package flows
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/flow"
+)
+
+var (
+	ErrFlowNotFound    = errors.New("Fluxo não encontrado")
+	ErrDuplicateName   = errors.New("Já existe um fluxo com este nome")
+	ErrEditConflict    = errors.New("O registro foi alterado por outra requisição, tente novamente")
+	ErrSessionNotFound = errors.New("Nenhuma sessão de fluxo ativa")
+)
+
+const duplicateName = `pq: duplicate key value violates unique constraint "flows_name_key"`
+
+type FlowModel struct {
+	DB *sql.DB
+}
+
+const flowColumns = `id, name, description, definition, created_by, created_at, updated_at, version`
+
+func scanFlow(row interface{ Scan(...any) error }, f *Flow) error {
+	var definition []byte
+	err := row.Scan(
+		&f.ID,
+		&f.Name,
+		&f.Description,
+		&definition,
+		&f.CreatedBy,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.Version,
+	)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(definition, &f.Definition)
+}
+
+func (m FlowModel) Insert(f *Flow) error {
+	definition, err := json.Marshal(f.Definition)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO flows (name, description, definition, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + flowColumns
+
+	err = scanFlow(m.DB.QueryRow(query, f.Name, f.Description, definition, f.CreatedBy), f)
+	if err != nil && err.Error() == duplicateName {
+		return ErrDuplicateName
+	}
+	return err
+}
+
+func (m FlowModel) Get(id int64) (*Flow, error) {
+	query := `SELECT ` + flowColumns + ` FROM flows WHERE id = $1`
+
+	f := Flow{}
+	err := scanFlow(m.DB.QueryRow(query, id), &f)
+	if err == sql.ErrNoRows {
+		return nil, ErrFlowNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &f, nil
+}
+
+func (m FlowModel) List() ([]*Flow, error) {
+	rows, err := m.DB.Query(`SELECT ` + flowColumns + ` FROM flows ORDER BY name ASC`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Flow{}
+	for rows.Next() {
+		f := Flow{}
+		if err := scanFlow(rows, &f); err != nil {
+			return nil, err
+		}
+		list = append(list, &f)
+	}
+
+	return list, rows.Err()
+}
+
+// Update saves the flow only if it still has the version that was read.
+// Sessions already running keep their current node and variables; nodes
+// that no longer exist are reported when the session advances.
+func (m FlowModel) Update(f *Flow) error {
+	definition, err := json.Marshal(f.Definition)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE flows
+		SET name = $1, description = $2, definition = $3, updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING ` + flowColumns
+
+	err = scanFlow(m.DB.QueryRow(query, f.Name, f.Description, definition, f.ID, f.Version), f)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	} else if err != nil && err.Error() == duplicateName {
+		return ErrDuplicateName
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+	return nil
+}
+
+func (m FlowModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM flows WHERE id = $1`, id)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Remoção falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrFlowNotFound
+	}
+	return nil
+}
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+const sessionColumns = `id, user_id, flow_id, bot_version_id, current_node, variables, status, started_at, updated_at, finished_at, attempts, version`
+
+func scanSession(row interface{ Scan(...any) error }, s *Session) error {
+	var variables []byte
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.FlowID,
+		&s.BotVersionID,
+		&s.Node,
+		&variables,
+		&s.Status,
+		&s.StartedAt,
+		&s.UpdatedAt,
+		&s.FinishedAt,
+		&s.Attempts,
+		&s.Version,
+	)
+	if err != nil {
+		return err
+	}
+	s.Variables = map[string]string{}
+	return json.Unmarshal(variables, &s.Variables)
+}
+
+// Start cancels the active session of the user, if any, and records the new
+// one in the same transaction.
+func (m SessionModel) Start(s *Session) error {
+	variables, err := json.Marshal(s.Variables)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE flow_sessions
+		SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND status = 'active'
+	`, s.UserID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao cancelar sessão anterior: %v", err))
+	}
+
+	query := `
+		INSERT INTO flow_sessions (user_id, flow_id, bot_version_id, current_node, variables, status, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $6 = 'active' THEN NULL ELSE CURRENT_TIMESTAMP END)
+		RETURNING ` + sessionColumns
+
+	err = scanSession(tx.QueryRow(query, s.UserID, s.FlowID, s.BotVersionID, s.Node, variables, s.Status), s)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao criar sessão: %v", err))
+	}
+
+	return tx.Commit()
+}
+
+func (m SessionModel) Active(userID int64) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM flow_sessions WHERE user_id = $1 AND status = 'active'`
+
+	s := Session{}
+	err := scanSession(m.DB.QueryRow(query, userID), &s)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &s, nil
+}
+
+// Save stores the new state of the session, along with the slots filled by
+// the last answer, if nobody else advanced it since it was read, so two
+// concurrent answers cannot both be applied. A slot filled again replaces
+// its previous value.
+func (m SessionModel) Save(s *Session, slots []flow.SlotValue) error {
+	variables, err := json.Marshal(s.Variables)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE flow_sessions
+		SET current_node = $1, variables = $2, status = $3, attempts = $4, updated_at = CURRENT_TIMESTAMP,
+			finished_at = CASE WHEN $3 = 'active' THEN NULL ELSE CURRENT_TIMESTAMP END,
+			version = version + 1
+		WHERE id = $5 AND version = $6
+		RETURNING ` + sessionColumns
+
+	err = scanSession(tx.QueryRow(query, s.Node, variables, s.Status, s.Attempts, s.ID, s.Version), s)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	for _, slot := range slots {
+		query := `
+			INSERT INTO flow_slots (session_id, user_id, name, entity, value, raw_text)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (session_id, name) DO UPDATE
+			SET entity = EXCLUDED.entity, value = EXCLUDED.value, raw_text = EXCLUDED.raw_text, filled_at = CURRENT_TIMESTAMP
+		`
+
+		_, err = tx.Exec(query, s.ID, s.UserID, slot.Name, slot.Type, slot.Value, slot.Text)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Falha ao salvar slot %q: %v", slot.Name, err))
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m SessionModel) Slots(sessionID int64) ([]*Slot, error) {
+	query := `
+		SELECT name, entity, value, raw_text, filled_at
+		FROM flow_slots
+		WHERE session_id = $1
+		ORDER BY filled_at ASC, id ASC
+	`
+
+	rows, err := m.DB.Query(query, sessionID)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	slots := []*Slot{}
+	for rows.Next() {
+		slot := Slot{}
+		if err := rows.Scan(&slot.Name, &slot.Entity, &slot.Value, &slot.Text, &slot.FilledAt); err != nil {
+			return nil, err
+		}
+		slots = append(slots, &slot)
+	}
+
+	return slots, rows.Err()
+}
+
+func (m SessionModel) Cancel(userID int64) error {
+	result, err := m.DB.Exec(`
+		UPDATE flow_sessions
+		SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE user_id = $1 AND status = 'active'
+	`, userID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
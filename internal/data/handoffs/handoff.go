@@ -0,0 +1,66 @@
+package handoffs
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type Status string
+
+const (
+	StatusQueued   Status = "queued"
+	StatusAssigned Status = "assigned"
+	StatusClosed   Status = "closed"
+)
+
+type Action string
+
+const (
+	ActionRequested   Action = "requested"
+	ActionClaimed     Action = "claimed"
+	ActionReleased    Action = "released"
+	ActionTransferred Action = "transferred"
+	ActionClosed      Action = "closed"
+	ActionAssigned    Action = "assigned"
+)
+
+// Handoff is a request for a human to take over a conversation from the bot.
+// It waits in the queue until a collaborator claims it and stays assigned to
+// that collaborator until it is released, transferred or closed.
+type Handoff struct {
+	ID             int64      `json:"id,string"`
+	ConversationID int64      `json:"conversation_id,string"`
+	RequestedBy    *int64     `json:"requested_by,omitempty,string"`
+	Reason         string     `json:"reason"`
+	Tags           []string   `json:"tags"`
+	Status         Status     `json:"status"`
+	AssignedTo     *int64     `json:"assigned_to,omitempty,string"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AssignedAt     *time.Time `json:"assigned_at,omitempty"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+}
+
+func (h *Handoff) AssignedToUser(userID int64) bool {
+	return h.Status == StatusAssigned && h.AssignedTo != nil && *h.AssignedTo == userID
+}
+
+type RequestHandoffDTO struct {
+	Reason string   `json:"reason,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+func (dto RequestHandoffDTO) Validate(v *validator.Validator) {
+	v.Check(utf8.RuneCountInString(dto.Reason) <= 1000, "reason", "não deve ter mais de 1000 caracteres")
+	v.Check(len(dto.Tags) <= 10, "tags", "não deve ter mais de 10 itens")
+	v.Check(validator.Unique(dto.Tags), "tags", "não deve conter valores duplicados")
+}
+
+type TransferHandoffDTO struct {
+	CollaboratorID int64 `json:"collaboratorId,string"`
+}
+
+func (dto TransferHandoffDTO) Validate(v *validator.Validator) {
+	v.Check(dto.CollaboratorID > 0, "collaboratorId", "é obrigatório")
+}
@@ -0,0 +1,338 @@
+package handoffs
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrHandoffNotFound    = errors.New("Transferência para atendimento humano não encontrada")
+	ErrActiveHandoff      = errors.New("A conversa já possui uma transferência para atendimento humano em andamento")
+	ErrInvalidTransition  = errors.New("A transferência não está em um estado que permita esta operação")
+	ErrNotAssignedToAgent = errors.New("A transferência não está atribuída a você")
+	ErrCapacityReached    = errors.New("O colaborador atingiu o limite de conversas simultâneas")
+)
+
+// StrategyManual is recorded on assignments made by a collaborator claiming
+// or transferring a handoff instead of by the router.
+const StrategyManual = "manual"
+
+type HandoffModel struct {
+	DB *sql.DB
+}
+
+const handoffColumns = `id, conversation_id, requested_by, reason, tags, status, assigned_to, created_at, assigned_at, closed_at`
+
+func scanHandoff(row interface{ Scan(...any) error }, h *Handoff) error {
+	return row.Scan(
+		&h.ID,
+		&h.ConversationID,
+		&h.RequestedBy,
+		&h.Reason,
+		pq.Array(&h.Tags),
+		&h.Status,
+		&h.AssignedTo,
+		&h.CreatedAt,
+		&h.AssignedAt,
+		&h.ClosedAt,
+	)
+}
+
+func recordEvent(tx *sql.Tx, handoffID int64, action Action, actorID, targetID *int64) error {
+	query := `
+		INSERT INTO handoff_events (handoff_id, action, actor_id, target_id)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := tx.Exec(query, handoffID, action, actorID, targetID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao registrar evento da transferência: %v", err))
+	}
+	return nil
+}
+
+// syncAssignment keeps the assignments table in step with the handoff: the
+// open assignment, if any, is released and a new one is opened when the
+// handoff ends up assigned.
+func syncAssignment(tx *sql.Tx, h *Handoff, strategy string) error {
+	_, err := tx.Exec(`UPDATE assignments SET released_at = CURRENT_TIMESTAMP WHERE handoff_id = $1 AND released_at IS NULL`, h.ID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao liberar atribuição: %v", err))
+	}
+
+	if h.Status != StatusAssigned || h.AssignedTo == nil {
+		return nil
+	}
+
+	query := `
+		INSERT INTO assignments (user_id, handoff_id, strategy)
+		VALUES ($1, $2, $3)
+	`
+	_, err = tx.Exec(query, *h.AssignedTo, h.ID, strategy)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao registrar atribuição: %v", err))
+	}
+
+	query = `
+		INSERT INTO collaborator_availability (user_id, last_assigned_at)
+		VALUES ($1, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE SET last_assigned_at = EXCLUDED.last_assigned_at
+	`
+	_, err = tx.Exec(query, *h.AssignedTo)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao registrar atribuição: %v", err))
+	}
+	return nil
+}
+
+func (m HandoffModel) Request(conversationID, requestedBy int64, reason string, tags []string) (*Handoff, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO handoffs (conversation_id, requested_by, reason, tags)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + handoffColumns
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	h := Handoff{}
+	err = scanHandoff(tx.QueryRow(query, conversationID, requestedBy, reason, pq.Array(tags)), &h)
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "handoffs_one_active_per_conversation_idx"` {
+			return nil, ErrActiveHandoff
+		}
+		return nil, errors.New(fmt.Sprintf("Falha ao criar transferência: %v", err))
+	}
+
+	if err = recordEvent(tx, h.ID, ActionRequested, &requestedBy, nil); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (m HandoffModel) Get(id int64) (*Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE id = $1`
+
+	h := Handoff{}
+	err := scanHandoff(m.DB.QueryRow(query, id), &h)
+	if err == sql.ErrNoRows {
+		return nil, ErrHandoffNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &h, nil
+}
+
+// Active returns the handoff of the conversation that is still queued or
+// assigned, or ErrHandoffNotFound when the bot is in charge.
+func (m HandoffModel) Active(conversationID int64) (*Handoff, error) {
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE conversation_id = $1 AND status <> 'closed'`
+
+	h := Handoff{}
+	err := scanHandoff(m.DB.QueryRow(query, conversationID), &h)
+	if err == sql.ErrNoRows {
+		return nil, ErrHandoffNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &h, nil
+}
+
+// List returns handoffs in queue order. A zero assignedTo lists handoffs
+// assigned to anyone; an empty status lists every active handoff.
+func (m HandoffModel) List(status Status, assignedTo int64) ([]*Handoff, error) {
+	query := `
+		SELECT ` + handoffColumns + `
+		FROM handoffs
+		WHERE (status = $1 OR ($1 = '' AND status <> 'closed'))
+		AND (assigned_to = $2 OR $2 = 0)
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := m.DB.Query(query, status, assignedTo)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Handoff{}
+	for rows.Next() {
+		h := Handoff{}
+		if err := scanHandoff(rows, &h); err != nil {
+			return nil, err
+		}
+		list = append(list, &h)
+	}
+
+	return list, rows.Err()
+}
+
+// transition locks the handoff, lets check validate the current state and
+// applies update in the same transaction, recording the action and the
+// resulting assignment under the given strategy.
+func (m HandoffModel) transition(id int64, action Action, actorID int64, targetID *int64, strategy string, check func(tx *sql.Tx, h *Handoff) error, update string, args ...any) (*Handoff, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + handoffColumns + ` FROM handoffs WHERE id = $1 FOR UPDATE`
+
+	h := Handoff{}
+	err = scanHandoff(tx.QueryRow(query, id), &h)
+	if err == sql.ErrNoRows {
+		return nil, ErrHandoffNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	if err = check(tx, &h); err != nil {
+		return nil, err
+	}
+
+	query = update + ` WHERE id = $1 RETURNING ` + handoffColumns
+	err = scanHandoff(tx.QueryRow(query, append([]any{id}, args...)...), &h)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao atualizar transferência: %v", err))
+	}
+
+	if err = syncAssignment(tx, &h, strategy); err != nil {
+		return nil, err
+	}
+
+	if err = recordEvent(tx, h.ID, action, &actorID, targetID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Claim assigns a queued handoff to the collaborator taking it. Unless force
+// is set, the collaborator must be online and below their capacity, as for
+// assignments made by the router.
+func (m HandoffModel) Claim(id, agentID int64, force bool) (*Handoff, error) {
+	check := func(tx *sql.Tx, h *Handoff) error {
+		if h.Status != StatusQueued {
+			return ErrInvalidTransition
+		}
+		if !force {
+			return checkCapacity(tx, agentID)
+		}
+		return nil
+	}
+
+	update := `UPDATE handoffs SET status = 'assigned', assigned_to = $2, assigned_at = CURRENT_TIMESTAMP`
+	return m.transition(id, ActionClaimed, agentID, &agentID, StrategyManual, check, update, agentID)
+}
+
+// Release puts an assigned handoff back in the queue. Unless force is set,
+// only the collaborator it is assigned to can release it.
+func (m HandoffModel) Release(id, agentID int64, force bool) (*Handoff, error) {
+	check := func(tx *sql.Tx, h *Handoff) error {
+		if h.Status != StatusAssigned {
+			return ErrInvalidTransition
+		}
+		if !force && !h.AssignedToUser(agentID) {
+			return ErrNotAssignedToAgent
+		}
+		return nil
+	}
+
+	update := `UPDATE handoffs SET status = 'queued', assigned_to = NULL, assigned_at = NULL`
+	return m.transition(id, ActionReleased, agentID, nil, "", check, update)
+}
+
+// Transfer assigns the handoff to another collaborator. Unless force is set,
+// only the collaborator it is currently assigned to can transfer it, and only
+// to a collaborator who is online and below their capacity.
+func (m HandoffModel) Transfer(id, agentID, targetID int64, force bool) (*Handoff, error) {
+	check := func(tx *sql.Tx, h *Handoff) error {
+		if h.Status == StatusClosed {
+			return ErrInvalidTransition
+		}
+		if force {
+			return nil
+		}
+		if !h.AssignedToUser(agentID) {
+			return ErrNotAssignedToAgent
+		}
+		return checkCapacity(tx, targetID)
+	}
+
+	update := `UPDATE handoffs SET status = 'assigned', assigned_to = $2, assigned_at = CURRENT_TIMESTAMP`
+	return m.transition(id, ActionTransferred, agentID, &targetID, StrategyManual, check, update, targetID)
+}
+
+// Close ends the handoff and gives the conversation back to the bot. Unless
+// force is set, only the collaborator it is assigned to can close it.
+func (m HandoffModel) Close(id, agentID int64, force bool) (*Handoff, error) {
+	check := func(tx *sql.Tx, h *Handoff) error {
+		if h.Status == StatusClosed {
+			return ErrInvalidTransition
+		}
+		if !force && !h.AssignedToUser(agentID) {
+			return ErrNotAssignedToAgent
+		}
+		return nil
+	}
+
+	update := `UPDATE handoffs SET status = 'closed', closed_at = CURRENT_TIMESTAMP`
+	return m.transition(id, ActionClosed, agentID, nil, "", check, update)
+}
+
+// checkCapacity refuses to give the collaborator another handoff when they
+// are not online or already at their capacity. Their availability row is
+// locked and their workload counted inside the transaction, so concurrent
+// assignments cannot exceed it.
+func checkCapacity(tx *sql.Tx, agentID int64) error {
+	query := `
+		SELECT a.capacity,
+			(SELECT count(*) FROM assignments s WHERE s.user_id = a.user_id AND s.released_at IS NULL)
+		FROM collaborator_availability a
+		WHERE a.user_id = $1 AND a.status = 'online'
+		FOR UPDATE
+	`
+
+	var capacity, open int
+	err := tx.QueryRow(query, agentID).Scan(&capacity, &open)
+	if err == sql.ErrNoRows {
+		return ErrCapacityReached
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	if open >= capacity {
+		return ErrCapacityReached
+	}
+	return nil
+}
+
+// Assign gives a queued handoff to a collaborator picked by the router.
+func (m HandoffModel) Assign(id, agentID int64, strategy string) (*Handoff, error) {
+	check := func(tx *sql.Tx, h *Handoff) error {
+		if h.Status != StatusQueued {
+			return ErrInvalidTransition
+		}
+		return checkCapacity(tx, agentID)
+	}
+
+	update := `UPDATE handoffs SET status = 'assigned', assigned_to = $2, assigned_at = CURRENT_TIMESTAMP`
+	return m.transition(id, ActionAssigned, agentID, &agentID, strategy, check, update, agentID)
+}
@@ -0,0 +1,66 @@
+package intents
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+var NameRX = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+type Intent struct {
+	ID          int64     `json:"id,string"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Examples    []string  `json:"examples"`
+	Responses   []string  `json:"responses"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Version     int       `json:"version"`
+}
+
+type IntentDTO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Examples    []string `json:"examples"`
+	Responses   []string `json:"responses,omitempty"`
+}
+
+func (dto *IntentDTO) Normalize() {
+	dto.Name = strings.TrimSpace(dto.Name)
+	for i := range dto.Examples {
+		dto.Examples[i] = strings.TrimSpace(dto.Examples[i])
+	}
+	if dto.Responses == nil {
+		dto.Responses = []string{}
+	}
+}
+
+func (dto IntentDTO) Validate(v *validator.Validator) {
+	v.Check(validator.Matches(dto.Name, NameRX), "name", "deve ter entre 1 e 64 letras minúsculas, números, _, . ou -")
+	v.Check(utf8.RuneCountInString(dto.Description) <= 1000, "description", "não deve ter mais de 1000 caracteres")
+	v.Check(len(dto.Examples) > 0, "examples", "deve conter ao menos um exemplo")
+	v.Check(len(dto.Examples) <= 1000, "examples", "não deve ter mais de 1000 exemplos")
+	v.Check(validator.Unique(dto.Examples), "examples", "não deve conter exemplos duplicados")
+	for _, example := range dto.Examples {
+		v.Check(example != "", "examples", "não deve conter exemplos vazios")
+		v.Check(utf8.RuneCountInString(example) <= 500, "examples", "cada exemplo deve ter no máximo 500 caracteres")
+	}
+	v.Check(len(dto.Responses) <= 20, "responses", "não deve ter mais de 20 respostas")
+	for _, response := range dto.Responses {
+		v.Check(strings.TrimSpace(response) != "", "responses", "não deve conter respostas vazias")
+		v.Check(utf8.RuneCountInString(response) <= 4000, "responses", "cada resposta deve ter no máximo 4000 caracteres")
+	}
+}
+
+type TestSentenceDTO struct {
+	Text string `json:"text"`
+}
+
+func (dto TestSentenceDTO) Validate(v *validator.Validator) {
+	v.Check(strings.TrimSpace(dto.Text) != "", "text", "é obrigatório")
+	v.Check(utf8.RuneCountInString(dto.Text) <= 1000, "text", "não deve ter mais de 1000 caracteres")
+}
@@ -0,0 +1,258 @@
+package intents
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+)
+
+var (
+	ErrIntentNotFound = errors.New("Intenção não encontrada")
+	ErrDuplicateName  = errors.New("Já existe uma intenção com este nome")
+	ErrEditConflict   = errors.New("A intenção foi alterada por outra requisição, tente novamente")
+	ErrModelNotFound  = errors.New("Nenhum modelo de intenções treinado")
+)
+
+const duplicateName = `pq: duplicate key value violates unique constraint "intents_name_key"`
+
+type IntentModel struct {
+	DB *sql.DB
+}
+
+const intentColumns = `i.id, i.name, i.description, i.responses, i.created_at, i.updated_at, i.version,
+	ARRAY(SELECT text FROM intent_examples WHERE intent_id = i.id ORDER BY id)`
+
+func scanIntent(row interface{ Scan(...any) error }, i *Intent) error {
+	return row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		pq.Array(&i.Responses),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		pq.Array(&i.Examples),
+	)
+}
+
+func replaceExamples(tx *sql.Tx, intentID int64, examples []string) error {
+	_, err := tx.Exec(`DELETE FROM intent_examples WHERE intent_id = $1`, intentID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao remover exemplos: %v", err))
+	}
+
+	query := `
+		INSERT INTO intent_examples (intent_id, text)
+		SELECT $1, unnest($2::text[])
+	`
+	_, err = tx.Exec(query, intentID, pq.Array(examples))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao salvar exemplos: %v", err))
+	}
+	return nil
+}
+
+func (m IntentModel) Insert(i *Intent) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO intents (name, description, responses)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	err = tx.QueryRow(query, i.Name, i.Description, pq.Array(i.Responses)).Scan(&i.ID)
+	if err != nil {
+		if err.Error() == duplicateName {
+			return ErrDuplicateName
+		}
+		return errors.New(fmt.Sprintf("Falha ao criar intenção: %v", err))
+	}
+
+	if err = replaceExamples(tx, i.ID, i.Examples); err != nil {
+		return err
+	}
+
+	err = scanIntent(tx.QueryRow(`SELECT `+intentColumns+` FROM intents i WHERE i.id = $1`, i.ID), i)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m IntentModel) Get(id int64) (*Intent, error) {
+	query := `SELECT ` + intentColumns + ` FROM intents i WHERE i.id = $1`
+
+	i := Intent{}
+	err := scanIntent(m.DB.QueryRow(query, id), &i)
+	if err == sql.ErrNoRows {
+		return nil, ErrIntentNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &i, nil
+}
+
+func (m IntentModel) List() ([]*Intent, error) {
+	rows, err := m.DB.Query(`SELECT ` + intentColumns + ` FROM intents i ORDER BY i.name ASC`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Intent{}
+	for rows.Next() {
+		i := Intent{}
+		if err := scanIntent(rows, &i); err != nil {
+			return nil, err
+		}
+		list = append(list, &i)
+	}
+
+	return list, rows.Err()
+}
+
+// Update replaces the intent and all of its examples if it still has the
+// version that was read.
+func (m IntentModel) Update(i *Intent) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE intents
+		SET name = $1, description = $2, responses = $3, updated_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING id
+	`
+
+	err = tx.QueryRow(query, i.Name, i.Description, pq.Array(i.Responses), i.ID, i.Version).Scan(&i.ID)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	} else if err != nil && err.Error() == duplicateName {
+		return ErrDuplicateName
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	if err = replaceExamples(tx, i.ID, i.Examples); err != nil {
+		return err
+	}
+
+	err = scanIntent(tx.QueryRow(`SELECT `+intentColumns+` FROM intents i WHERE i.id = $1`, i.ID), i)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m IntentModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM intents WHERE id = $1`, id)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Remoção falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrIntentNotFound
+	}
+	return nil
+}
+
+// Examples returns the training examples of every intent.
+func (m IntentModel) Examples() ([]nlu.Example, error) {
+	query := `
+		SELECT i.name, e.text
+		FROM intent_examples e
+		JOIN intents i ON i.id = e.intent_id
+		ORDER BY i.name, e.id
+	`
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	examples := []nlu.Example{}
+	for rows.Next() {
+		ex := nlu.Example{}
+		if err := rows.Scan(&ex.Intent, &ex.Text); err != nil {
+			return nil, err
+		}
+		examples = append(examples, ex)
+	}
+
+	return examples, rows.Err()
+}
+
+// Responses returns the replies of every intent that has any.
+func (m IntentModel) Responses() (map[string][]string, error) {
+	rows, err := m.DB.Query(`SELECT name, responses FROM intents WHERE cardinality(responses) > 0`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	responses := map[string][]string{}
+	for rows.Next() {
+		var name string
+		var replies []string
+		if err := rows.Scan(&name, pq.Array(&replies)); err != nil {
+			return nil, err
+		}
+		responses[name] = replies
+	}
+
+	return responses, rows.Err()
+}
+
+// SaveModel stores a trained classifier so it can be loaded on startup.
+func (m IntentModel) SaveModel(c *nlu.Classifier, trainedBy int64) error {
+	model, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO nlu_models (model, intents, examples, trained_by, trained_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err = m.DB.Exec(query, model, len(c.Intents), c.Examples, trainedBy, c.TrainedAt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao salvar modelo: %v", err))
+	}
+	return nil
+}
+
+func (m IntentModel) LatestModel() (*nlu.Classifier, error) {
+	var model []byte
+	err := m.DB.QueryRow(`SELECT model FROM nlu_models ORDER BY id DESC LIMIT 1`).Scan(&model)
+	if err == sql.ErrNoRows {
+		return nil, ErrModelNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	c := nlu.Classifier{}
+	if err := json.Unmarshal(model, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
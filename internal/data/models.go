@@ -3,18 +3,54 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/pedro-git-projects/chatbot-back/internal/data/collaborators"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/faq"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/flows"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/intents"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/tokens"
 	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/versions"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
 )
 
 var ErrRecordNotFound = errors.New("Registro não encontrado")
 
 type Models struct {
-	Users users.UserModel
+	Users         users.UserModel
+	RoleRequests  users.RoleRequestModel
+	RefreshTokens tokens.RefreshTokenModel
+	Revocations   *tokens.RevocationStore
+	Conversations conversations.ConversationModel
+	Messages      conversations.MessageModel
+	Events        conversations.EventModel
+	Handoffs      handoffs.HandoffModel
+	Availability  collaborators.AvailabilityModel
+	Articles      faq.ArticleModel
+	Flows         flows.FlowModel
+	FlowSessions  flows.SessionModel
+	Intents       intents.IntentModel
+	Versions      versions.VersionModel
 }
 
-func NewModels(db *sql.DB) Models {
+func NewModels(db *sql.DB, hasher password.Hasher) Models {
 	return Models{
-		Users: users.UserModel{DB: db},
+		Users:         users.UserModel{DB: db, Hasher: hasher},
+		RoleRequests:  users.RoleRequestModel{DB: db},
+		RefreshTokens: tokens.RefreshTokenModel{DB: db},
+		Revocations:   tokens.NewRevocationStore(db, 30*time.Second),
+		Conversations: conversations.ConversationModel{DB: db},
+		Messages:      conversations.MessageModel{DB: db},
+		Events:        conversations.EventModel{DB: db},
+		Handoffs:      handoffs.HandoffModel{DB: db},
+		Availability:  collaborators.AvailabilityModel{DB: db},
+		Articles:      faq.ArticleModel{DB: db},
+		Flows:         flows.FlowModel{DB: db},
+		FlowSessions:  flows.SessionModel{DB: db},
+		Intents:       intents.IntentModel{DB: db},
+		Versions:      versions.VersionModel{DB: db},
 	}
 }
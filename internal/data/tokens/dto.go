@@ -0,0 +1,11 @@
+package tokens
+
+import "github.com/pedro-git-projects/chatbot-back/internal/validator"
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (dto RefreshTokenDTO) Validate(v *validator.Validator) {
+	v.Check(dto.RefreshToken != "", "refreshToken", "é obrigatório")
+}
@@ -0,0 +1,154 @@
+package tokens
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("Token de atualização inválido ou expirado")
+	ErrRefreshTokenReused  = errors.New("Token de atualização reutilizado")
+)
+
+type RefreshTokenModel struct {
+	DB *sql.DB
+}
+
+func (m RefreshTokenModel) Insert(token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	args := []any{token.UserID, token.FamilyID, token.Hash, token.ExpiresAt}
+	return m.DB.QueryRow(query, args...).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Rotate exchanges a refresh token for a new one in the same family. A token
+// can only be exchanged once: presenting an already used or revoked token is
+// treated as theft, and every token in its family is revoked.
+func (m RefreshTokenModel) Rotate(plaintext string, ttl time.Duration) (*RefreshToken, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, family_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	var (
+		id        int64
+		userID    int64
+		familyID  string
+		expiresAt time.Time
+		usedAt    sql.NullTime
+		revokedAt sql.NullTime
+	)
+
+	err = tx.QueryRow(query, hashToken(plaintext)).Scan(&id, &userID, &familyID, &expiresAt, &usedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	if usedAt.Valid || revokedAt.Valid {
+		_, err = tx.Exec(`
+			UPDATE refresh_tokens
+			SET revoked_at = CURRENT_TIMESTAMP
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`, familyID)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Falha ao revogar família de tokens: %v", err))
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, err := NewRefreshToken(userID, ttl, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, next.UserID, next.FamilyID, next.Hash, next.ExpiresAt).Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao inserir token de atualização: %v", err))
+	}
+
+	_, err = tx.Exec(`
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP, replaced_by = $1
+		WHERE id = $2
+	`, next.ID, id)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao marcar token de atualização como usado: %v", err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+func (m RefreshTokenModel) RevokeFamily(familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := m.DB.Exec(query, familyID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao revogar família de tokens: %v", err))
+	}
+	return nil
+}
+
+func (m RefreshTokenModel) RevokeAllForUser(userID int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := m.DB.Exec(query, userID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao revogar tokens do usuário: %v", err))
+	}
+	return nil
+}
+
+func (m RefreshTokenModel) RevokeFamilyOf(plaintext string, userID int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE revoked_at IS NULL AND family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		)
+	`
+
+	_, err := m.DB.Exec(query, hashToken(plaintext), userID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao revogar família de tokens: %v", err))
+	}
+	return nil
+}
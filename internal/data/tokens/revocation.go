@@ -0,0 +1,173 @@
+package tokens
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrSessionUserNotFound = errors.New("Usuário da sessão não encontrado")
+
+// SessionState is what the revocation store knows about a user's sessions.
+// Tokens issued before Cutoff are invalid, i.e. before the last password
+// change or "log out everywhere".
+type SessionState struct {
+	Cutoff            time.Time
+	Disabled          bool
+	MustResetPassword bool
+}
+
+// RevocationStore keeps track of access tokens that must be rejected before
+// they expire. Revoked token IDs and per-user session cutoffs are persisted in
+// Postgres and cached in memory, so checking a token on every request does
+// not require a round trip to the database.
+type RevocationStore struct {
+	DB       *sql.DB
+	CacheTTL time.Duration
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time
+	loadedAt time.Time
+	sessions map[int64]sessionEntry
+}
+
+type sessionEntry struct {
+	state    SessionState
+	loadedAt time.Time
+}
+
+func NewRevocationStore(db *sql.DB, cacheTTL time.Duration) *RevocationStore {
+	return &RevocationStore{
+		DB:       db,
+		CacheTTL: cacheTTL,
+		revoked:  map[string]time.Time{},
+		sessions: map[int64]sessionEntry{},
+	}
+}
+
+func (s *RevocationStore) Revoke(jti string, userID int64, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := s.DB.Exec(query, jti, userID, expiresAt)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao revogar token: %v", err))
+	}
+
+	s.mu.Lock()
+	s.revoked[jti] = expiresAt
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > s.CacheTTL
+	_, revoked := s.revoked[jti]
+	s.mu.RUnlock()
+
+	if stale {
+		if err := s.reload(); err != nil {
+			return false, err
+		}
+		s.mu.RLock()
+		_, revoked = s.revoked[jti]
+		s.mu.RUnlock()
+	}
+
+	return revoked, nil
+}
+
+func (s *RevocationStore) reload() error {
+	query := `
+		SELECT jti, expires_at
+		FROM revoked_tokens
+		WHERE expires_at > CURRENT_TIMESTAMP
+	`
+
+	rows, err := s.DB.Query(query)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	revoked := map[string]time.Time{}
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return err
+		}
+		revoked[jti] = expiresAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revoked = revoked
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *RevocationStore) SessionState(userID int64) (SessionState, error) {
+	s.mu.RLock()
+	entry, ok := s.sessions[userID]
+	s.mu.RUnlock()
+
+	if ok && time.Since(entry.loadedAt) <= s.CacheTTL {
+		return entry.state, nil
+	}
+
+	query := `
+		SELECT GREATEST(password_changed_at, COALESCE(sessions_revoked_at, password_changed_at)),
+			disabled_at IS NOT NULL,
+			must_reset_password
+		FROM users
+		WHERE id = $1
+	`
+
+	state := SessionState{}
+	err := s.DB.QueryRow(query, userID).Scan(&state.Cutoff, &state.Disabled, &state.MustResetPassword)
+	if err == sql.ErrNoRows {
+		return SessionState{}, ErrSessionUserNotFound
+	} else if err != nil {
+		return SessionState{}, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	s.mu.Lock()
+	s.sessions[userID] = sessionEntry{state: state, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return state, nil
+}
+
+// RevokeAllSessions ends every session of the user. The cutoff is taken from
+// this process's clock, the same one that stamps iat_us on new tokens, so a
+// database clock running ahead cannot reject tokens issued right after it.
+func (s *RevocationStore) RevokeAllSessions(userID int64) error {
+	query := `
+		UPDATE users
+		SET sessions_revoked_at = $2
+		WHERE id = $1
+	`
+
+	_, err := s.DB.Exec(query, userID, time.Now())
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao revogar sessões do usuário: %v", err))
+	}
+
+	s.InvalidateUser(userID)
+	return nil
+}
+
+func (s *RevocationStore) InvalidateUser(userID int64) {
+	s.mu.Lock()
+	delete(s.sessions, userID)
+	s.mu.Unlock()
+}
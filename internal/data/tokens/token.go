@@ -0,0 +1,61 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        int64
+	Plaintext string
+	Hash      []byte
+	UserID    int64
+	FamilyID  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NewRefreshToken creates an opaque refresh token. Only the SHA-256 hash of
+// the plaintext is ever persisted; an empty familyID starts a new family.
+func NewRefreshToken(userID int64, ttl time.Duration, familyID string) (*RefreshToken, error) {
+	if familyID == "" {
+		id, err := NewUUID()
+		if err != nil {
+			return nil, err
+		}
+		familyID = id
+	}
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+
+	token := &RefreshToken{
+		Plaintext: base64.RawURLEncoding.EncodeToString(randomBytes),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	token.Hash = hashToken(token.Plaintext)
+
+	return token, nil
+}
+
+func hashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
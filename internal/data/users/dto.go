@@ -7,11 +7,10 @@ import (
 )
 
 type CreateUserDTO struct {
-	Email    string   `json:"email"`
-	Password string   `json:"password"`
-	Name     string   `json:"name"`
-	ImageURL string   `json:"imageUrl"`
-	Role     UserRole `json:"role"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	ImageURL string `json:"imageUrl"`
 }
 
 func (dto CreateUserDTO) Validate(v *validator.Validator) {
@@ -19,9 +18,6 @@ func (dto CreateUserDTO) Validate(v *validator.Validator) {
 	v.Check(dto.Password != "", "senha", "é obrigatória")
 	v.Check(dto.Email != "", "senha", "é obrigatório")
 
-	validRole := dto.Role == "user" || dto.Role == "admin" || dto.Role == "collaborator"
-	v.Check(validRole, "role", "deve ser uma das opções (admin|collaborator|user)")
-
 	_, err := mail.ParseAddress(dto.Email)
 	validMail := err == nil
 	v.Check(validMail, "email", "deve ser um endereço de email válido")
@@ -42,22 +38,34 @@ func (dto LoginUserDTO) Validate(v *validator.Validator) {
 }
 
 type UpdateUserDTO struct {
-	Email    string   `json:"email,omitempty"`
-	Password string   `json:"password,omitempty"`
-	Name     string   `json:"name,omitempty"`
-	ImageURL string   `json:"imageUrl,omitempty"`
-	Role     UserRole `json:"role,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+	Name     string `json:"name,omitempty"`
+	ImageURL string `json:"imageUrl,omitempty"`
 }
 
 func (dto UpdateUserDTO) Validate(v *validator.Validator) {
-	if dto.Role != "" {
-		validRole := dto.Role == "user" || dto.Role == "admin" || dto.Role == "collaborator"
-		v.Check(validRole, "role", "deve ser uma das opções (admin|collaborator|user)")
-	}
-
 	if dto.Email != "" {
 		_, err := mail.ParseAddress(dto.Email)
 		validMail := err == nil
 		v.Check(validMail, "email", "deve ser um endereço de email válido")
 	}
 }
+
+type ChangeRoleDTO struct {
+	Role   UserRole `json:"role"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+func (dto ChangeRoleDTO) Validate(v *validator.Validator) {
+	v.Check(dto.Role.Valid(), "role", "deve ser uma das opções (admin|collaborator|user)")
+	v.Check(len(dto.Reason) <= 1000, "reason", "não deve ter mais de 1000 caracteres")
+}
+
+type ReviewRoleRequestDTO struct {
+	Note string `json:"note,omitempty"`
+}
+
+func (dto ReviewRoleRequestDTO) Validate(v *validator.Validator) {
+	v.Check(len(dto.Note) <= 1000, "note", "não deve ter mais de 1000 caracteres")
+}
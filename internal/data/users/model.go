@@ -4,52 +4,128 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/filters"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
+)
+
+var (
+	ErrUserNotFound = errors.New("Usuário não encontrado")
+	ErrUserDisabled = errors.New("Conta de usuário desativada")
 )
 
 type UserModel struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Hasher password.Hasher
+}
+
+var (
+	dummyOnce sync.Once
+	dummyHash string
+)
+
+// verifyDummy spends the same time as verifying a real password, so that
+// unknown emails cannot be told apart from wrong passwords by timing.
+func (m UserModel) verifyDummy(plaintext string) {
+	dummyOnce.Do(func() {
+		dummyHash, _ = m.Hasher.Hash("senha-inexistente")
+	})
+	m.Hasher.Verify(plaintext, dummyHash)
 }
 
 func (m UserModel) Insert(user *User) error {
+	hash, err := m.Hasher.Hash(user.Password)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao gerar hash da senha: %v", err))
+	}
+	user.Password = hash
+
 	query := `
-	INSERT INTO users (email, password, name, role,image_url)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO users (email, password, name, role,image_url, password_changed_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	RETURNING id, created_at 
 	`
 
-	args := []any{user.Email, user.Password, user.Name, user.Role, user.ImageURL}
+	args := []any{user.Email, user.Password, user.Name, user.Role, user.ImageURL, time.Now()}
 	return m.DB.QueryRow(query, args...).Scan(&user.ID, &user.CreatedAt)
 }
 
-func (m UserModel) Authenticate(email, password string) (*User, error) {
+func (m UserModel) Authenticate(email, plaintext string) (*User, error) {
 	query := `
-	SELECT id, email, name, role, image_url, created_at
+	SELECT id, email, password, name, role, image_url, created_at, disabled_at, must_reset_password
 	FROM users
-	WHERE email = $1 AND password = $2
+	WHERE email = $1
 	`
 
 	user := User{}
-	err := m.DB.QueryRow(query, email, password).Scan(
+	err := m.DB.QueryRow(query, email).Scan(
 		&user.ID,
 		&user.Email,
+		&user.Password,
 		&user.Name,
 		&user.Role,
 		&user.ImageURL,
 		&user.CreatedAt,
+		&user.DisabledAt,
+		&user.MustResetPassword,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("Usuário não encontrado")
+		m.verifyDummy(plaintext)
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
 	}
 
+	err = m.Hasher.Verify(plaintext, user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, ErrUserDisabled
+	}
+
+	// The password was right: a failed upgrade of its hash is retried on the
+	// next login instead of refusing this one.
+	if m.Hasher.NeedsRehash(user.Password) {
+		err = m.rehash(&user, plaintext)
+		if err != nil {
+			slog.Error(err.Error(), "user_id", user.ID)
+		}
+	}
+
 	return &user, nil
 }
 
+func (m UserModel) rehash(user *User, plaintext string) error {
+	hash, err := m.Hasher.Hash(plaintext)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao gerar hash da senha: %v", err))
+	}
+
+	query := `
+		UPDATE users
+		SET password = $1
+		WHERE id = $2 AND password = $3
+	`
+
+	_, err = m.DB.Exec(query, hash, user.ID, user.Password)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Atualização do hash da senha falhou com erro: %v", err))
+	}
+
+	user.Password = hash
+	return nil
+}
+
 func (m UserModel) Get(id int64) (*User, error) {
 	query := `
-		SELECT id, email, name, role, image_url, created_at
+		SELECT id, email, name, role, image_url, created_at, disabled_at, must_reset_password
 		FROM users
 		WHERE id = $1
 	`
@@ -62,10 +138,40 @@ func (m UserModel) Get(id int64) (*User, error) {
 		&user.Role,
 		&user.ImageURL,
 		&user.CreatedAt,
+		&user.DisabledAt,
+		&user.MustResetPassword,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	return &user, nil
+}
+
+func (m UserModel) GetByEmail(email string) (*User, error) {
+	query := `
+		SELECT id, email, name, role, image_url, created_at, disabled_at, must_reset_password
+		FROM users
+		WHERE email = $1
+	`
+
+	user := User{}
+	err := m.DB.QueryRow(query, email).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.Role,
+		&user.ImageURL,
+		&user.CreatedAt,
+		&user.DisabledAt,
+		&user.MustResetPassword,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("Usuário não encontrado")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
 	}
@@ -73,6 +179,21 @@ func (m UserModel) Get(id int64) (*User, error) {
 	return &user, nil
 }
 
+func (m UserModel) CountByRole(role UserRole) (int, error) {
+	query := `
+		SELECT count(*)
+		FROM users
+		WHERE role = $1
+	`
+
+	var count int
+	err := m.DB.QueryRow(query, role).Scan(&count)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return count, nil
+}
+
 func (m UserModel) Update(id int64, updatedUser *User) (*User, error) {
 	existingUser, err := m.Get(id)
 	if err != nil {
@@ -83,7 +204,11 @@ func (m UserModel) Update(id int64, updatedUser *User) (*User, error) {
 		existingUser.Email = updatedUser.Email
 	}
 	if updatedUser.Password != "" {
-		existingUser.Password = updatedUser.Password
+		hash, err := m.Hasher.Hash(updatedUser.Password)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Falha ao gerar hash da senha: %v", err))
+		}
+		existingUser.Password = hash
 	}
 	if updatedUser.Name != "" {
 		existingUser.Name = updatedUser.Name
@@ -95,14 +220,20 @@ func (m UserModel) Update(id int64, updatedUser *User) (*User, error) {
 		existingUser.ImageURL = updatedUser.ImageURL
 	}
 
+	// password_changed_at is compared with the iat_us of tokens, which comes
+	// from this process's clock, not the database's.
 	query := `
 		UPDATE users
-		SET email = $1, password = $2, name = $3, role = $4, image_url = $5
+		SET email = $1,
+			password = COALESCE(NULLIF($2, ''), password),
+			password_changed_at = CASE WHEN $2 = '' THEN password_changed_at ELSE $7 END,
+			must_reset_password = CASE WHEN $2 = '' THEN must_reset_password ELSE false END,
+			name = $3, role = $4, image_url = $5
 		WHERE id = $6
-		RETURNING id, email, name, role, image_url, created_at
+		RETURNING id, email, name, role, image_url, created_at, disabled_at, must_reset_password
 	`
 
-	err = m.DB.QueryRow(query, existingUser.Email, existingUser.Password, existingUser.Name, existingUser.Role, existingUser.ImageURL, id).
+	err = m.DB.QueryRow(query, existingUser.Email, existingUser.Password, existingUser.Name, existingUser.Role, existingUser.ImageURL, id, time.Now()).
 		Scan(
 			&existingUser.ID,
 			&existingUser.Email,
@@ -110,6 +241,8 @@ func (m UserModel) Update(id int64, updatedUser *User) (*User, error) {
 			&existingUser.Role,
 			&existingUser.ImageURL,
 			&existingUser.CreatedAt,
+			&existingUser.DisabledAt,
+			&existingUser.MustResetPassword,
 		)
 
 	if err != nil {
@@ -136,8 +269,118 @@ func (m UserModel) Delete(id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("Usuário não encontrado")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
+
+type ListFilter struct {
+	Role          UserRole
+	Email         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match literally inside a LIKE pattern, so that a search
+// for "a_b" does not also find "axb".
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func (m UserModel) List(filter ListFilter, f filters.Filters) ([]*User, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, email, name, role, image_url, created_at, disabled_at, must_reset_password
+		FROM users
+		WHERE (role = $1 OR $1 = '')
+		AND (email ILIKE '%%' || $2 || '%%' ESCAPE '\' OR $2 = '')
+		AND (created_at >= $3 OR $3 IS NULL)
+		AND (created_at <= $4 OR $4 IS NULL)
+		ORDER BY %s %s, id ASC
+		LIMIT $5 OFFSET $6
+	`, f.SortColumn(), f.SortDirection())
+
+	args := []any{filter.Role, escapeLike(filter.Email), filter.CreatedAfter, filter.CreatedBefore, f.Limit(), f.Offset()}
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, filters.Metadata{}, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	list := []*User{}
+
+	for rows.Next() {
+		user := User{}
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.Email,
+			&user.Name,
+			&user.Role,
+			&user.ImageURL,
+			&user.CreatedAt,
+			&user.DisabledAt,
+			&user.MustResetPassword,
+		)
+		if err != nil {
+			return nil, filters.Metadata{}, err
+		}
+		list = append(list, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return list, filters.CalculateMetadata(totalRecords, f.Page, f.PageSize), nil
+}
+
+func (m UserModel) SetDisabled(id int64, disabled bool) (*User, error) {
+	query := `
+		UPDATE users
+		SET disabled_at = CASE WHEN $1 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) ELSE NULL END
+		WHERE id = $2
+	`
+
+	result, err := m.DB.Exec(query, disabled, id)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao obter número de linhas afetadas: %v", err))
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return m.Get(id)
+}
+
+func (m UserModel) RequirePasswordReset(id int64) (*User, error) {
+	query := `
+		UPDATE users
+		SET must_reset_password = true
+		WHERE id = $1
+	`
+
+	result, err := m.DB.Exec(query, id)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao obter número de linhas afetadas: %v", err))
+	}
+	if rowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return m.Get(id)
+}
@@ -0,0 +1,22 @@
+package users
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"fulano@example.com", "fulano@example.com"},
+		{"a_b", `a\_b`},
+		{"100%", `100\%`},
+		{`a\b`, `a\\b`},
+		{`\%_`, `\\\%\_`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package users
+
+type Permission string
+
+const (
+	PermProfileRead         Permission = "profile:read"
+	PermProfileWrite        Permission = "profile:write"
+	PermConversationsRead   Permission = "conversations:read"
+	PermConversationsWrite  Permission = "conversations:write"
+	PermConversationsManage Permission = "conversations:manage"
+	PermUsersRead           Permission = "users:read"
+	PermUsersManage         Permission = "users:manage"
+)
+
+var rolePermissions = map[UserRole][]Permission{
+	RoleAdmin: {
+		PermProfileRead,
+		PermProfileWrite,
+		PermConversationsRead,
+		PermConversationsWrite,
+		PermConversationsManage,
+		PermUsersRead,
+		PermUsersManage,
+	},
+	RoleCollaborator: {
+		PermProfileRead,
+		PermProfileWrite,
+		PermConversationsRead,
+		PermConversationsWrite,
+		PermConversationsManage,
+		PermUsersRead,
+	},
+	RoleUser: {
+		PermProfileRead,
+		PermProfileWrite,
+		PermConversationsRead,
+		PermConversationsWrite,
+	},
+}
+
+func (r UserRole) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func (r UserRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
@@ -0,0 +1,50 @@
+package users
+
+import "time"
+
+// UserResponse is the public view of a User. It is the only user type that
+// should ever be handed to writeJSON: credentials are not part of it at all,
+// and fields that are private to the account owner are only filled in when
+// the viewer is allowed to see them.
+type UserResponse struct {
+	ID        int64      `json:"id,string"`
+	Name      string     `json:"name"`
+	ImageURL  string     `json:"image_url,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	Role      UserRole   `json:"role,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	MustResetPassword bool       `json:"must_reset_password,omitempty"`
+	DisabledAt        *time.Time `json:"disabled_at,omitempty"`
+}
+
+type Viewer struct {
+	ID   int64
+	Role UserRole
+}
+
+func NewUserResponse(user *User, viewer Viewer) UserResponse {
+	response := UserResponse{
+		ID:       user.ID,
+		Name:     user.Name,
+		ImageURL: user.ImageURL,
+	}
+
+	if viewer.ID == user.ID || viewer.Role == RoleAdmin {
+		createdAt := user.CreatedAt
+		response.Email = user.Email
+		response.Role = user.Role
+		response.CreatedAt = &createdAt
+		response.MustResetPassword = user.MustResetPassword
+	}
+
+	if viewer.Role == RoleAdmin {
+		response.DisabledAt = user.DisabledAt
+	}
+
+	return response
+}
+
+func (u *User) Self() Viewer {
+	return Viewer{ID: u.ID, Role: u.Role}
+}
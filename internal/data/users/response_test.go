@@ -0,0 +1,91 @@
+package users
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewUserResponseVisibility(t *testing.T) {
+	disabled := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	user := &User{
+		ID:                7,
+		CreatedAt:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Email:             "fulano@example.com",
+		Password:          "$argon2id$v=19$m=65536,t=1,p=4$c2FsdA$aGFzaA",
+		Name:              "Fulano",
+		Role:              RoleCollaborator,
+		DisabledAt:        &disabled,
+		MustResetPassword: true,
+	}
+
+	tests := []struct {
+		name    string
+		viewer  Viewer
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "self",
+			viewer:  user.Self(),
+			present: []string{"id", "name", "email", "role", "created_at", "must_reset_password"},
+			absent:  []string{"disabled_at"},
+		},
+		{
+			name:    "admin",
+			viewer:  Viewer{ID: 1, Role: RoleAdmin},
+			present: []string{"id", "name", "email", "role", "created_at", "must_reset_password", "disabled_at"},
+		},
+		{
+			name:    "another collaborator",
+			viewer:  Viewer{ID: 2, Role: RoleCollaborator},
+			present: []string{"id", "name"},
+			absent:  []string{"email", "role", "created_at", "must_reset_password", "disabled_at"},
+		},
+		{
+			name:    "another user",
+			viewer:  Viewer{ID: 3, Role: RoleUser},
+			present: []string{"id", "name"},
+			absent:  []string{"email", "role", "created_at", "must_reset_password", "disabled_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js, err := json.Marshal(NewUserResponse(user, tt.viewer))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(string(js), user.Password) || strings.Contains(string(js), `"password"`) {
+				t.Fatalf("response leaks the password: %s", js)
+			}
+
+			fields := map[string]any{}
+			if err := json.Unmarshal(js, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.present {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("missing %q in %s", key, js)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := fields[key]; ok {
+					t.Errorf("unexpected %q in %s", key, js)
+				}
+			}
+		})
+	}
+}
+
+func TestUserEntityDoesNotSerializePassword(t *testing.T) {
+	js, err := json.Marshal(User{ID: 1, Password: "hash-secreto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(js), "hash-secreto") {
+		t.Fatalf("User serializes its password: %s", js)
+	}
+}
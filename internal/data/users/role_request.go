@@ -0,0 +1,236 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type RoleRequestStatus string
+
+const (
+	RoleRequestPending  RoleRequestStatus = "pending"
+	RoleRequestApproved RoleRequestStatus = "approved"
+	RoleRequestRejected RoleRequestStatus = "rejected"
+)
+
+var (
+	ErrRoleRequestNotFound   = errors.New("Solicitação de mudança de papel não encontrada")
+	ErrRoleRequestNotPending = errors.New("Solicitação de mudança de papel já foi analisada")
+	ErrRoleRequestDuplicate  = errors.New("Já existe uma solicitação de mudança de papel pendente")
+	ErrRoleRequestStale      = errors.New("O papel do usuário mudou desde a solicitação")
+)
+
+type RoleChangeRequest struct {
+	ID            int64             `json:"id,string"`
+	UserID        int64             `json:"user_id,string"`
+	PreviousRole  UserRole          `json:"previous_role"`
+	RequestedRole UserRole          `json:"requested_role"`
+	Reason        string            `json:"reason"`
+	Status        RoleRequestStatus `json:"status"`
+	ReviewedBy    *int64            `json:"reviewed_by,omitempty,string"`
+	ReviewNote    string            `json:"review_note,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ReviewedAt    *time.Time        `json:"reviewed_at,omitempty"`
+}
+
+type RoleRequestModel struct {
+	DB *sql.DB
+}
+
+const roleRequestColumns = `id, user_id, previous_role, requested_role, reason, status, reviewed_by, review_note, created_at, reviewed_at`
+
+func scanRoleRequest(row interface{ Scan(...any) error }, req *RoleChangeRequest) error {
+	var reviewedBy sql.NullInt64
+	var reviewedAt sql.NullTime
+
+	err := row.Scan(
+		&req.ID,
+		&req.UserID,
+		&req.PreviousRole,
+		&req.RequestedRole,
+		&req.Reason,
+		&req.Status,
+		&reviewedBy,
+		&req.ReviewNote,
+		&req.CreatedAt,
+		&reviewedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if reviewedBy.Valid {
+		req.ReviewedBy = &reviewedBy.Int64
+	}
+	if reviewedAt.Valid {
+		req.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+func (m RoleRequestModel) Insert(req *RoleChangeRequest) error {
+	query := `
+		INSERT INTO role_change_requests (user_id, previous_role, requested_role, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, created_at
+	`
+
+	args := []any{req.UserID, req.PreviousRole, req.RequestedRole, req.Reason}
+	err := m.DB.QueryRow(query, args...).Scan(&req.ID, &req.Status, &req.CreatedAt)
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "role_change_requests_one_pending_idx"` {
+			return ErrRoleRequestDuplicate
+		}
+		return errors.New(fmt.Sprintf("Falha ao inserir solicitação: %v", err))
+	}
+	return nil
+}
+
+func (m RoleRequestModel) Get(id int64) (*RoleChangeRequest, error) {
+	query := `SELECT ` + roleRequestColumns + ` FROM role_change_requests WHERE id = $1`
+
+	req := RoleChangeRequest{}
+	err := scanRoleRequest(m.DB.QueryRow(query, id), &req)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleRequestNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &req, nil
+}
+
+func (m RoleRequestModel) List(userID int64, status RoleRequestStatus) ([]*RoleChangeRequest, error) {
+	query := `
+		SELECT ` + roleRequestColumns + `
+		FROM role_change_requests
+		WHERE (user_id = $1 OR $1 = 0) AND (status = $2 OR $2 = '')
+		ORDER BY created_at DESC
+	`
+
+	rows, err := m.DB.Query(query, userID, status)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	requests := []*RoleChangeRequest{}
+	for rows.Next() {
+		req := RoleChangeRequest{}
+		if err := scanRoleRequest(rows, &req); err != nil {
+			return nil, err
+		}
+		requests = append(requests, &req)
+	}
+
+	return requests, rows.Err()
+}
+
+// Review approves or rejects a pending request. Approving it changes the
+// user's role in the same transaction.
+func (m RoleRequestModel) Review(id, reviewerID int64, approve bool, note string) (*RoleChangeRequest, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + roleRequestColumns + ` FROM role_change_requests WHERE id = $1 FOR UPDATE`
+
+	req := RoleChangeRequest{}
+	err = scanRoleRequest(tx.QueryRow(query, id), &req)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleRequestNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	if req.Status != RoleRequestPending {
+		return nil, ErrRoleRequestNotPending
+	}
+
+	status := RoleRequestRejected
+	if approve {
+		status = RoleRequestApproved
+
+		// The role may have changed since the request was made, in which case
+		// approving it would act on a state the reviewer never saw.
+		result, err := tx.Exec(`UPDATE users SET role = $1 WHERE id = $2 AND role = $3`, req.RequestedRole, req.UserID, req.PreviousRole)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Falha ao alterar papel do usuário: %v", err))
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rows == 0 {
+			return nil, ErrRoleRequestStale
+		}
+	}
+
+	query = `
+		UPDATE role_change_requests
+		SET status = $1, reviewed_by = $2, review_note = $3, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING ` + roleRequestColumns
+
+	err = scanRoleRequest(tx.QueryRow(query, status, reviewerID, note, id), &req)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao atualizar solicitação: %v", err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ChangeRole sets a user's role directly on behalf of an admin. The change is
+// still recorded as an approved request so that every role change has an
+// audit entry.
+func (m RoleRequestModel) ChangeRole(userID, adminID int64, role UserRole, reason string) (*RoleChangeRequest, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current UserRole
+	err = tx.QueryRow(`SELECT role FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	_, err = tx.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, userID)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao alterar papel do usuário: %v", err))
+	}
+
+	_, err = tx.Exec(`
+		UPDATE role_change_requests
+		SET status = 'rejected', reviewed_by = $1, review_note = 'Substituída por alteração direta', reviewed_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND status = 'pending'
+	`, adminID, userID)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao encerrar solicitações pendentes: %v", err))
+	}
+
+	query := `
+		INSERT INTO role_change_requests (user_id, previous_role, requested_role, reason, status, reviewed_by, reviewed_at)
+		VALUES ($1, $2, $3, $4, 'approved', $5, CURRENT_TIMESTAMP)
+		RETURNING ` + roleRequestColumns
+
+	req := RoleChangeRequest{}
+	err = scanRoleRequest(tx.QueryRow(query, userID, current, role, reason, adminID), &req)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao registrar alteração de papel: %v", err))
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
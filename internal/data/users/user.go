@@ -5,11 +5,13 @@ import (
 )
 
 type User struct {
-	ID        int64     `json:"id,string"`
-	CreatedAt time.Time `json:"created_at"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	Name      string    `json:"name"`
-	Role      UserRole  `json:"role"`
-	ImageURL  string    `json:"image_url,omitempty"`
+	ID                int64      `json:"id,string"`
+	CreatedAt         time.Time  `json:"created_at"`
+	Email             string     `json:"email"`
+	Password          string     `json:"-"`
+	Name              string     `json:"name"`
+	Role              UserRole   `json:"role"`
+	ImageURL          string     `json:"image_url,omitempty"`
+	DisabledAt        *time.Time `json:"disabled_at,omitempty"`
+	MustResetPassword bool       `json:"must_reset_password"`
 }
@@ -0,0 +1,447 @@
+package versions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+)
+
+var (
+	ErrVersionNotFound  = errors.New("Versão não encontrada")
+	ErrNotEditable      = errors.New("Versões publicadas não podem ser alteradas")
+	ErrNotValidated     = errors.New("A versão deve ser validada antes de ser publicada")
+	ErrAlreadyActive    = errors.New("A versão já está publicada")
+	ErrNoActiveVersion  = errors.New("Nenhuma versão publicada")
+	ErrNothingToRestore = errors.New("Não há versão anterior para restaurar")
+	ErrEditConflict     = errors.New("A versão foi alterada por outra requisição, tente novamente")
+)
+
+const duplicateNumber = `pq: duplicate key value violates unique constraint "bot_versions_number_key"`
+
+type VersionModel struct {
+	DB *sql.DB
+}
+
+const versionColumns = `id, number, status, notes, snapshot, model, validation_errors, active,
+	created_by, created_at, updated_at, validated_at, published_by, published_at`
+
+// summaryColumns leaves the snapshot and the model out of listings.
+const summaryColumns = `id, number, status, notes, NULL::jsonb, NULL::jsonb, validation_errors, active,
+	created_by, created_at, updated_at, validated_at, published_by, published_at`
+
+func scanVersion(row interface{ Scan(...any) error }, v *Version) error {
+	var snapshot, model, validationErrors []byte
+	err := row.Scan(
+		&v.ID,
+		&v.Number,
+		&v.Status,
+		&v.Notes,
+		&snapshot,
+		&model,
+		&validationErrors,
+		&v.Active,
+		&v.CreatedBy,
+		&v.CreatedAt,
+		&v.UpdatedAt,
+		&v.ValidatedAt,
+		&v.PublishedBy,
+		&v.PublishedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if snapshot != nil {
+		v.Snapshot = &Snapshot{}
+		if err := json.Unmarshal(snapshot, v.Snapshot); err != nil {
+			return err
+		}
+	}
+	if model != nil {
+		v.Model = &nlu.Classifier{}
+		if err := json.Unmarshal(model, v.Model); err != nil {
+			return err
+		}
+	}
+	if validationErrors != nil {
+		if err := json.Unmarshal(validationErrors, &v.ValidationErrors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot copies the editable flows and intents. It must run inside a
+// repeatable read transaction so both tables are read at the same instant.
+func snapshot(tx *sql.Tx) ([]byte, error) {
+	s := Snapshot{Flows: []FlowDefinition{}, Intents: []IntentDefinition{}}
+
+	rows, err := tx.Query(`SELECT id, name, description, definition FROM flows ORDER BY name ASC`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f := FlowDefinition{}
+		var definition []byte
+		if err := rows.Scan(&f.ID, &f.Name, &f.Description, &definition); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(definition, &f.Definition); err != nil {
+			return nil, err
+		}
+		s.Flows = append(s.Flows, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT i.name, i.description, i.responses,
+			ARRAY(SELECT text FROM intent_examples WHERE intent_id = i.id ORDER BY id)
+		FROM intents i
+		ORDER BY i.name ASC
+	`
+
+	rows, err = tx.Query(query)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		i := IntentDefinition{}
+		if err := rows.Scan(&i.Name, &i.Description, pq.Array(&i.Responses), pq.Array(&i.Examples)); err != nil {
+			return nil, err
+		}
+		s.Intents = append(s.Intents, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(s)
+}
+
+func recordEvent(tx *sql.Tx, versionID int64, action Action, actorID int64, previousID *int64) error {
+	query := `
+		INSERT INTO bot_version_events (version_id, action, actor_id, previous_version_id)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := tx.Exec(query, versionID, action, actorID, previousID)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Falha ao registrar evento: %v", err))
+	}
+	return nil
+}
+
+// CreateDraft snapshots the current flows and intents as a new draft
+// numbered after the latest version.
+func (m VersionModel) CreateDraft(createdBy int64, notes string) (*Version, error) {
+	tx, err := m.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	s, err := snapshot(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO bot_versions (number, notes, snapshot, created_by)
+		SELECT COALESCE(MAX(number), 0) + 1, $1, $2, $3 FROM bot_versions
+		RETURNING ` + versionColumns
+
+	v := Version{}
+	err = scanVersion(tx.QueryRow(query, notes, s, createdBy), &v)
+	if err != nil && err.Error() == duplicateNumber {
+		return nil, ErrEditConflict
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Falha ao criar versão: %v", err))
+	}
+
+	if err = recordEvent(tx, v.ID, ActionCreated, createdBy, nil); err != nil {
+		return nil, err
+	}
+
+	return &v, tx.Commit()
+}
+
+// UpdateDraft takes a new snapshot of the current definitions for a version
+// that was not published yet. Any previous validation is discarded.
+func (m VersionModel) UpdateDraft(id int64, actorID int64, notes string) (*Version, error) {
+	tx, err := m.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status Status
+	err = tx.QueryRow(`SELECT status FROM bot_versions WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrVersionNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	if status == StatusPublished {
+		return nil, ErrNotEditable
+	}
+
+	s, err := snapshot(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE bot_versions
+		SET notes = $1, snapshot = $2, status = 'draft', model = NULL, validation_errors = NULL,
+			validated_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+		RETURNING ` + versionColumns
+
+	v := Version{}
+	if err = scanVersion(tx.QueryRow(query, notes, s, id), &v); err != nil {
+		return nil, errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	if err = recordEvent(tx, v.ID, ActionUpdated, actorID, nil); err != nil {
+		return nil, err
+	}
+
+	return &v, tx.Commit()
+}
+
+func (m VersionModel) Get(id int64) (*Version, error) {
+	query := `SELECT ` + versionColumns + ` FROM bot_versions WHERE id = $1`
+
+	v := Version{}
+	err := scanVersion(m.DB.QueryRow(query, id), &v)
+	if err == sql.ErrNoRows {
+		return nil, ErrVersionNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &v, nil
+}
+
+// Active returns the published version new conversations start on.
+func (m VersionModel) Active() (*Version, error) {
+	query := `SELECT ` + versionColumns + ` FROM bot_versions WHERE active`
+
+	v := Version{}
+	err := scanVersion(m.DB.QueryRow(query), &v)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoActiveVersion
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	return &v, nil
+}
+
+func (m VersionModel) List() ([]*Version, error) {
+	rows, err := m.DB.Query(`SELECT ` + summaryColumns + ` FROM bot_versions ORDER BY number DESC`)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	list := []*Version{}
+	for rows.Next() {
+		v := Version{}
+		if err := scanVersion(rows, &v); err != nil {
+			return nil, err
+		}
+		list = append(list, &v)
+	}
+
+	return list, rows.Err()
+}
+
+// Delete removes a version that was never published.
+func (m VersionModel) Delete(id int64) error {
+	result, err := m.DB.Exec(`DELETE FROM bot_versions WHERE id = $1 AND status <> 'published'`, id)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Remoção falhou com erro: %v", err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := m.Get(id); err != nil {
+			return err
+		}
+		return ErrNotEditable
+	}
+	return nil
+}
+
+// SaveValidation records the outcome of validating the version. A valid
+// version keeps the classifier trained from its intents so publishing it
+// does not need to train again.
+func (m VersionModel) SaveValidation(v *Version, actorID int64, errs map[string]string, model *nlu.Classifier) error {
+	status := StatusValidated
+	var validationErrors, classifier []byte
+	var err error
+
+	if len(errs) > 0 {
+		status = StatusInvalid
+		if validationErrors, err = json.Marshal(errs); err != nil {
+			return err
+		}
+	} else if model != nil {
+		if classifier, err = json.Marshal(model); err != nil {
+			return err
+		}
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE bot_versions
+		SET status = $1, validation_errors = $2, model = $3, validated_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status <> 'published' AND updated_at = $5
+		RETURNING ` + versionColumns
+
+	err = scanVersion(tx.QueryRow(query, status, validationErrors, classifier, v.ID, v.UpdatedAt), v)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	if err = recordEvent(tx, v.ID, ActionValidated, actorID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Publish makes the version the active one. Only validated versions, or
+// versions that were published before, may be published.
+func (m VersionModel) Publish(id int64, actorID int64) (*Version, error) {
+	return m.activate(id, actorID, ActionPublished)
+}
+
+// Rollback reactivates the version that was active before the current one
+// was published.
+func (m VersionModel) Rollback(actorID int64) (*Version, error) {
+	query := `
+		SELECT e.previous_version_id
+		FROM bot_version_events e
+		JOIN bot_versions v ON v.id = e.version_id
+		WHERE v.active AND e.action = 'published'
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT 1
+	`
+
+	var previousID *int64
+	err := m.DB.QueryRow(query).Scan(&previousID)
+	if err == sql.ErrNoRows {
+		if _, err := m.Active(); err != nil {
+			return nil, err
+		}
+		return nil, ErrNothingToRestore
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	if previousID == nil {
+		return nil, ErrNothingToRestore
+	}
+
+	return m.activate(*previousID, actorID, ActionRolledBack)
+}
+
+func (m VersionModel) activate(id int64, actorID int64, action Action) (*Version, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status Status
+	var active bool
+	err = tx.QueryRow(`SELECT status, active FROM bot_versions WHERE id = $1 FOR UPDATE`, id).Scan(&status, &active)
+	if err == sql.ErrNoRows {
+		return nil, ErrVersionNotFound
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+
+	switch {
+	case active:
+		return nil, ErrAlreadyActive
+	case status != StatusValidated && status != StatusPublished:
+		return nil, ErrNotValidated
+	}
+
+	var previousID *int64
+	err = tx.QueryRow(`UPDATE bot_versions SET active = FALSE WHERE active RETURNING id`).Scan(&previousID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	query := `
+		UPDATE bot_versions
+		SET status = 'published', active = TRUE, published_by = $1,
+			published_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING ` + versionColumns
+
+	v := Version{}
+	if err = scanVersion(tx.QueryRow(query, actorID, id), &v); err != nil {
+		return nil, errors.New(fmt.Sprintf("Atualização falhou com erro: %v", err))
+	}
+
+	if err = recordEvent(tx, v.ID, action, actorID, previousID); err != nil {
+		return nil, err
+	}
+
+	return &v, tx.Commit()
+}
+
+// Events returns the audit trail of the versions, newest first, optionally
+// restricted to a single version.
+func (m VersionModel) Events(versionID int64, limit int) ([]*Event, error) {
+	query := `
+		SELECT e.id, e.version_id, v.number, e.action, e.actor_id, u.name, e.previous_version_id, e.created_at
+		FROM bot_version_events e
+		JOIN bot_versions v ON v.id = e.version_id
+		LEFT JOIN users u ON u.id = e.actor_id
+		WHERE ($1 = 0 OR e.version_id = $1)
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2
+	`
+
+	rows, err := m.DB.Query(query, versionID, limit)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Query falhou com erro: %v", err))
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		e := Event{}
+		err := rows.Scan(&e.ID, &e.VersionID, &e.VersionNumber, &e.Action, &e.ActorID, &e.ActorName, &e.PreviousVersionID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+
+	return events, rows.Err()
+}
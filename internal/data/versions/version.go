@@ -0,0 +1,143 @@
+package versions
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/flow"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusInvalid   Status = "invalid"
+	StatusValidated Status = "validated"
+	StatusPublished Status = "published"
+)
+
+type Action string
+
+const (
+	ActionCreated    Action = "created"
+	ActionUpdated    Action = "updated"
+	ActionValidated  Action = "validated"
+	ActionPublished  Action = "published"
+	ActionRolledBack Action = "rolled_back"
+)
+
+// Snapshot is a frozen copy of the editable bot definitions. Flows keep the
+// id they have in the flows table so sessions can refer to them.
+type Snapshot struct {
+	Flows   []FlowDefinition   `json:"flows"`
+	Intents []IntentDefinition `json:"intents"`
+}
+
+type FlowDefinition struct {
+	ID          int64           `json:"id,string"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Definition  flow.Definition `json:"definition"`
+}
+
+type IntentDefinition struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Examples    []string `json:"examples"`
+	Responses   []string `json:"responses"`
+}
+
+func (s *Snapshot) Flow(id int64) (*FlowDefinition, bool) {
+	for i := range s.Flows {
+		if s.Flows[i].ID == id {
+			return &s.Flows[i], true
+		}
+	}
+	return nil, false
+}
+
+// Examples returns the training examples of every intent of the snapshot.
+func (s *Snapshot) Examples() []nlu.Example {
+	examples := []nlu.Example{}
+	for _, intent := range s.Intents {
+		for _, text := range intent.Examples {
+			examples = append(examples, nlu.Example{Intent: intent.Name, Text: text})
+		}
+	}
+	return examples
+}
+
+// Responses returns the replies of every intent of the snapshot that has any.
+func (s *Snapshot) Responses() map[string][]string {
+	responses := map[string][]string{}
+	for _, intent := range s.Intents {
+		if len(intent.Responses) > 0 {
+			responses[intent.Name] = intent.Responses
+		}
+	}
+	return responses
+}
+
+// Validate checks every flow of the snapshot, prefixing the errors of each
+// one with its name, and that intents have examples.
+func (s *Snapshot) Validate(v *validator.Validator) {
+	for _, f := range s.Flows {
+		fv := validator.New()
+		f.Definition.Validate(fv)
+		for key, message := range fv.Errors {
+			v.AddError(fmt.Sprintf("flows[%s].%s", f.Name, key), message)
+		}
+	}
+
+	for _, intent := range s.Intents {
+		v.Check(len(intent.Examples) > 0, fmt.Sprintf("intents[%s].examples", intent.Name), "deve conter ao menos um exemplo")
+	}
+}
+
+// Version is a snapshot of the bot definitions going through the draft,
+// validation and publication lifecycle. Exactly one published version is
+// active at a time; conversations started on it stay on it.
+type Version struct {
+	ID               int64             `json:"id,string"`
+	Number           int               `json:"number"`
+	Status           Status            `json:"status"`
+	Notes            string            `json:"notes"`
+	Snapshot         *Snapshot         `json:"snapshot,omitempty"`
+	ValidationErrors map[string]string `json:"validation_errors,omitempty"`
+	Model            *nlu.Classifier   `json:"-"`
+	Active           bool              `json:"active"`
+	CreatedBy        *int64            `json:"created_by,omitempty,string"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	ValidatedAt      *time.Time        `json:"validated_at,omitempty"`
+	PublishedBy      *int64            `json:"published_by,omitempty,string"`
+	PublishedAt      *time.Time        `json:"published_at,omitempty"`
+}
+
+// Editable reports whether the version may still be changed, which is only
+// the case until it is published for the first time.
+func (v *Version) Editable() bool {
+	return v.Status != StatusPublished
+}
+
+type Event struct {
+	ID                int64     `json:"id,string"`
+	VersionID         int64     `json:"version_id,string"`
+	VersionNumber     int       `json:"version_number"`
+	Action            Action    `json:"action"`
+	ActorID           *int64    `json:"actor_id,omitempty,string"`
+	ActorName         *string   `json:"actor_name,omitempty"`
+	PreviousVersionID *int64    `json:"previous_version_id,omitempty,string"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type VersionDTO struct {
+	Notes string `json:"notes,omitempty"`
+}
+
+func (dto VersionDTO) Validate(v *validator.Validator) {
+	v.Check(utf8.RuneCountInString(dto.Notes) <= 2000, "notes", "não deve ter mais de 2000 caracteres")
+}
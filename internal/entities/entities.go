@@ -0,0 +1,260 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type Type string
+
+const (
+	Email  Type = "email"
+	CPF    Type = "cpf"
+	Phone  Type = "phone"
+	Date   Type = "date"
+	Number Type = "number"
+	// Text accepts any non-empty answer as is.
+	Text Type = "text"
+)
+
+var Types = []string{string(Email), string(CPF), string(Phone), string(Date), string(Number), string(Text)}
+
+// Entity is a value found in a message. Text is the excerpt as typed and
+// Value its normalized form: lowercase emails, CPFs and phones as digits only
+// (phones prefixed with +55), dates as YYYY-MM-DD and numbers with a decimal
+// point.
+type Entity struct {
+	Type  Type   `json:"type"`
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+// Extractor finds the first valid entity of its type in a message.
+type Extractor struct {
+	// Now anchors relative dates such as "amanhã". Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (e Extractor) Extract(t Type, message string) (Entity, bool) {
+	switch t {
+	case Email:
+		return find(Email, validator.EmailFinderRX, message, func(s string) (string, bool) {
+			return strings.ToLower(s), true
+		})
+	case CPF:
+		return find(CPF, validator.CPFFinderRX, message, func(s string) (string, bool) {
+			return digits(s), validator.ValidCPF(s)
+		})
+	case Phone:
+		return find(Phone, validator.PhoneFinderRX, message, normalizePhone)
+	case Date:
+		return e.extractDate(message)
+	case Number:
+		if entity, ok := find(Number, validator.NumberFinderRX, message, normalizeNumber); ok {
+			return entity, true
+		}
+		return numberWord(message)
+	case Text:
+		text := strings.TrimSpace(message)
+		return Entity{Type: Text, Text: text, Value: text}, text != ""
+	default:
+		return Entity{}, false
+	}
+}
+
+// ExtractAll returns the first entity of every type found in the message.
+// Numbers written out in words only count when nothing else was found.
+func (e Extractor) ExtractAll(message string) []Entity {
+	found := []Entity{}
+	for _, t := range []Type{Email, CPF, Phone, Date} {
+		if entity, ok := e.Extract(t, message); ok {
+			found = append(found, entity)
+		}
+	}
+
+	if entity, ok := find(Number, validator.NumberFinderRX, message, normalizeNumber); ok {
+		found = append(found, entity)
+	} else if len(found) == 0 {
+		if entity, ok := numberWord(message); ok {
+			found = append(found, entity)
+		}
+	}
+	return found
+}
+
+// endsValue checks the character after a value found by one of the
+// validator finders.
+func endsValue(message string, end int) bool {
+	return end == len(message) || strings.ContainsRune(" \t\r\n)>:;,.!?", rune(message[end]))
+}
+
+// find returns the first match that normalize accepts. Matches are tried in
+// order so that, for instance, an invalid CPF does not hide a valid one
+// later in the message.
+func find(t Type, rx *regexp.Regexp, message string, normalize func(string) (string, bool)) (Entity, bool) {
+	for _, m := range rx.FindAllStringSubmatchIndex(message, -1) {
+		if !endsValue(message, m[3]) {
+			continue
+		}
+		text := message[m[2]:m[3]]
+		if value, ok := normalize(text); ok {
+			return Entity{Type: t, Text: text, Value: value}, true
+		}
+	}
+	return Entity{}, false
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// normalizePhone accepts Brazilian numbers with area code: 10 digits for
+// landlines and 11 for mobiles, optionally preceded by the country code.
+func normalizePhone(s string) (string, bool) {
+	d := digits(s)
+	if len(d) >= 12 && strings.HasPrefix(d, "55") {
+		d = d[2:]
+	}
+	if len(d) != 10 && len(d) != 11 {
+		return "", false
+	}
+	if d[0] == '0' {
+		return "", false
+	}
+	return "+55" + d, true
+}
+
+var thousandsRX = regexp.MustCompile(`^[-+]?\d{1,3}(?:\.\d{3})+$`)
+
+// normalizeNumber reads Brazilian notation, where the dot groups thousands
+// and the comma separates decimals, as well as plain decimals with a dot.
+func normalizeNumber(s string) (string, bool) {
+	if strings.Contains(s, ",") || thousandsRX.MatchString(s) {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64), true
+}
+
+var numberWords = map[string]int{
+	"zero": 0, "um": 1, "uma": 1, "dois": 2, "duas": 2, "três": 3, "tres": 3, "quatro": 4, "cinco": 5,
+	"seis": 6, "sete": 7, "oito": 8, "nove": 9, "dez": 10, "onze": 11, "doze": 12,
+}
+
+// numberWord reads a number written out in words. "um" and "uma" are
+// also articles, as in "quero uma pizza", so they only count when they are
+// the whole answer.
+func numberWord(message string) (Entity, bool) {
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, word := range words {
+		n, ok := numberWords[word]
+		if !ok {
+			continue
+		}
+		if (word == "um" || word == "uma") && len(words) > 1 {
+			continue
+		}
+		return Entity{Type: Number, Text: word, Value: strconv.Itoa(n)}, true
+	}
+	return Entity{}, false
+}
+
+var months = map[string]time.Month{
+	"janeiro": time.January, "fevereiro": time.February, "março": time.March, "marco": time.March,
+	"abril": time.April, "maio": time.May, "junho": time.June, "julho": time.July, "agosto": time.August,
+	"setembro": time.September, "outubro": time.October, "novembro": time.November, "dezembro": time.December,
+}
+
+var (
+	longDateRX     = regexp.MustCompile(`(?i)\b(\d{1,2})\s+de\s+([a-zç]+)(?:\s+de\s+(\d{4}))?`)
+	relativeDateRX = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(hoje|amanhã|amanha|ontem|depois de amanhã|depois de amanha)(?:$|[^\p{L}])`)
+)
+
+// extractDate understands numeric dates (day first, as written in Brazil, or
+// ISO), dates written out such as "5 de maio de 2024", and the words hoje,
+// amanhã, depois de amanhã and ontem. Dates without a year fall in the
+// current year.
+func (e Extractor) extractDate(message string) (Entity, bool) {
+	now := time.Now()
+	if e.Now != nil {
+		now = e.Now()
+	}
+
+	entity, ok := find(Date, validator.DateFinderRX, message, func(s string) (string, bool) {
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+
+		parts := strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '-' || r == '.' })
+		day, _ := strconv.Atoi(parts[0])
+		month, _ := strconv.Atoi(parts[1])
+		year := now.Year()
+		if len(parts) == 3 {
+			year, _ = strconv.Atoi(parts[2])
+			if len(parts[2]) == 2 {
+				year += 2000
+			}
+		}
+		return formatDate(year, time.Month(month), day)
+	})
+	if ok {
+		return entity, true
+	}
+
+	if m := longDateRX.FindStringSubmatch(message); m != nil {
+		if month, ok := months[strings.ToLower(m[2])]; ok {
+			day, _ := strconv.Atoi(m[1])
+			year := now.Year()
+			if m[3] != "" {
+				year, _ = strconv.Atoi(m[3])
+			}
+			if value, ok := formatDate(year, month, day); ok {
+				return Entity{Type: Date, Text: m[0], Value: value}, true
+			}
+		}
+	}
+
+	if m := relativeDateRX.FindStringSubmatch(message); m != nil {
+		offset := 0
+		switch strings.ToLower(m[1]) {
+		case "amanhã", "amanha":
+			offset = 1
+		case "depois de amanhã", "depois de amanha":
+			offset = 2
+		case "ontem":
+			offset = -1
+		}
+		return Entity{Type: Date, Text: m[1], Value: now.AddDate(0, 0, offset).Format("2006-01-02")}, true
+	}
+
+	return Entity{}, false
+}
+
+// formatDate rejects impossible dates such as 31/02 instead of letting
+// time.Date roll them over into the next month.
+func formatDate(year int, month time.Month, day int) (string, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || t.Month() != month || t.Year() != year {
+		return "", false
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day), true
+}
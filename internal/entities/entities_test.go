@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtract(t *testing.T) {
+	e := Extractor{Now: func() time.Time { return time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC) }}
+
+	tests := []struct {
+		name    string
+		t       Type
+		message string
+		value   string
+		ok      bool
+	}{
+		{"email in text", Email, "meu email é (Fulano@Example.com).", "fulano@example.com", true},
+		{"email glued to a word", Email, "emailfulano@example.com", "emailfulano@example.com", true},
+		{"cpf with punctuation", CPF, "cpf: 529.982.247-25", "52998224725", true},
+		{"invalid cpf", CPF, "cpf 111.111.111-11", "", false},
+		{"phone", Phone, "ligue (11) 98765-4321", "+5511987654321", true},
+		{"number with thousands", Number, "são 1.234,5 reais", "1234.5", true},
+		{"number word", Number, "duas pessoas", "2", true},
+		{"article alone", Number, "Uma.", "1", true},
+		{"article before a noun", Number, "quero uma pizza", "", false},
+		{"article with a number word", Number, "um pedido para três", "3", true},
+		{"no number", Number, "nenhum", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, ok := e.Extract(tt.t, tt.message)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (%+v)", ok, tt.ok, entity)
+			}
+			if ok && entity.Value != tt.value {
+				t.Errorf("value = %q, want %q", entity.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestExtractAllNumberWords(t *testing.T) {
+	tests := []struct {
+		message string
+		types   []Type
+	}{
+		{"um", []Type{Number}},
+		{"duas", []Type{Number}},
+		{"fulano@example.com, um abraço", []Type{Email}},
+		{"fulano@example.com e dois", []Type{Email}},
+		{"fulano@example.com e 2", []Type{Email, Number}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			found := Extractor{}.ExtractAll(tt.message)
+			if len(found) != len(tt.types) {
+				t.Fatalf("found %+v, want types %v", found, tt.types)
+			}
+			for i, entity := range found {
+				if entity.Type != tt.types[i] {
+					t.Errorf("found[%d] = %+v, want type %s", i, entity, tt.types[i])
+				}
+			}
+		})
+	}
+}
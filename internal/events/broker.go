@@ -0,0 +1,150 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+)
+
+// Broker fans conversation events out to the subscribers of each
+// conversation. Publishing never blocks: a subscriber whose buffer is full is
+// dropped and its channel closed, and it is expected to resubscribe and
+// resume from the persisted event log.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[int64]map[*Subscription]struct{}
+	buffer int
+	closed bool
+
+	appendMu sync.Mutex
+	appends  map[int64]*appendLock
+}
+
+type appendLock struct {
+	sync.Mutex
+	waiters int
+}
+
+type Subscription struct {
+	C <-chan conversations.Event
+
+	ch             chan conversations.Event
+	conversationID int64
+	broker         *Broker
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		subs:    map[int64]map[*Subscription]struct{}{},
+		buffer:  buffer,
+		appends: map[int64]*appendLock{},
+	}
+}
+
+func (b *Broker) Subscribe(conversationID int64) *Subscription {
+	ch := make(chan conversations.Event, b.buffer)
+	sub := &Subscription{C: ch, ch: ch, conversationID: conversationID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return sub
+	}
+
+	if b.subs[conversationID] == nil {
+		b.subs[conversationID] = map[*Subscription]struct{}{}
+	}
+	b.subs[conversationID][sub] = struct{}{}
+	return sub
+}
+
+func (b *Broker) Publish(event conversations.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs[event.ConversationID] {
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Append persists event with store and publishes it. Appends to the same
+// conversation are serialized, so the IDs the store assigns reach the
+// subscribers in increasing order: otherwise an event could be published
+// after one with a higher ID and be skipped by subscribers resuming from the
+// last ID they saw.
+func (b *Broker) Append(event *conversations.Event, store func(*conversations.Event) error) error {
+	unlock := b.lockConversation(event.ConversationID)
+	defer unlock()
+
+	if err := store(event); err != nil {
+		return err
+	}
+
+	b.Publish(*event)
+	return nil
+}
+
+func (b *Broker) lockConversation(conversationID int64) func() {
+	b.appendMu.Lock()
+	l, ok := b.appends[conversationID]
+	if !ok {
+		l = &appendLock{}
+		b.appends[conversationID] = l
+	}
+	l.waiters++
+	b.appendMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		b.appendMu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(b.appends, conversationID)
+		}
+		b.appendMu.Unlock()
+	}
+}
+
+// Close drops every subscriber. It is meant to be called on shutdown so that
+// long-lived streams end promptly.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	subs, ok := b.subs[sub.conversationID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	close(sub.ch)
+	if len(subs) == 0 {
+		delete(b.subs, sub.conversationID)
+	}
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+)
+
+func TestAppendPublishesInIDOrder(t *testing.T) {
+	const writers, perWriter = 8, 50
+
+	b := NewBroker(writers * perWriter)
+	sub := b.Subscribe(1)
+	defer sub.Close()
+
+	// store behaves like an insert into a table with a sequence: the ID is
+	// taken first, and the insert takes a while to return.
+	var sequence atomic.Int64
+	store := func(event *conversations.Event) error {
+		event.ID = sequence.Add(1)
+		time.Sleep(time.Duration(event.ID%3) * 100 * time.Microsecond)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				event := &conversations.Event{ConversationID: 1, Type: conversations.EventMessage}
+				if err := b.Append(event, store); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var last int64
+	for i := 0; i < writers*perWriter; i++ {
+		event := <-sub.C
+		if event.ID <= last {
+			t.Fatalf("event %d published after event %d", event.ID, last)
+		}
+		last = event.ID
+	}
+
+	if len(b.appends) != 0 {
+		t.Errorf("%d conversation locks left behind", len(b.appends))
+	}
+}
+
+func TestAppendDoesNotPublishFailedEvents(t *testing.T) {
+	b := NewBroker(1)
+	sub := b.Subscribe(1)
+	defer sub.Close()
+
+	failure := errors.New("falha ao inserir")
+	err := b.Append(&conversations.Event{ConversationID: 1}, func(*conversations.Event) error {
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("error = %v, want %v", err, failure)
+	}
+
+	select {
+	case event := <-sub.C:
+		t.Fatalf("failed event was published: %+v", event)
+	default:
+	}
+}
+
+func TestPublishDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(1)
+	slow := b.Subscribe(1)
+	other := b.Subscribe(2)
+	defer other.Close()
+
+	b.Publish(conversations.Event{ID: 1, ConversationID: 1})
+	b.Publish(conversations.Event{ID: 2, ConversationID: 1})
+
+	if event := <-slow.C; event.ID != 1 {
+		t.Fatalf("first event = %d, want 1", event.ID)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber was not dropped")
+	}
+
+	select {
+	case event := <-other.C:
+		t.Fatalf("subscriber of another conversation got %+v", event)
+	default:
+	}
+}
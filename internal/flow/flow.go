@@ -0,0 +1,176 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/entities"
+)
+
+type NodeType string
+
+const (
+	// NodeMessage sends its text and moves on right away.
+	NodeMessage NodeType = "message"
+	// NodeQuestion sends its text and waits for the user's answer, which is
+	// stored in the node's variable before the transitions are evaluated.
+	NodeQuestion NodeType = "question"
+	// NodeEnd sends its text and finishes the flow.
+	NodeEnd NodeType = "end"
+)
+
+type Operator string
+
+const (
+	OpEquals    Operator = "equals"
+	OpNotEquals Operator = "not_equals"
+	OpContains  Operator = "contains"
+	OpMatches   Operator = "matches"
+	OpIn        Operator = "in"
+	OpExists    Operator = "exists"
+	OpGreater   Operator = "gt"
+	OpLess      Operator = "lt"
+)
+
+// Definition is a guided dialog: a graph of nodes, starting at Start, whose
+// transitions are chosen by conditions on the variables collected so far.
+type Definition struct {
+	Start     string            `json:"start"`
+	Variables map[string]string `json:"variables,omitempty"`
+	Nodes     []Node            `json:"nodes"`
+}
+
+type Node struct {
+	ID          string            `json:"id"`
+	Type        NodeType          `json:"type"`
+	Text        string            `json:"text,omitempty"`
+	Variable    string            `json:"variable,omitempty"`
+	Set         map[string]string `json:"set,omitempty"`
+	Slot        *Slot             `json:"slot,omitempty"`
+	Transitions []Transition      `json:"transitions,omitempty"`
+}
+
+// Slot makes a question node collect an entity from the answer. Answers
+// without a valid entity are met with Reprompt until MaxAttempts answers
+// failed; then the flow goes to OnFailure or, when it is not set, follows
+// the transitions with the slot left empty.
+type Slot struct {
+	Name        string        `json:"name"`
+	Entity      entities.Type `json:"entity"`
+	Reprompt    string        `json:"reprompt,omitempty"`
+	MaxAttempts int           `json:"max_attempts,omitempty"`
+	OnFailure   string        `json:"on_failure,omitempty"`
+}
+
+const defaultMaxAttempts = 3
+
+func (s *Slot) maxAttempts() int {
+	if s.MaxAttempts == 0 {
+		return defaultMaxAttempts
+	}
+	return s.MaxAttempts
+}
+
+// targets lists every node the flow may go to from n.
+func (n *Node) targets() []string {
+	targets := make([]string, 0, len(n.Transitions)+1)
+	for _, t := range n.Transitions {
+		targets = append(targets, t.Target)
+	}
+	if n.Slot != nil && n.Slot.OnFailure != "" {
+		targets = append(targets, n.Slot.OnFailure)
+	}
+	return targets
+}
+
+// Transition leads to Target when its condition holds. A transition without
+// a condition always holds, so it works as the default branch when listed
+// last.
+type Transition struct {
+	Target    string     `json:"target"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+type Condition struct {
+	Variable string   `json:"variable"`
+	Operator Operator `json:"operator"`
+	Value    string   `json:"value,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+func Parse(r io.Reader) (*Definition, error) {
+	def := Definition{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("Definição de fluxo inválida: %v", err)
+	}
+	return &def, nil
+}
+
+func (d *Definition) Node(id string) (*Node, bool) {
+	for i := range d.Nodes {
+		if d.Nodes[i].ID == id {
+			return &d.Nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// Holds evaluates the condition against the variables. Comparisons ignore
+// case and surrounding spaces, since values usually come from free text.
+func (c Condition) Holds(vars map[string]string) bool {
+	value := strings.TrimSpace(vars[c.Variable])
+
+	switch c.Operator {
+	case OpEquals:
+		return strings.EqualFold(value, strings.TrimSpace(c.Value))
+	case OpNotEquals:
+		return !strings.EqualFold(value, strings.TrimSpace(c.Value))
+	case OpContains:
+		return strings.Contains(strings.ToLower(value), strings.ToLower(c.Value))
+	case OpMatches:
+		rx, err := regexp.Compile(c.Value)
+		return err == nil && rx.MatchString(value)
+	case OpIn:
+		for _, v := range c.Values {
+			if strings.EqualFold(value, strings.TrimSpace(v)) {
+				return true
+			}
+		}
+		return false
+	case OpExists:
+		return value != ""
+	case OpGreater, OpLess:
+		a, errA := parseNumber(value)
+		b, errB := parseNumber(c.Value)
+		if errA != nil || errB != nil {
+			return false
+		}
+		if c.Operator == OpGreater {
+			return a > b
+		}
+		return a < b
+	default:
+		return false
+	}
+}
+
+// parseNumber accepts both the decimal point and the Brazilian decimal comma.
+func parseNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", ".", 1), 64)
+}
+
+var placeholderRX = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_]*)\s*}}`)
+
+// Render replaces {{name}} placeholders in text with the variable values.
+func Render(text string, vars map[string]string) string {
+	return placeholderRX.ReplaceAllStringFunc(text, func(m string) string {
+		name := placeholderRX.FindStringSubmatch(m)[1]
+		return vars[name]
+	})
+}
@@ -0,0 +1,171 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/entities"
+)
+
+// maxSteps bounds how many nodes a single call may go through, guarding
+// against loops made only of message nodes, which never wait for input.
+const maxSteps = 100
+
+var ErrFlowFinished = errors.New("O fluxo já foi encerrado")
+
+// RuntimeError is returned when the definition itself keeps the flow from
+// going on, such as a node with no applicable transition. Validate rejects
+// most of these, but transitions depend on the variables of each session.
+type RuntimeError struct {
+	Node    string
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+// State is where a session stands in a flow: the node waiting for an answer
+// and the variables collected so far.
+type State struct {
+	Node      string            `json:"node"`
+	Variables map[string]string `json:"variables"`
+	Attempts  int               `json:"attempts"`
+	Done      bool              `json:"done"`
+}
+
+// SlotValue is an entity collected for a slot.
+type SlotValue struct {
+	Name string `json:"name"`
+	entities.Entity
+}
+
+// Output is what a single call to Begin or Advance produced: the messages to
+// send to the user, in order.
+type Output struct {
+	Messages []string    `json:"messages"`
+	Slots    []SlotValue `json:"slots"`
+}
+
+// Begin enters the start node with the default variables and runs until the
+// flow waits for an answer or ends.
+func (d *Definition) Begin() (State, Output, error) {
+	state := State{Variables: map[string]string{}}
+	for name, value := range d.Variables {
+		state.Variables[name] = value
+	}
+
+	out := Output{Messages: []string{}, Slots: []SlotValue{}}
+	err := d.run(&state, d.Start, &out)
+	return state, out, err
+}
+
+// Advance stores input as the answer to the current question and follows
+// the first transition whose condition holds. When none holds the question
+// is asked again. Questions with a slot first extract its entity from the
+// input, reprompting while it is missing.
+func (d *Definition) Advance(state State, input string) (State, Output, error) {
+	return d.AdvanceWith(entities.Extractor{}, state, input)
+}
+
+func (d *Definition) AdvanceWith(extractor entities.Extractor, state State, input string) (State, Output, error) {
+	out := Output{Messages: []string{}, Slots: []SlotValue{}}
+
+	if state.Done {
+		return state, out, ErrFlowFinished
+	}
+
+	node, ok := d.Node(state.Node)
+	if !ok {
+		return state, out, &RuntimeError{Node: state.Node, Message: fmt.Sprintf("Nó atual %q não existe no fluxo", state.Node)}
+	}
+
+	next := State{Node: state.Node, Variables: map[string]string{}, Attempts: state.Attempts}
+	for name, value := range state.Variables {
+		next.Variables[name] = value
+	}
+
+	name := node.Variable
+	if name == "" {
+		name = "input"
+	}
+	next.Variables[name] = input
+
+	if slot := node.Slot; slot != nil {
+		entity, ok := extractor.Extract(slot.Entity, input)
+		if ok {
+			next.Variables[slot.Name] = entity.Value
+			out.Slots = append(out.Slots, SlotValue{Name: slot.Name, Entity: entity})
+		} else {
+			next.Attempts++
+			if next.Attempts < slot.maxAttempts() {
+				reprompt := slot.Reprompt
+				if reprompt == "" {
+					reprompt = node.Text
+				}
+				out.Messages = append(out.Messages, Render(reprompt, next.Variables))
+				return next, out, nil
+			}
+
+			if slot.OnFailure != "" {
+				next.Attempts = 0
+				err := d.run(&next, slot.OnFailure, &out)
+				return next, out, err
+			}
+		}
+		next.Attempts = 0
+	}
+
+	target, ok := node.next(next.Variables)
+	if !ok {
+		out.Messages = append(out.Messages, Render(node.Text, next.Variables))
+		return next, out, nil
+	}
+
+	err := d.run(&next, target, &out)
+	return next, out, err
+}
+
+// run enters nodes starting at id until one waits for input or ends the
+// flow.
+func (d *Definition) run(state *State, id string, out *Output) error {
+	for step := 0; step < maxSteps; step++ {
+		node, ok := d.Node(id)
+		if !ok {
+			return &RuntimeError{Node: id, Message: fmt.Sprintf("Nó %q não existe no fluxo", id)}
+		}
+
+		state.Node = node.ID
+		for name, value := range node.Set {
+			state.Variables[name] = Render(value, state.Variables)
+		}
+		if node.Text != "" {
+			out.Messages = append(out.Messages, Render(node.Text, state.Variables))
+		}
+
+		switch node.Type {
+		case NodeQuestion:
+			return nil
+		case NodeEnd:
+			state.Done = true
+			return nil
+		}
+
+		target, ok := node.next(state.Variables)
+		if !ok {
+			return &RuntimeError{Node: node.ID, Message: fmt.Sprintf("Nenhuma transição do nó %q se aplica", node.ID)}
+		}
+		id = target
+	}
+
+	return &RuntimeError{Node: id, Message: fmt.Sprintf("O fluxo excedeu %d passos sem aguardar resposta", maxSteps)}
+}
+
+func (n *Node) next(vars map[string]string) (string, bool) {
+	for _, t := range n.Transitions {
+		if t.Condition == nil || t.Condition.Holds(vars) {
+			return t.Target, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,281 @@
+package flow
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConditionHolds(t *testing.T) {
+	vars := map[string]string{
+		"plano":  " Premium ",
+		"texto":  "Quero CANCELAR meu pedido",
+		"cep":    "01310-100",
+		"valor":  "10,5",
+		"idade":  "17",
+		"vazio":  "",
+		"nome":   "abc",
+		"espaco": "   ",
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{"equals ignores case and spaces", Condition{Variable: "plano", Operator: OpEquals, Value: "premium"}, true},
+		{"equals", Condition{Variable: "plano", Operator: OpEquals, Value: "básico"}, false},
+		{"not_equals", Condition{Variable: "plano", Operator: OpNotEquals, Value: "básico"}, true},
+		{"not_equals ignores case", Condition{Variable: "plano", Operator: OpNotEquals, Value: "PREMIUM"}, false},
+		{"contains ignores case", Condition{Variable: "texto", Operator: OpContains, Value: "cancelar"}, true},
+		{"contains", Condition{Variable: "texto", Operator: OpContains, Value: "trocar"}, false},
+		{"matches", Condition{Variable: "cep", Operator: OpMatches, Value: `^\d{5}-\d{3}$`}, true},
+		{"matches", Condition{Variable: "nome", Operator: OpMatches, Value: `^\d+$`}, false},
+		{"matches with an invalid pattern", Condition{Variable: "nome", Operator: OpMatches, Value: `(`}, false},
+		{"in ignores case", Condition{Variable: "plano", Operator: OpIn, Values: []string{"básico", "PREMIUM "}}, true},
+		{"in", Condition{Variable: "plano", Operator: OpIn, Values: []string{"básico"}}, false},
+		{"exists", Condition{Variable: "nome", Operator: OpExists}, true},
+		{"exists on an empty value", Condition{Variable: "vazio", Operator: OpExists}, false},
+		{"exists on blanks", Condition{Variable: "espaco", Operator: OpExists}, false},
+		{"exists on a missing variable", Condition{Variable: "nada", Operator: OpExists}, false},
+		{"gt with a decimal comma", Condition{Variable: "valor", Operator: OpGreater, Value: "10,4"}, true},
+		{"gt with a decimal point", Condition{Variable: "valor", Operator: OpGreater, Value: "10.5"}, false},
+		{"gt", Condition{Variable: "idade", Operator: OpGreater, Value: "17,5"}, false},
+		{"lt with a decimal comma", Condition{Variable: "valor", Operator: OpLess, Value: "10,6"}, true},
+		{"lt", Condition{Variable: "idade", Operator: OpLess, Value: "17"}, false},
+		{"gt on text", Condition{Variable: "nome", Operator: OpGreater, Value: "1"}, false},
+		{"lt on text", Condition{Variable: "nome", Operator: OpLess, Value: "1"}, false},
+		{"unknown operator", Condition{Variable: "nome", Operator: "starts_with", Value: "a"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.condition.Holds(vars); got != tt.want {
+			t.Errorf("%s: %+v = %t, want %t", tt.name, tt.condition, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	vars := map[string]string{"nome": "Ana", "pedido_2": "42"}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Olá, {{nome}}!", "Olá, Ana!"},
+		{"Pedido {{ pedido_2 }} de {{nome}}", "Pedido 42 de Ana"},
+		{"Sem {{valor}} definido", "Sem  definido"},
+		{"Chaves {{ 1x }} e {nome}", "Chaves {{ 1x }} e {nome}"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Render(tt.text, vars); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+const supportFlow = `{"start":"welcome","variables":{"canal":"chat"},"nodes":[
+	{"id":"welcome","type":"message","text":"Olá pelo {{canal}}!","transitions":[{"target":"name"}]},
+	{"id":"name","type":"question","text":"Qual é o seu nome?","variable":"nome","transitions":[
+		{"condition":{"variable":"nome","operator":"exists"},"target":"amount"}]},
+	{"id":"amount","type":"question","text":"{{nome}}, qual o valor do pedido?","variable":"valor","transitions":[
+		{"condition":{"variable":"valor","operator":"gt","value":"100,5"},"target":"vip"},
+		{"target":"regular"}]},
+	{"id":"vip","type":"message","set":{"fila":"prioritária","resumo":"{{nome}} ({{valor}})"},"transitions":[{"target":"done"}]},
+	{"id":"regular","type":"message","set":{"fila":"comum","resumo":"{{nome}} ({{valor}})"},"transitions":[{"target":"done"}]},
+	{"id":"done","type":"end","text":"{{resumo}} vai para a fila {{fila}}."}]}`
+
+func TestAdvanceHappyPath(t *testing.T) {
+	tests := []struct {
+		amount   string
+		queue    string
+		messages []string
+	}{
+		{"100,6", "prioritária", []string{"Ana (100,6) vai para a fila prioritária."}},
+		{"100,5", "comum", []string{"Ana (100,5) vai para a fila comum."}},
+		{"cem", "comum", []string{"Ana (cem) vai para a fila comum."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			d := parseDefinition(t, supportFlow)
+
+			state, out, err := d.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Node != "name" || state.Done {
+				t.Fatalf("after Begin: state = %+v, want to wait at name", state)
+			}
+			if want := []string{"Olá pelo chat!", "Qual é o seu nome?"}; !reflect.DeepEqual(out.Messages, want) {
+				t.Errorf("after Begin: messages = %q, want %q", out.Messages, want)
+			}
+
+			state, out, err = d.Advance(state, "Ana")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Node != "amount" || state.Variables["nome"] != "Ana" {
+				t.Fatalf("after the name: state = %+v", state)
+			}
+			if want := []string{"Ana, qual o valor do pedido?"}; !reflect.DeepEqual(out.Messages, want) {
+				t.Errorf("after the name: messages = %q, want %q", out.Messages, want)
+			}
+
+			state, out, err = d.Advance(state, tt.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Node != "done" || !state.Done {
+				t.Errorf("after the amount: state = %+v, want done", state)
+			}
+			if state.Variables["fila"] != tt.queue || state.Variables["canal"] != "chat" {
+				t.Errorf("variables = %v", state.Variables)
+			}
+			if !reflect.DeepEqual(out.Messages, tt.messages) {
+				t.Errorf("after the amount: messages = %q, want %q", out.Messages, tt.messages)
+			}
+
+			if _, _, err := d.Advance(state, "oi"); !errors.Is(err, ErrFlowFinished) {
+				t.Errorf("Advance after the end: error = %v, want %v", err, ErrFlowFinished)
+			}
+		})
+	}
+}
+
+func TestAdvanceAsksAgainWithoutTransition(t *testing.T) {
+	d := parseDefinition(t, supportFlow)
+	state, _, err := d.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, out, err := d.Advance(state, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Node != "name" || next.Done {
+		t.Errorf("state = %+v, want to wait at name again", next)
+	}
+	if want := []string{"Qual é o seu nome?"}; !reflect.DeepEqual(out.Messages, want) {
+		t.Errorf("messages = %q, want %q", out.Messages, want)
+	}
+	if state.Variables["nome"] != "" {
+		t.Error("Advance changed the variables of the state it was given")
+	}
+}
+
+func TestBeginAtEndNode(t *testing.T) {
+	d := parseDefinition(t, `{"start":"bye","nodes":[{"id":"bye","type":"end","text":"Até logo"}]}`)
+
+	state, out, err := d.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Done || state.Node != "bye" {
+		t.Errorf("state = %+v, want done at bye", state)
+	}
+	if want := []string{"Até logo"}; !reflect.DeepEqual(out.Messages, want) {
+		t.Errorf("messages = %q, want %q", out.Messages, want)
+	}
+}
+
+const slotFlow = `{"start":"ask","variables":{"nome":"Ana"},"nodes":[
+	{"id":"ask","type":"question","text":"Qual é o seu e-mail?","variable":"resposta",
+		"slot":{"name":"email","entity":"email","reprompt":"{{nome}}, não encontrei um e-mail em {{resposta}}.","max_attempts":2,"on_failure":"human"},
+		"transitions":[{"condition":{"variable":"email","operator":"exists"},"target":"thanks"},{"target":"missing"}]},
+	{"id":"ask_default","type":"question","text":"E o seu telefone?",
+		"slot":{"name":"telefone","entity":"phone"},
+		"transitions":[{"condition":{"variable":"telefone","operator":"exists"},"target":"thanks"},{"target":"missing"}]},
+	{"id":"thanks","type":"end","text":"Obrigado!"},
+	{"id":"missing","type":"end","text":"Seguimos sem o contato."},
+	{"id":"human","type":"end","text":"Vou chamar um atendente."}]}`
+
+func TestAdvanceSlot(t *testing.T) {
+	type step struct {
+		input    string
+		node     string
+		attempts int
+		done     bool
+		messages []string
+	}
+
+	tests := []struct {
+		name  string
+		start string
+		steps []step
+		slot  string
+		value string
+	}{
+		{
+			name:  "filled at once",
+			start: "ask",
+			steps: []step{{"ana@exemplo.com", "thanks", 0, true, []string{"Obrigado!"}}},
+			slot:  "email",
+			value: "ana@exemplo.com",
+		},
+		{
+			name:  "reprompt then filled",
+			start: "ask",
+			steps: []step{
+				{"não sei", "ask", 1, false, []string{"Ana, não encontrei um e-mail em não sei."}},
+				{"é ana@exemplo.com", "thanks", 0, true, []string{"Obrigado!"}},
+			},
+			slot:  "email",
+			value: "ana@exemplo.com",
+		},
+		{
+			name:  "on_failure after max_attempts",
+			start: "ask",
+			steps: []step{
+				{"não sei", "ask", 1, false, []string{"Ana, não encontrei um e-mail em não sei."}},
+				{"depois", "human", 0, true, []string{"Vou chamar um atendente."}},
+			},
+			slot: "email",
+		},
+		{
+			name:  "default attempts fall through with the slot empty",
+			start: "ask_default",
+			steps: []step{
+				{"não tenho", "ask_default", 1, false, []string{"E o seu telefone?"}},
+				{"não lembro", "ask_default", 2, false, []string{"E o seu telefone?"}},
+				{"prefiro não dizer", "missing", 0, true, []string{"Seguimos sem o contato."}},
+			},
+			slot: "telefone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := parseDefinition(t, slotFlow)
+			state := State{Node: tt.start, Variables: map[string]string{"nome": "Ana"}}
+
+			var out Output
+			for i, s := range tt.steps {
+				var err error
+				state, out, err = d.Advance(state, s.input)
+				if err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				if state.Node != s.node || state.Attempts != s.attempts || state.Done != s.done {
+					t.Errorf("step %d: state = %+v, want node %s, %d attempts, done %t", i, state, s.node, s.attempts, s.done)
+				}
+				if !reflect.DeepEqual(out.Messages, s.messages) {
+					t.Errorf("step %d: messages = %q, want %q", i, out.Messages, s.messages)
+				}
+			}
+
+			if got := state.Variables[tt.slot]; got != tt.value {
+				t.Errorf("%s = %q, want %q", tt.slot, got, tt.value)
+			}
+			if tt.value == "" && len(out.Slots) != 0 {
+				t.Errorf("slots = %+v, want none", out.Slots)
+			}
+			if tt.value != "" && (len(out.Slots) != 1 || out.Slots[0].Name != tt.slot || out.Slots[0].Entity.Value != tt.value) {
+				t.Errorf("slots = %+v, want %s = %q", out.Slots, tt.slot, tt.value)
+			}
+		})
+	}
+}
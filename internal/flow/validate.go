@@ -0,0 +1,269 @@
+package flow
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/entities"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+var (
+	nodeIDRX   = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	variableRX = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+)
+
+const maxNodes = 500
+
+// Validate checks the structure of the definition and then its graph:
+// transitions pointing to missing nodes, nodes that cannot be reached from
+// the start, cycles the user can never leave and cycles of message nodes,
+// which never wait for input. Errors are keyed by the path of the offending
+// field, e.g. nodes[menu].transitions[1].target.
+func (d *Definition) Validate(v *validator.Validator) {
+	v.Check(d.Start != "", "start", "é obrigatório")
+	v.Check(len(d.Nodes) > 0, "nodes", "deve conter ao menos um nó")
+	v.Check(len(d.Nodes) <= maxNodes, "nodes", fmt.Sprintf("não deve ter mais de %d nós", maxNodes))
+
+	for name := range d.Variables {
+		v.Check(variableRX.MatchString(name), "variables", fmt.Sprintf("nome de variável inválido: %q", name))
+	}
+
+	ids := map[string]bool{}
+	for i, node := range d.Nodes {
+		key := fmt.Sprintf("nodes[%d]", i)
+		if !nodeIDRX.MatchString(node.ID) {
+			v.AddError(key+".id", "deve ter entre 1 e 64 letras, números, _ ou -")
+			continue
+		}
+		if ids[node.ID] {
+			v.AddError(key+".id", fmt.Sprintf("identificador duplicado: %q", node.ID))
+			continue
+		}
+		ids[node.ID] = true
+	}
+
+	if d.Start != "" && !ids[d.Start] {
+		v.AddError("start", fmt.Sprintf("nó inicial inexistente: %q", d.Start))
+	}
+
+	for _, node := range d.Nodes {
+		d.validateNode(v, node, ids)
+	}
+
+	if !v.Valid() {
+		return
+	}
+
+	d.validateGraph(v)
+}
+
+func (d *Definition) validateNode(v *validator.Validator, node Node, ids map[string]bool) {
+	key := fmt.Sprintf("nodes[%s]", node.ID)
+
+	switch node.Type {
+	case NodeMessage, NodeQuestion:
+		v.Check(len(node.Transitions) > 0, key+".transitions", "deve conter ao menos uma transição; use um nó end para encerrar o fluxo")
+	case NodeEnd:
+		v.Check(len(node.Transitions) == 0, key+".transitions", "nós end não devem ter transições")
+	default:
+		v.AddError(key+".type", "deve ser uma das opções (message|question|end)")
+	}
+
+	v.Check(node.Type == NodeMessage || node.Type == NodeEnd || node.Text != "", key+".text", "é obrigatório em nós question")
+	if node.Type == NodeMessage && len(node.Transitions) > 0 {
+		v.Check(node.Transitions[len(node.Transitions)-1].Condition == nil, key+".transitions",
+			"a última transição de um nó message não deve ter condição, pois ele não espera resposta")
+	}
+	v.Check(node.Variable == "" || variableRX.MatchString(node.Variable), key+".variable", "nome de variável inválido")
+	v.Check(node.Variable == "" || node.Type == NodeQuestion, key+".variable", "só é permitido em nós question")
+
+	for name := range node.Set {
+		v.Check(variableRX.MatchString(name), key+".set", fmt.Sprintf("nome de variável inválido: %q", name))
+	}
+
+	if node.Slot != nil {
+		validateSlot(v, key+".slot", node, ids)
+	}
+
+	for i, t := range node.Transitions {
+		tkey := fmt.Sprintf("%s.transitions[%d]", key, i)
+
+		if t.Target == "" {
+			v.AddError(tkey+".target", "é obrigatório")
+		} else if !ids[t.Target] {
+			v.AddError(tkey+".target", fmt.Sprintf("nó inexistente: %q", t.Target))
+		}
+
+		if t.Condition == nil {
+			v.Check(i == len(node.Transitions)-1, tkey+".condition", "somente a última transição pode não ter condição")
+			continue
+		}
+		validateCondition(v, tkey+".condition", *t.Condition)
+	}
+}
+
+func validateSlot(v *validator.Validator, key string, node Node, ids map[string]bool) {
+	slot := node.Slot
+
+	v.Check(node.Type == NodeQuestion, key, "só é permitido em nós question")
+	v.Check(variableRX.MatchString(slot.Name), key+".name", "nome de variável inválido")
+	v.Check(validator.In(string(slot.Entity), entities.Types...), key+".entity", "deve ser uma das opções ("+strings.Join(entities.Types, "|")+")")
+	v.Check(slot.MaxAttempts >= 0 && slot.MaxAttempts <= 10, key+".max_attempts", "deve estar entre 0 e 10")
+	if slot.OnFailure != "" {
+		v.Check(ids[slot.OnFailure], key+".on_failure", fmt.Sprintf("nó inexistente: %q", slot.OnFailure))
+	}
+}
+
+func validateCondition(v *validator.Validator, key string, c Condition) {
+	v.Check(variableRX.MatchString(c.Variable), key+".variable", "nome de variável inválido")
+
+	switch c.Operator {
+	case OpEquals, OpNotEquals, OpContains:
+	case OpMatches:
+		_, err := regexp.Compile(c.Value)
+		v.Check(err == nil, key+".value", "expressão regular inválida")
+	case OpIn:
+		v.Check(len(c.Values) > 0, key+".values", "deve conter ao menos um valor")
+	case OpExists:
+	case OpGreater, OpLess:
+		_, err := parseNumber(c.Value)
+		v.Check(err == nil, key+".value", "deve ser um número")
+	default:
+		v.AddError(key+".operator", "deve ser uma das opções (equals|not_equals|contains|matches|in|exists|gt|lt)")
+	}
+}
+
+// validateGraph runs once every transition is known to point to an existing
+// node.
+func (d *Definition) validateGraph(v *validator.Validator) {
+	reachable := map[string]bool{d.Start: true}
+	queue := []string{d.Start}
+	for len(queue) > 0 {
+		node, _ := d.Node(queue[0])
+		queue = queue[1:]
+		for _, target := range node.targets() {
+			if !reachable[target] {
+				reachable[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	for _, node := range d.Nodes {
+		v.Check(reachable[node.ID], fmt.Sprintf("nodes[%s]", node.ID), "nó inalcançável a partir do nó inicial")
+	}
+
+	for _, component := range d.components() {
+		if !reachable[component[0]] {
+			continue
+		}
+		sort.Strings(component)
+		switch {
+		case d.closed(component):
+			v.AddError(fmt.Sprintf("nodes[%s]", component[0]),
+				fmt.Sprintf("ciclo sem saída entre os nós %s", strings.Join(component, ", ")))
+		case d.spins(component):
+			v.AddError(fmt.Sprintf("nodes[%s]", component[0]),
+				fmt.Sprintf("ciclo sem perguntas entre os nós %s; inclua um nó question para aguardar a resposta do usuário", strings.Join(component, ", ")))
+		}
+	}
+}
+
+// closed reports whether component is a cycle with no transition leaving it
+// and no end node in it, trapping the user forever.
+func (d *Definition) closed(component []string) bool {
+	members := map[string]bool{}
+	for _, id := range component {
+		members[id] = true
+	}
+
+	cycle := len(component) > 1
+	for _, id := range component {
+		node, _ := d.Node(id)
+		if node.Type == NodeEnd {
+			return false
+		}
+		for _, target := range node.targets() {
+			if !members[target] {
+				return false
+			}
+			if target == id {
+				cycle = true
+			}
+		}
+	}
+	return cycle
+}
+
+// spins reports whether component is a cycle made only of message nodes.
+// Nothing in it waits for input, so the variables its transitions test can
+// only change through set, and the flow would go round until maxSteps.
+func (d *Definition) spins(component []string) bool {
+	cycle := len(component) > 1
+	for _, id := range component {
+		node, _ := d.Node(id)
+		if node.Type != NodeMessage {
+			return false
+		}
+		for _, target := range node.targets() {
+			if target == id {
+				cycle = true
+			}
+		}
+	}
+	return cycle
+}
+
+// components returns the strongly connected components of the graph using
+// Tarjan's algorithm.
+func (d *Definition) components() [][]string {
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	components := [][]string{}
+	next := 0
+
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = next
+		low[id] = next
+		next++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		node, _ := d.Node(id)
+		for _, target := range node.targets() {
+			if _, visited := index[target]; !visited {
+				connect(target)
+				low[id] = min(low[id], low[target])
+			} else if onStack[target] {
+				low[id] = min(low[id], index[target])
+			}
+		}
+
+		if low[id] == index[id] {
+			component := []string{}
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range d.Nodes {
+		if _, visited := index[node.ID]; !visited {
+			connect(node.ID)
+		}
+	}
+	return components
+}
@@ -0,0 +1,149 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func parseDefinition(t *testing.T, js string) *Definition {
+	t.Helper()
+
+	d := &Definition{}
+	if err := json.Unmarshal([]byte(js), d); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestValidateGraph(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		key        string
+		message    string
+	}{
+		{
+			name: "valid",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"question","text":"Nome?","transitions":[{"target":"b"}]},
+				{"id":"b","type":"end","text":"Tchau"}]}`,
+		},
+		{
+			name: "question loop with an exit",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"question","text":"Confirma?","transitions":[
+					{"condition":{"variable":"input","operator":"equals","value":"sim"},"target":"b"},
+					{"target":"a"}]},
+				{"id":"b","type":"end"}]}`,
+		},
+		{
+			name: "unreachable node",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"end"},
+				{"id":"b","type":"end"}]}`,
+			key:     "nodes[b]",
+			message: "inalcançável",
+		},
+		{
+			name: "closed cycle",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"question","text":"?","transitions":[{"target":"b"}]},
+				{"id":"b","type":"question","text":"?","transitions":[{"target":"a"}]}]}`,
+			key:     "nodes[a]",
+			message: "ciclo sem saída",
+		},
+		{
+			name: "message self loop with an exit",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"message","text":"Oi","transitions":[
+					{"condition":{"variable":"pronto","operator":"exists"},"target":"b"},
+					{"target":"a"}]},
+				{"id":"b","type":"end"}]}`,
+			key:     "nodes[a]",
+			message: "ciclo sem perguntas",
+		},
+		{
+			name: "message cycle with an exit",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"message","text":"Um","transitions":[{"target":"b"}]},
+				{"id":"b","type":"message","text":"Dois","transitions":[
+					{"condition":{"variable":"x","operator":"equals","value":"1"},"target":"c"},
+					{"target":"a"}]},
+				{"id":"c","type":"end"}]}`,
+			key:     "nodes[a]",
+			message: "ciclo sem perguntas entre os nós a, b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			parseDefinition(t, tt.definition).Validate(v)
+
+			if tt.key == "" {
+				if !v.Valid() {
+					t.Fatalf("unexpected errors: %v", v.Errors)
+				}
+				return
+			}
+			if !strings.Contains(v.Errors[tt.key], tt.message) {
+				t.Fatalf("errors = %v, want %s to contain %q", v.Errors, tt.key, tt.message)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	// The last transition of message nodes has no condition, so a message
+	// node always moves on; a question whose transitions do not apply asks
+	// again. Only definitions that skip validation fail at runtime.
+	tests := []struct {
+		name       string
+		definition string
+		message    string
+	}{
+		{
+			name: "message loop",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"message","text":"Oi","transitions":[{"target":"a"}]}]}`,
+			message: "excedeu 100 passos",
+		},
+		{
+			name: "no applicable transition",
+			definition: `{"start":"a","nodes":[
+				{"id":"a","type":"message","transitions":[
+					{"condition":{"variable":"x","operator":"exists"},"target":"a"}]}]}`,
+			message: "Nenhuma transição do nó \"a\"",
+		},
+		{
+			name:       "missing node",
+			definition: `{"start":"z","nodes":[{"id":"a","type":"end"}]}`,
+			message:    "Nó \"z\" não existe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseDefinition(t, tt.definition).Begin()
+
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("error = %v (%T), want a *RuntimeError", err, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.message)
+			}
+		})
+	}
+
+	d := parseDefinition(t, `{"start":"a","nodes":[{"id":"a","type":"question","text":"?","transitions":[{"target":"a"}]}]}`)
+	_, _, err := d.Advance(State{Node: "sumiu", Variables: map[string]string{}}, "oi")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Node != "sumiu" {
+		t.Errorf("Advance from a missing node: error = %v, want a *RuntimeError for node sumiu", err)
+	}
+}
@@ -0,0 +1,192 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-process server implementing the subset of the OpenAI
+// chat completions API used by OpenAIClient. Replies are deterministic and
+// failures and delays can be scripted per request.
+type fakeServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	failures   []fakeFailure
+	delay      time.Duration
+	requests   int
+	requestAt  []time.Time
+	lastAPIKey string
+}
+
+type fakeFailure struct {
+	status     int
+	retryAfter int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	fs := &fakeServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", fs.handleChatCompletions)
+	fs.Server = httptest.NewServer(mux)
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// client returns a client of the server whose backoff is short enough for
+// tests.
+func (fs *fakeServer) client(maxRetries int, pricing Pricing) *OpenAIClient {
+	c := NewOpenAIClient(fs.URL+"/v1/", "chave", time.Second, maxRetries, pricing)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+// fail makes the next requests fail with the given statuses, in order. A
+// positive retryAfter is sent as the Retry-After header, in seconds.
+func (fs *fakeServer) fail(failures ...fakeFailure) {
+	fs.mu.Lock()
+	fs.failures = append(fs.failures, failures...)
+	fs.mu.Unlock()
+}
+
+// slow delays every response by d.
+func (fs *fakeServer) slow(d time.Duration) {
+	fs.mu.Lock()
+	fs.delay = d
+	fs.mu.Unlock()
+}
+
+func (fs *fakeServer) Requests() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests
+}
+
+func fakeReply(prompt string) string {
+	return "Resposta simulada para: " + prompt
+}
+
+func (fs *fakeServer) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	fs.mu.Lock()
+	fs.requests++
+	fs.requestAt = append(fs.requestAt, time.Now())
+	fs.lastAPIKey = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	delay := fs.delay
+	var failure *fakeFailure
+	if len(fs.failures) > 0 {
+		failure = &fs.failures[0]
+		fs.failures = fs.failures[1:]
+	}
+	fs.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if failure != nil {
+		if failure.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(failure.retryAfter))
+		}
+		http.Error(w, `{"error":{"message":"unavailable"}}`, failure.status)
+		return
+	}
+
+	req := chatCompletionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":{"message":"invalid request"}}`, http.StatusBadRequest)
+		return
+	}
+
+	prompt := ""
+	promptTokens := 0
+	for _, message := range req.Messages {
+		promptTokens += countTokens(message.Content)
+		if message.Role == RoleUser {
+			prompt = message.Content
+		}
+	}
+
+	content := fakeReply(prompt)
+	words := strings.Fields(content)
+	if req.MaxTokens > 0 && len(words) > req.MaxTokens {
+		words = words[:req.MaxTokens]
+		content = strings.Join(words, " ")
+	}
+
+	if req.Stream {
+		fs.stream(w, req, words, promptTokens)
+		return
+	}
+
+	response := map[string]any{
+		"model": req.Model,
+		"choices": []any{
+			map[string]any{
+				"index":         0,
+				"message":       Message{Role: RoleAssistant, Content: content},
+				"finish_reason": "stop",
+			},
+		},
+		"usage": map[string]int{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": len(words),
+			"total_tokens":      promptTokens + len(words),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (fs *fakeServer) stream(w http.ResponseWriter, req chatCompletionRequest, words []string, promptTokens int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(chunk map[string]any) {
+		js, _ := json.Marshal(chunk)
+		w.Write([]byte("data: " + string(js) + "\n\n"))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for i, word := range words {
+		if i > 0 {
+			word = " " + word
+		}
+		send(map[string]any{
+			"model": req.Model,
+			"choices": []any{
+				map[string]any{"index": 0, "delta": map[string]string{"content": word}},
+			},
+		})
+	}
+
+	if req.StreamOpts != nil && req.StreamOpts.IncludeUsage {
+		send(map[string]any{
+			"model":   req.Model,
+			"choices": []any{},
+			"usage": map[string]int{
+				"prompt_tokens":     promptTokens,
+				"completion_tokens": len(words),
+				"total_tokens":      promptTokens + len(words),
+			},
+		})
+	}
+
+	w.Write([]byte("data: [DONE]\n\n"))
+}
+
+func countTokens(s string) int {
+	return len(strings.Fields(s))
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrEmptyCompletion = errors.New("O provedor de LLM não retornou nenhuma resposta")
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+type Message struct {
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+}
+
+type Request struct {
+	Model        string
+	SystemPrompt string
+	Messages     []Message
+	MaxTokens    int
+	Temperature  float64
+}
+
+type Usage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+type Response struct {
+	Content string
+	Usage   Usage
+}
+
+// Provider is implemented by every large language model backend. Providers
+// must be safe for concurrent use.
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Pricing is the cost, in US dollars, of 1000 prompt and completion tokens.
+type Pricing struct {
+	PromptPer1K     float64
+	CompletionPer1K float64
+}
+
+func (p Pricing) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)/1000*p.PromptPer1K + float64(completionTokens)/1000*p.CompletionPer1K
+}
+
+// StreamingProvider is implemented by providers able to deliver a completion
+// incrementally. onDelta is called with each piece of text as it arrives; the
+// returned Response holds the full content and usage.
+type StreamingProvider interface {
+	Provider
+	Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error)
+}
@@ -0,0 +1,271 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAIClient talks to any server implementing the OpenAI chat completions
+// API (OpenAI itself, Azure, vLLM, Ollama, LocalAI...). Requests that fail
+// with a network error, 429 or 5xx are retried with exponential backoff,
+// honoring Retry-After when the server sends it.
+type OpenAIClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	MaxRetries int
+	Backoff    time.Duration
+	Pricing    Pricing
+}
+
+func NewOpenAIClient(baseURL, apiKey string, timeout time.Duration, maxRetries int, pricing Pricing) *OpenAIClient {
+	return &OpenAIClient{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: timeout},
+		MaxRetries: maxRetries,
+		Backoff:    500 * time.Millisecond,
+		Pricing:    pricing,
+	}
+}
+
+type chatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
+	StreamOpts  *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+}
+
+type chatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+type apiError struct {
+	status     int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Provedor de LLM respondeu com status %d: %s", e.status, e.body)
+}
+
+func (e *apiError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(c.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	httpResponse, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	completion := chatCompletionResponse{}
+	err = json.NewDecoder(httpResponse.Body).Decode(&completion)
+	if err != nil {
+		return nil, fmt.Errorf("Resposta do provedor de LLM malformada: %v", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, ErrEmptyCompletion
+	}
+
+	model := completion.Model
+	if model == "" {
+		model = req.Model
+	}
+
+	return &Response{
+		Content: completion.Choices[0].Message.Content,
+		Usage: Usage{
+			Model:            model,
+			PromptTokens:     completion.Usage.PromptTokens,
+			CompletionTokens: completion.Usage.CompletionTokens,
+			Cost:             c.Pricing.Cost(completion.Usage.PromptTokens, completion.Usage.CompletionTokens),
+		},
+	}, nil
+}
+
+func (c *OpenAIClient) Stream(ctx context.Context, req Request, onDelta func(delta string) error) (*Response, error) {
+	chatRequest := c.buildRequest(req)
+	chatRequest.Stream = true
+	chatRequest.StreamOpts = &struct {
+		IncludeUsage bool `json:"include_usage"`
+	}{IncludeUsage: true}
+
+	body, err := json.Marshal(chatRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResponse, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	response := &Response{Usage: Usage{Model: req.Model}}
+	content := strings.Builder{}
+
+	scanner := bufio.NewScanner(httpResponse.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		chunk := chatCompletionChunk{}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("Fragmento do provedor de LLM malformado: %v", err)
+		}
+
+		if chunk.Model != "" {
+			response.Usage.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.Usage.PromptTokens = chunk.Usage.PromptTokens
+			response.Usage.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if content.Len() == 0 {
+		return nil, ErrEmptyCompletion
+	}
+
+	response.Content = content.String()
+	response.Usage.Cost = c.Pricing.Cost(response.Usage.PromptTokens, response.Usage.CompletionTokens)
+	return response, nil
+}
+
+func (c *OpenAIClient) buildRequest(req Request) chatCompletionRequest {
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.SystemPrompt != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: req.SystemPrompt})
+	}
+	messages = append(messages, req.Messages...)
+
+	return chatCompletionRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+}
+
+// do sends the request, retrying transient failures. On success the caller
+// owns the response body.
+func (c *OpenAIClient) do(ctx context.Context, body []byte) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			wait := c.Backoff << (attempt - 1)
+			var apiErr *apiError
+			if errors.As(lastErr, &apiErr) && apiErr.retryAfter > 0 {
+				wait = apiErr.retryAfter
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header.Set("Content-Type", "application/json")
+		if c.APIKey != "" {
+			httpRequest.Header.Set("Authorization", "Bearer "+c.APIKey)
+		}
+
+		httpResponse, err := c.HTTPClient.Do(httpRequest)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		if httpResponse.StatusCode == http.StatusOK {
+			return httpResponse, nil
+		}
+
+		errorBody, _ := io.ReadAll(io.LimitReader(httpResponse.Body, 4096))
+		httpResponse.Body.Close()
+
+		apiErr := &apiError{status: httpResponse.StatusCode, body: strings.TrimSpace(string(errorBody))}
+		if seconds, err := strconv.Atoi(httpResponse.Header.Get("Retry-After")); err == nil {
+			apiErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+
+		if !apiErr.retryable() {
+			return nil, apiErr
+		}
+		lastErr = apiErr
+	}
+
+	return nil, fmt.Errorf("Provedor de LLM falhou após %d tentativas: %w", c.MaxRetries+1, lastErr)
+}
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRequest = Request{
+	Model:        "modelo-teste",
+	SystemPrompt: "Você é um assistente",
+	Messages: []Message{
+		{Role: RoleUser, Content: "olá"},
+		{Role: RoleAssistant, Content: "Olá! Como posso ajudar?"},
+		{Role: RoleUser, Content: "qual o horário de atendimento"},
+	},
+}
+
+// Prompt tokens of testRequest as counted by the fake server: 4 + 1 + 4 + 5.
+const testPromptTokens = 14
+
+var testPricing = Pricing{PromptPer1K: 0.5, CompletionPer1K: 1.5}
+
+func TestCompleteAccountsUsage(t *testing.T) {
+	fs := newFakeServer(t)
+	c := fs.client(0, testPricing)
+
+	response, err := c.Complete(context.Background(), testRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fakeReply("qual o horário de atendimento")
+	if response.Content != want {
+		t.Errorf("Content = %q, want %q", response.Content, want)
+	}
+
+	completionTokens := len(strings.Fields(want))
+	wantUsage := Usage{
+		Model:            "modelo-teste",
+		PromptTokens:     testPromptTokens,
+		CompletionTokens: completionTokens,
+		Cost:             float64(testPromptTokens)/1000*0.5 + float64(completionTokens)/1000*1.5,
+	}
+	if response.Usage.Model != wantUsage.Model ||
+		response.Usage.PromptTokens != wantUsage.PromptTokens ||
+		response.Usage.CompletionTokens != wantUsage.CompletionTokens ||
+		math.Abs(response.Usage.Cost-wantUsage.Cost) > 1e-12 {
+		t.Errorf("Usage = %+v, want %+v", response.Usage, wantUsage)
+	}
+
+	if fs.lastAPIKey != "chave" {
+		t.Errorf("Authorization bearer = %q, want %q", fs.lastAPIKey, "chave")
+	}
+}
+
+func TestCompleteHonorsMaxTokens(t *testing.T) {
+	fs := newFakeServer(t)
+	c := fs.client(0, testPricing)
+
+	req := testRequest
+	req.MaxTokens = 2
+	response, err := c.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Content != "Resposta simulada" || response.Usage.CompletionTokens != 2 {
+		t.Errorf("response = %q with %d completion tokens, want two tokens", response.Content, response.Usage.CompletionTokens)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   []fakeFailure
+		wantErr    bool
+		wantStatus int
+		requests   int
+	}{
+		{"success after server errors", 2, []fakeFailure{{status: 503}, {status: 500}}, false, 0, 3},
+		{"success after rate limiting", 1, []fakeFailure{{status: 429}}, false, 0, 2},
+		{"retries exhausted", 1, []fakeFailure{{status: 503}, {status: 502}, {status: 503}}, true, 502, 2},
+		{"client errors are not retried", 3, []fakeFailure{{status: 400}}, true, 400, 1},
+		{"unauthorized is not retried", 3, []fakeFailure{{status: 401}}, true, 401, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			fs.fail(tt.failures...)
+			c := fs.client(tt.maxRetries, testPricing)
+
+			_, err := c.Complete(context.Background(), testRequest)
+			if tt.wantErr {
+				var apiErr *apiError
+				if !errors.As(err, &apiErr) || apiErr.status != tt.wantStatus {
+					t.Fatalf("error = %v, want status %d", err, tt.wantStatus)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fs.Requests() != tt.requests {
+				t.Errorf("requests = %d, want %d", fs.Requests(), tt.requests)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.fail(fakeFailure{status: http.StatusTooManyRequests, retryAfter: 1})
+	c := fs.client(1, testPricing)
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := c.Complete(ctx, testRequest); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fs.requestAt) != 2 {
+		t.Fatalf("requests = %d, want 2", len(fs.requestAt))
+	}
+	if wait := fs.requestAt[1].Sub(fs.requestAt[0]); wait < time.Second || wait > 3*time.Second {
+		t.Errorf("waited %v before retrying, want the 1s of Retry-After instead of the backoff", wait)
+	}
+}
+
+func TestRetryWaitStopsOnCancel(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.fail(fakeFailure{status: http.StatusServiceUnavailable, retryAfter: 60})
+	c := fs.client(1, testPricing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Complete(ctx, testRequest)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %v, want it to stop waiting when the context ends", elapsed)
+	}
+	if fs.Requests() != 1 {
+		t.Errorf("requests = %d, want 1", fs.Requests())
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.slow(time.Second)
+	c := fs.client(1, testPricing)
+	c.HTTPClient.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := c.Complete(context.Background(), testRequest)
+	if err == nil || !strings.Contains(err.Error(), "2 tentativas") {
+		t.Fatalf("error = %v, want a failure after 2 attempts", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("returned after %v, want each attempt to time out", elapsed)
+	}
+	if fs.Requests() != 2 {
+		t.Errorf("requests = %d, want 2", fs.Requests())
+	}
+}
+
+func TestStream(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.fail(fakeFailure{status: http.StatusServiceUnavailable})
+	c := fs.client(1, testPricing)
+
+	deltas := []string{}
+	response, err := c.Stream(context.Background(), testRequest, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fakeReply("qual o horário de atendimento")
+	words := strings.Fields(want)
+	if len(deltas) != len(words) {
+		t.Errorf("got %d deltas, want one per word (%d): %q", len(deltas), len(words), deltas)
+	}
+	if strings.Join(deltas, "") != want || response.Content != want {
+		t.Errorf("streamed %q, content %q, want %q", strings.Join(deltas, ""), response.Content, want)
+	}
+
+	cost := testPricing.Cost(testPromptTokens, len(words))
+	if response.Usage.Model != "modelo-teste" ||
+		response.Usage.PromptTokens != testPromptTokens ||
+		response.Usage.CompletionTokens != len(words) ||
+		math.Abs(response.Usage.Cost-cost) > 1e-12 {
+		t.Errorf("Usage = %+v", response.Usage)
+	}
+	if fs.Requests() != 2 {
+		t.Errorf("requests = %d, want the failed attempt to be retried", fs.Requests())
+	}
+}
+
+func TestStreamStopsOnCallbackError(t *testing.T) {
+	fs := newFakeServer(t)
+	c := fs.client(0, testPricing)
+
+	stop := errors.New("cliente desconectado")
+	calls := 0
+	_, err := c.Stream(context.Background(), testRequest, func(string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("error = %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("onDelta called %d times after failing, want 1", calls)
+	}
+}
+
+func TestPricingCost(t *testing.T) {
+	tests := []struct {
+		pricing    Pricing
+		prompt     int
+		completion int
+		want       float64
+	}{
+		{Pricing{}, 1000, 1000, 0},
+		{Pricing{PromptPer1K: 1}, 1000, 1000, 1},
+		{Pricing{PromptPer1K: 0.15, CompletionPer1K: 0.6}, 2000, 500, 0.6},
+		{Pricing{PromptPer1K: 0.15, CompletionPer1K: 0.6}, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pricing.Cost(tt.prompt, tt.completion); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%+v.Cost(%d, %d) = %v, want %v", tt.pricing, tt.prompt, tt.completion, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency
+// histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes its metric families in the Prometheus text exposition
+// format.
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds every metric exposed by the application, written in the
+// order they were registered.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector{}, r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry to Prometheus scrapers.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		parts = append(parts, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+`="`+labelEscaper.Replace(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// series keeps the label values of a vector sorted so scrapes are stable.
+type series[T any] struct {
+	labels []string
+	values map[string]*T
+	keys   map[string][]string
+}
+
+func newSeries[T any](labels []string) series[T] {
+	return series[T]{labels: labels, values: map[string]*T{}, keys: map[string][]string{}}
+}
+
+func (s *series[T]) get(values []string, init func() *T) *T {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %d valores para os rótulos %v", len(values), s.labels))
+	}
+
+	key := strings.Join(values, "\xff")
+	v, ok := s.values[key]
+	if !ok {
+		v = init()
+		s.values[key] = v
+		s.keys[key] = append([]string{}, values...)
+	}
+	return v
+}
+
+func (s *series[T]) sorted() []string {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec is a monotonically increasing value per combination of label
+// values.
+type CounterVec struct {
+	name, help string
+	mu         sync.Mutex
+	series     series[float64]
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, series: newSeries[float64](labels)}
+	if len(labels) == 0 {
+		// Expose counters without labels as 0 from the start.
+		c.Add(0)
+	}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	*c.series.get(labelValues, func() *float64 { return new(float64) }) += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range c.series.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.series.labels, c.series.keys[key]), formatFloat(*c.series.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec counts observations in cumulative buckets per combination
+// of label values.
+type HistogramVec struct {
+	name, help string
+	buckets    []float64
+	mu         sync.Mutex
+	series     series[histogram]
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, buckets: buckets, series: newSeries[histogram](labels)}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.series.get(labelValues, func() *histogram {
+		return &histogram{counts: make([]uint64, len(h.buckets))}
+	})
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, key := range h.series.sorted() {
+		s := h.series.values[key]
+		values := h.series.keys[key]
+
+		for i, upper := range h.buckets {
+			labels := formatLabels(h.series.labels, values, "le", formatFloat(upper))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.series.labels, values, "le", "+Inf"), s.count)
+
+		labels := formatLabels(h.series.labels, values)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labels, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, labels, s.count)
+	}
+}
+
+type Type string
+
+const (
+	Counter Type = "counter"
+	Gauge   Type = "gauge"
+)
+
+// Sample is a single unlabelled value read when the registry is scraped.
+type Sample struct {
+	Name  string
+	Help  string
+	Type  Type
+	Value float64
+}
+
+type sampleFunc func() []Sample
+
+func (fn sampleFunc) write(w *bufio.Writer) {
+	for _, s := range fn() {
+		writeHeader(w, s.Name, s.Help, string(s.Type))
+		fmt.Fprintf(w, "%s %s\n", s.Name, formatFloat(s.Value))
+	}
+}
+
+// Collect registers fn to be called on every scrape, for values that are
+// read from elsewhere, such as connection pool or runtime statistics, and
+// are cheaper to read together.
+func (r *Registry) Collect(fn func() []Sample) {
+	r.register(sampleFunc(fn))
+}
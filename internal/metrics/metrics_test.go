@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	b := strings.Builder{}
+	if err := r.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestCounterFormat(t *testing.T) {
+	r := NewRegistry()
+	plain := r.NewCounter("signins_total", "Logins bem-sucedidos.")
+	labelled := r.NewCounter("requests_total", "Requisições\natendidas.", "method", "route")
+
+	plain.Inc()
+	plain.Add(1.5)
+	labelled.Inc("POST", "/v1/a")
+	labelled.Inc("GET", `/v1/"b"\c`)
+	labelled.Inc("GET", `/v1/"b"\c`)
+
+	want := `# HELP signins_total Logins bem-sucedidos.
+# TYPE signins_total counter
+signins_total 2.5
+# HELP requests_total Requisições\natendidas.
+# TYPE requests_total counter
+requests_total{method="GET",route="/v1/\"b\"\\c"} 2
+requests_total{method="POST",route="/v1/a"} 1
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterWithoutObservations(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("signups_total", "Cadastros.")
+	r.NewCounter("failed_total", "Falhas.", "reason")
+
+	want := `# HELP signups_total Cadastros.
+# TYPE signups_total counter
+signups_total 0
+# HELP failed_total Falhas.
+# TYPE failed_total counter
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramFormat(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("duration_seconds", "Duração.", []float64{0.1, 0.5, 1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.7, "/a")
+	h.Observe(3, "/a")
+	h.Observe(0.2, "/b")
+
+	want := `# HELP duration_seconds Duração.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 2
+duration_seconds_bucket{route="/a",le="0.5"} 2
+duration_seconds_bucket{route="/a",le="1"} 3
+duration_seconds_bucket{route="/a",le="+Inf"} 4
+duration_seconds_sum{route="/a"} 3.85
+duration_seconds_count{route="/a"} 4
+duration_seconds_bucket{route="/b",le="0.1"} 0
+duration_seconds_bucket{route="/b",le="0.5"} 1
+duration_seconds_bucket{route="/b",le="1"} 1
+duration_seconds_bucket{route="/b",le="+Inf"} 1
+duration_seconds_sum{route="/b"} 0.2
+duration_seconds_count{route="/b"} 1
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSamplesFormat(t *testing.T) {
+	r := NewRegistry()
+	r.Collect(func() []Sample {
+		return []Sample{
+			{Name: "goroutines", Help: "Goroutines.", Type: Gauge, Value: 12},
+			{Name: "wait_seconds_total", Help: "Espera.", Type: Counter, Value: math.Inf(1)},
+		}
+	})
+
+	want := `# HELP goroutines Goroutines.
+# TYPE goroutines gauge
+goroutines 12
+# HELP wait_seconds_total Espera.
+# TYPE wait_seconds_total counter
+wait_seconds_total +Inf
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("signups_total", "Cadastros.")
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "signups_total 0\n") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requisições.", "method", "route")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	c.Inc("GET")
+}
@@ -0,0 +1,216 @@
+package nlu
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+var ErrNotTrained = errors.New("O classificador de intenções ainda não foi treinado")
+
+// Example is a sentence labelled with the intent it expresses.
+type Example struct {
+	Intent string
+	Text   string
+}
+
+// Prediction is the confidence, between 0 and 1, that a sentence expresses
+// an intent. The confidences of a classification add up to 1.
+type Prediction struct {
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Classifier is a multinomial naive Bayes model over TF-IDF weighted
+// features, as proposed by Rennie et al. (2003) for short texts. Features are
+// the stemmed words of a sentence and the pairs of adjacent stems. Its fields
+// are exported so that a trained model can be stored as JSON.
+type Classifier struct {
+	Intents    []string       `json:"intents"`
+	Vocabulary map[string]int `json:"vocabulary"`
+	IDF        []float64      `json:"idf"`
+	Priors     []float64      `json:"priors"`
+	LogProbs   [][]float64    `json:"log_probs"`
+	Examples   int            `json:"examples"`
+	TrainedAt  time.Time      `json:"trained_at"`
+	// Responses are the replies configured for each intent when the model
+	// was trained, kept with it so that they change together.
+	Responses map[string][]string `json:"responses,omitempty"`
+}
+
+// smoothing is the additive (Laplace) smoothing applied to feature weights.
+const smoothing = 0.1
+
+func features(text string) []string {
+	tokens := Tokenize(text)
+	features := make([]string, 0, 2*len(tokens))
+	features = append(features, tokens...)
+	for i := 1; i < len(tokens); i++ {
+		features = append(features, tokens[i-1]+"_"+tokens[i])
+	}
+	return features
+}
+
+// Train builds a classifier from the examples. Every intent needs at least
+// one example with a known word.
+func Train(examples []Example) (*Classifier, error) {
+	if len(examples) == 0 {
+		return nil, errors.New("Nenhum exemplo para treinar o classificador")
+	}
+
+	c := &Classifier{Vocabulary: map[string]int{}, Examples: len(examples), TrainedAt: time.Now()}
+
+	intentIndex := map[string]int{}
+	docs := make([][]string, len(examples))
+	labels := make([]int, len(examples))
+	df := []float64{}
+
+	for i, ex := range examples {
+		idx, ok := intentIndex[ex.Intent]
+		if !ok {
+			idx = len(c.Intents)
+			intentIndex[ex.Intent] = idx
+			c.Intents = append(c.Intents, ex.Intent)
+		}
+		labels[i] = idx
+		docs[i] = features(ex.Text)
+
+		seen := map[int]bool{}
+		for _, f := range docs[i] {
+			id, ok := c.Vocabulary[f]
+			if !ok {
+				id = len(df)
+				c.Vocabulary[f] = id
+				df = append(df, 0)
+			}
+			if !seen[id] {
+				seen[id] = true
+				df[id]++
+			}
+		}
+	}
+
+	if len(c.Vocabulary) == 0 {
+		return nil, errors.New("Os exemplos não contêm palavras significativas")
+	}
+
+	n := float64(len(examples))
+	c.IDF = make([]float64, len(df))
+	for id, d := range df {
+		c.IDF[id] = math.Log((n+1)/(d+1)) + 1
+	}
+
+	counts := make([][]float64, len(c.Intents))
+	totals := make([]float64, len(c.Intents))
+	docsPerIntent := make([]float64, len(c.Intents))
+	for i := range counts {
+		counts[i] = make([]float64, len(c.Vocabulary))
+	}
+
+	for i, doc := range docs {
+		label := labels[i]
+		docsPerIntent[label]++
+		for id, w := range c.weigh(doc) {
+			counts[label][id] += w
+			totals[label] += w
+		}
+	}
+
+	v := float64(len(c.Vocabulary))
+	c.Priors = make([]float64, len(c.Intents))
+	c.LogProbs = make([][]float64, len(c.Intents))
+	for i := range c.Intents {
+		if totals[i] == 0 {
+			return nil, errors.New("A intenção " + c.Intents[i] + " não possui exemplos com palavras significativas")
+		}
+
+		c.Priors[i] = math.Log(docsPerIntent[i] / n)
+		c.LogProbs[i] = make([]float64, len(c.Vocabulary))
+		for id := range c.LogProbs[i] {
+			c.LogProbs[i][id] = math.Log((counts[i][id] + smoothing) / (totals[i] + smoothing*v))
+		}
+	}
+
+	return c, nil
+}
+
+// weigh returns the L2-normalized TF-IDF weights of the known features of a
+// document. Term frequencies are dampened with log(1+tf).
+func (c *Classifier) weigh(doc []string) map[int]float64 {
+	tf := map[int]float64{}
+	for _, f := range doc {
+		if id, ok := c.Vocabulary[f]; ok {
+			tf[id]++
+		}
+	}
+
+	norm := 0.0
+	for id, count := range tf {
+		w := math.Log1p(count) * c.IDF[id]
+		tf[id] = w
+		norm += w * w
+	}
+
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for id := range tf {
+			tf[id] /= norm
+		}
+	}
+	return tf
+}
+
+// Known reports whether text has any feature the classifier was trained on.
+// Classify can only return the priors for a sentence that has none.
+func (c *Classifier) Known(text string) bool {
+	for _, f := range features(text) {
+		if _, ok := c.Vocabulary[f]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify ranks every intent by how likely it is to be expressed by text,
+// most likely first. A sentence without any known word gets the priors.
+//
+// The TF-IDF weighting only shapes the feature probabilities learned in
+// Train. The sentence itself is scored with the multinomial likelihood, the
+// log probability of each of its known features times its count.
+func (c *Classifier) Classify(text string) []Prediction {
+	counts := map[int]float64{}
+	for _, f := range features(text) {
+		if id, ok := c.Vocabulary[f]; ok {
+			counts[id]++
+		}
+	}
+
+	scores := make([]float64, len(c.Intents))
+	max := math.Inf(-1)
+	for i := range c.Intents {
+		scores[i] = c.Priors[i]
+		for id, count := range counts {
+			scores[i] += count * c.LogProbs[i][id]
+		}
+		if scores[i] > max {
+			max = scores[i]
+		}
+	}
+
+	sum := 0.0
+	for i := range scores {
+		scores[i] = math.Exp(scores[i] - max)
+		sum += scores[i]
+	}
+
+	predictions := make([]Prediction, len(c.Intents))
+	for i, intent := range c.Intents {
+		predictions[i] = Prediction{Intent: intent, Confidence: scores[i] / sum}
+	}
+
+	sort.SliceStable(predictions, func(i, j int) bool {
+		return predictions[i].Confidence > predictions[j].Confidence
+	})
+	return predictions
+}
@@ -0,0 +1,101 @@
+package nlu
+
+import (
+	"math"
+	"testing"
+)
+
+var trainingExamples = []Example{
+	{"cancelar", "quero cancelar meu pedido"},
+	{"cancelar", "cancela a minha compra"},
+	{"cancelar", "desistir do pedido e cancelar"},
+	{"cancelar", "como faço o cancelamento da assinatura"},
+	{"rastrear", "onde está meu pedido"},
+	{"rastrear", "quero rastrear a entrega"},
+	{"rastrear", "meu pedido ainda não chegou"},
+	{"rastrear", "qual o prazo de entrega"},
+	{"pagamento", "como pagar com boleto"},
+	{"pagamento", "posso pagar no cartão"},
+	{"pagamento", "quais as formas de pagamento"},
+	{"pagamento", "o boleto venceu"},
+	{"senha", "esqueci minha senha"},
+	{"senha", "como trocar a senha"},
+	{"senha", "não consigo entrar na conta"},
+}
+
+func TestClassify(t *testing.T) {
+	c, err := Train(trainingExamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text   string
+		intent string
+	}{
+		{"gostaria de cancelar o pedido", "cancelar"},
+		{"cancelamento", "cancelar"},
+		{"a entrega está atrasada, onde está?", "rastrear"},
+		{"qual o prazo?", "rastrear"},
+		{"dá para pagar com cartão?", "pagamento"},
+		{"segunda via do boleto", "pagamento"},
+		{"perdi a senha", "senha"},
+		{"não consigo entrar", "senha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			predictions := c.Classify(tt.text)
+			if len(predictions) != len(c.Intents) {
+				t.Fatalf("got %d predictions, want %d", len(predictions), len(c.Intents))
+			}
+			if predictions[0].Intent != tt.intent {
+				t.Errorf("intent = %v, want %s", predictions, tt.intent)
+			}
+
+			sum := 0.0
+			for i, p := range predictions {
+				sum += p.Confidence
+				if i > 0 && p.Confidence > predictions[i-1].Confidence {
+					t.Errorf("predictions are not sorted: %v", predictions)
+				}
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("confidences add up to %f", sum)
+			}
+		})
+	}
+}
+
+func TestClassifyUnknownWordsGetThePriors(t *testing.T) {
+	c, err := Train([]Example{
+		{"a", "cancelar pedido"},
+		{"a", "cancelar compra"},
+		{"a", "cancelar assinatura"},
+		{"b", "rastrear entrega"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	predictions := c.Classify("xyzzy")
+	if predictions[0].Intent != "a" || math.Abs(predictions[0].Confidence-0.75) > 1e-9 {
+		t.Errorf("predictions = %v, want the priors", predictions)
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	tests := map[string][]Example{
+		"no examples":    nil,
+		"only stopwords": {{"a", "o e a"}},
+		"empty intent":   {{"a", "cancelar"}, {"b", "de para"}},
+	}
+
+	for name, examples := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Train(examples); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
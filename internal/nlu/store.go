@@ -0,0 +1,26 @@
+package nlu
+
+import "sync"
+
+// Store holds the classifier in use, which is replaced whenever the model is
+// retrained while requests keep classifying sentences.
+type Store struct {
+	mu         sync.RWMutex
+	classifier *Classifier
+}
+
+func (s *Store) Set(c *Classifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.classifier = c
+}
+
+func (s *Store) Classifier() (*Classifier, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.classifier == nil {
+		return nil, ErrNotTrained
+	}
+	return s.classifier, nil
+}
@@ -0,0 +1,142 @@
+package nlu
+
+import (
+	"strings"
+	"unicode"
+)
+
+var accents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// Fold lowercases s and removes the accents used in Portuguese, so that
+// "Não" and "nao" are the same word.
+func Fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if folded, ok := accents[r]; ok {
+			return folded
+		}
+		return r
+	}, s)
+}
+
+// stopwords are folded, so they must be compared after Fold.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a o as os um uma uns umas de do da dos das no na nos nas em por para pra pro
+		com sem sob sobre ao aos e ou mas que se ja ate entre pelo pela pelos pelas
+		eu tu ele ela nos vos eles elas me te lhe lhes meu minha meus minhas seu sua
+		seus suas teu tua isso isto aquilo esse essa este esta aquele aquela ai la
+		ter tenho tem temos ser sou e era foi estou esta estamos
+		muito mais menos tambem entao so ne ola oi bom boa dia tarde noite favor
+	`) {
+		stopwords[w] = true
+	}
+	// Words that change the meaning of a sentence are kept. Verbs such as
+	// "quero" and "pode" and question words such as "como" and "qual" are not
+	// listed above for the same reason: they tell a request from a question.
+	delete(stopwords, "nao")
+}
+
+// Tokenize splits text into folded, stemmed words, dropping stopwords and
+// punctuation.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		if stopwords[w] {
+			continue
+		}
+		tokens = append(tokens, Stem(w))
+	}
+	return tokens
+}
+
+type suffixRule struct {
+	suffix      string
+	minStem     int
+	replacement string
+}
+
+// The rules below are a reduced version of the RSLP stemmer for Portuguese
+// (Orengo & Huyck, 2001), written for folded words. Each step removes at
+// most one suffix, trying the longest suffixes first.
+var (
+	pluralRules = []suffixRule{
+		{"oes", 3, "ao"}, {"aes", 1, "ao"}, {"ais", 1, "al"}, {"eis", 2, "el"},
+		{"ois", 1, "ol"}, {"is", 2, "il"}, {"les", 3, "l"}, {"res", 3, "r"},
+		{"ns", 1, "m"}, {"s", 2, ""},
+	}
+	feminineRules = []suffixRule{
+		{"ona", 3, "ao"}, {"ora", 3, "or"}, {"na", 4, "no"}, {"inha", 3, "inho"},
+		{"esa", 3, "es"}, {"osa", 3, "oso"}, {"iaca", 3, "iaco"}, {"ica", 3, "ico"},
+		{"ada", 2, "ado"}, {"ida", 3, "ido"}, {"ima", 3, "imo"}, {"iva", 3, "ivo"},
+		{"eira", 3, "eiro"},
+	}
+	diminutiveRules = []suffixRule{
+		{"zinho", 2, ""}, {"inho", 3, ""}, {"zao", 2, ""}, {"ao", 3, ""},
+	}
+	nounRules = []suffixRule{
+		{"amentos", 3, ""}, {"imentos", 3, ""}, {"amento", 3, ""}, {"imento", 3, ""},
+		{"mente", 4, ""}, {"idade", 4, ""}, {"acao", 3, ""}, {"icao", 3, ""}, {"cao", 3, ""},
+		{"ismo", 3, ""}, {"ista", 4, ""}, {"avel", 2, ""}, {"ivel", 3, ""},
+		{"ador", 3, ""}, {"edor", 3, ""}, {"idor", 4, ""}, {"ante", 2, ""},
+		{"oso", 3, ""}, {"ico", 4, ""}, {"ivo", 4, ""}, {"eiro", 3, ""},
+	}
+	verbRules = []suffixRule{
+		{"aramos", 4, ""}, {"eramos", 4, ""}, {"iramos", 3, ""}, {"assem", 4, ""},
+		{"essem", 4, ""}, {"issem", 3, ""}, {"aremos", 4, ""}, {"eremos", 3, ""},
+		{"iremos", 3, ""}, {"ariam", 3, ""}, {"eriam", 3, ""}, {"iriam", 3, ""},
+		{"aram", 2, ""}, {"eram", 3, ""}, {"iram", 3, ""}, {"avam", 2, ""},
+		{"ando", 2, ""}, {"endo", 3, ""}, {"indo", 3, ""}, {"aria", 3, ""},
+		{"eria", 3, ""}, {"iria", 3, ""}, {"ado", 2, ""}, {"ido", 3, ""},
+		{"ava", 2, ""}, {"iam", 3, ""}, {"ar", 2, ""}, {"er", 2, ""},
+		{"ir", 3, ""}, {"am", 2, ""}, {"em", 2, ""}, {"ou", 2, ""},
+		{"ei", 3, ""}, {"ia", 3, ""},
+	}
+	vowelRules = []suffixRule{
+		{"a", 3, ""}, {"e", 3, ""}, {"o", 3, ""},
+	}
+)
+
+func applyRules(word string, rules []suffixRule) (string, bool) {
+	for _, rule := range rules {
+		if strings.HasSuffix(word, rule.suffix) && len(word)-len(rule.suffix) >= rule.minStem {
+			return word[:len(word)-len(rule.suffix)] + rule.replacement, true
+		}
+	}
+	return word, false
+}
+
+// Stem reduces a folded Portuguese word to its stem, so that "cancelar",
+// "cancelamento" and "cancelei" share the same token.
+func Stem(word string) string {
+	if len(word) < 4 {
+		return word
+	}
+
+	word, _ = applyRules(word, pluralRules)
+	word, _ = applyRules(word, feminineRules)
+	word, _ = applyRules(word, diminutiveRules)
+
+	if stemmed, ok := applyRules(word, nounRules); ok {
+		return stemmed
+	}
+	if stemmed, ok := applyRules(word, verbRules); ok {
+		return stemmed
+	}
+
+	word, _ = applyRules(word, vowelRules)
+	return word
+}
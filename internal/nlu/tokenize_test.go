@@ -0,0 +1,68 @@
+package nlu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Não":        "nao",
+		"AÇÃO":       "acao",
+		"pé-de-moça": "pe-de-moca",
+		"número 42":  "numero 42",
+	}
+
+	for in, want := range tests {
+		if got := Fold(in); got != want {
+			t.Errorf("Fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		words []string
+		stem  string
+	}{
+		{[]string{"cancelar", "cancelamento", "cancelei", "cancela"}, "cancel"},
+		{[]string{"pedido", "pedidos"}, "ped"},
+		{[]string{"pagar", "pagamento"}, "pag"},
+		{[]string{"cartao", "cartoes"}, "cart"},
+		{[]string{"papeis"}, "papel"},
+		{[]string{"qual", "dia"}, ""},
+	}
+
+	for _, tt := range tests {
+		for _, w := range tt.words {
+			want := tt.stem
+			if want == "" {
+				// Words shorter than four letters are kept as they are.
+				want = w
+			}
+			if got := Stem(w); got != want {
+				t.Errorf("Stem(%q) = %q, want %q", w, got, want)
+			}
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens []string
+	}{
+		{"Quero cancelar o meu pedido, por favor!", []string{"quer", "cancel", "ped"}},
+		{"Como faço para pagar com boleto?", []string{"com", "fac", "pag", "bolet"}},
+		{"Qual é o prazo?", []string{"qual", "praz"}},
+		{"Não recebi a entrega", []string{"nao", "recebi", "entreg"}},
+		{"Pode me ajudar?", []string{"pod", "ajud"}},
+		{"o e a", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.tokens) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.tokens)
+		}
+	}
+}
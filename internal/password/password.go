@@ -0,0 +1,73 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownFormat = errors.New("Formato de hash de senha desconhecido")
+	ErrMismatch      = errors.New("Senha não confere")
+)
+
+// Hasher produces self-describing encoded hashes: the algorithm and its
+// parameters are stored alongside the salt and digest, so a hash can be
+// verified, and checked for outdated parameters, without any other state.
+type Hasher interface {
+	Hash(plaintext string) (string, error)
+	Verify(plaintext, encoded string) error
+	NeedsRehash(encoded string) bool
+	Identify(encoded string) bool
+}
+
+type multiHasher struct {
+	preferred Hasher
+	known     []Hasher
+}
+
+// New returns a Hasher that creates hashes with the given algorithm but is
+// still able to verify hashes produced by every other supported algorithm.
+// Hashes that were not produced by the preferred algorithm, or that use
+// outdated parameters, are reported by NeedsRehash.
+func New(algorithm string, bcryptCost int) (Hasher, error) {
+	bc := Bcrypt{Cost: bcryptCost}
+	ar := Argon2id{Params: DefaultArgon2idParams}
+
+	switch algorithm {
+	case "bcrypt":
+		return multiHasher{preferred: bc, known: []Hasher{bc, ar}}, nil
+	case "argon2id":
+		return multiHasher{preferred: ar, known: []Hasher{ar, bc}}, nil
+	default:
+		return nil, fmt.Errorf("Algoritmo de hash de senha não suportado: %s", algorithm)
+	}
+}
+
+func (m multiHasher) Hash(plaintext string) (string, error) {
+	return m.preferred.Hash(plaintext)
+}
+
+func (m multiHasher) Verify(plaintext, encoded string) error {
+	for _, h := range m.known {
+		if h.Identify(encoded) {
+			return h.Verify(plaintext, encoded)
+		}
+	}
+	return ErrUnknownFormat
+}
+
+func (m multiHasher) NeedsRehash(encoded string) bool {
+	if !m.preferred.Identify(encoded) {
+		return true
+	}
+	return m.preferred.NeedsRehash(encoded)
+}
+
+func (m multiHasher) Identify(encoded string) bool {
+	for _, h := range m.known {
+		if h.Identify(encoded) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Cheap parameters keep the tests fast; the format is the same.
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idEncoding(t *testing.T) {
+	a := Argon2id{Params: testArgon2idParams}
+
+	encoded, err := a.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string %q", encoded)
+	}
+
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != testArgon2idParams {
+		t.Errorf("decoded params = %+v, want %+v", params, testArgon2idParams)
+	}
+	if len(salt) != 16 || len(key) != 32 {
+		t.Errorf("decoded salt and key have %d and %d bytes", len(salt), len(key))
+	}
+
+	if err := a.Verify("correct horse", encoded); err != nil {
+		t.Errorf("Verify with the right password: %v", err)
+	}
+	if err := a.Verify("wrong horse", encoded); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify with a wrong password = %v, want ErrMismatch", err)
+	}
+}
+
+func TestArgon2idDecodeErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+	}
+
+	for _, encoded := range tests {
+		if _, _, _, err := decodeArgon2id(encoded); err == nil {
+			t.Errorf("decodeArgon2id(%q) succeeded", encoded)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weak := Argon2id{Params: testArgon2idParams}
+	argon, err := weak.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+
+	b := Bcrypt{Cost: 4}
+	bcrypted, err := b.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id same params", weak, argon, false},
+		{"argon2id outdated params", Argon2id{Params: stronger}, argon, true},
+		{"argon2id malformed", weak, "$argon2id$", true},
+		{"bcrypt same cost", b, bcrypted, false},
+		{"bcrypt outdated cost", Bcrypt{Cost: 5}, bcrypted, true},
+		{"preferring argon2id, bcrypt hash", multiHasher{preferred: weak, known: []Hasher{weak, b}}, bcrypted, true},
+		{"preferring argon2id, argon2id hash", multiHasher{preferred: weak, known: []Hasher{weak, b}}, argon, false},
+		{"preferring bcrypt, argon2id hash", multiHasher{preferred: b, known: []Hasher{b, weak}}, argon, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Legacy hashes were stored by pgcrypto's crypt(password, gen_salt('bf')),
+// which uses the $2a$ bcrypt format. The values below are the crypt_blowfish
+// reference vectors in that same format.
+func TestVerifyLegacyHashes(t *testing.T) {
+	hasher, err := New("argon2id", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		plaintext string
+		encoded   string
+	}{
+		{"U*U", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"},
+		{"U*U*", "$2a$05$CCCCCCCCCCCCCCCCCCCCC.VGOzA784oUp/Z0DY336zx7pLYAy0lwK"},
+	}
+
+	for _, tt := range tests {
+		if err := hasher.Verify(tt.plaintext, tt.encoded); err != nil {
+			t.Errorf("Verify(%q, %q): %v", tt.plaintext, tt.encoded, err)
+		}
+		if err := hasher.Verify(tt.plaintext+"x", tt.encoded); !errors.Is(err, ErrMismatch) {
+			t.Errorf("Verify with a wrong password = %v, want ErrMismatch", err)
+		}
+		if !hasher.NeedsRehash(tt.encoded) {
+			t.Errorf("NeedsRehash(%q) = false for a legacy hash", tt.encoded)
+		}
+	}
+
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if !hasher.Identify(prefix + "05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW") {
+			t.Errorf("%s hashes are not identified", prefix)
+		}
+	}
+
+	if err := hasher.Verify("secret", "md5$abc"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Verify of an unknown format = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestNewUnknownAlgorithm(t *testing.T) {
+	if _, err := New("md5", 0); err == nil {
+		t.Error("New accepted an unknown algorithm")
+	}
+}
@@ -0,0 +1,209 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that refilled completely, and thus
+// behave exactly like new ones, are dropped.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter allows Burst requests at once per key, refilled at Rate requests
+// per second.
+type Limiter struct {
+	Rate  float64
+	Burst int
+
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// Result describes the state of a key's bucket after a request, for the
+// RateLimit-* and Retry-After headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed; zero while
+	// Remaining is positive.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		Rate:    rate,
+		Burst:   burst,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of key, if there is one.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	result := Result{Limit: l.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	}
+
+	result.Remaining = int(b.tokens)
+	if b.tokens < 1 {
+		result.RetryAfter = l.refill(1 - b.tokens)
+	}
+	result.Reset = l.refill(float64(l.Burst) - b.tokens)
+	return result
+}
+
+// refill returns how long it takes to earn the given amount of tokens.
+func (l *Limiter) refill(tokens float64) time.Duration {
+	if l.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+type failures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// Lockout blocks a key after Threshold consecutive failures. The first lock
+// lasts Base and each further failure doubles it, up to Max. Failures are
+// forgotten after Window without any new one.
+type Lockout struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+
+	now       func() time.Time
+	mu        sync.Mutex
+	keys      map[string]*failures
+	lastSweep time.Time
+}
+
+func NewLockout(threshold int, base, max, window time.Duration) *Lockout {
+	return &Lockout{
+		Threshold: threshold,
+		Base:      base,
+		Max:       max,
+		Window:    window,
+		now:       time.Now,
+		keys:      map[string]*failures{},
+	}
+}
+
+// Attempt records an attempt for key. While key is locked the attempt is
+// refused and wait is how long the lock remains. Otherwise the attempt is
+// counted as a failure right away, so concurrent attempts cannot all get
+// through before any of them fails, and lock is how long key is locked by
+// it, or zero. A successful attempt must be followed by Reset, and one that
+// could not be checked, by Undo.
+func (l *Lockout) Attempt(key string) (wait, lock time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	f, ok := l.keys[key]
+	if ok && now.Before(f.lockedUntil) {
+		return f.lockedUntil.Sub(now), 0
+	}
+	if !ok || now.Sub(f.last) >= l.Window {
+		f = &failures{}
+		l.keys[key] = f
+	}
+
+	f.count++
+	f.last = now
+
+	if f.count < l.Threshold {
+		return 0, 0
+	}
+
+	lock = l.Base
+	for i := l.Threshold; i < f.count && lock < l.Max; i++ {
+		lock *= 2
+	}
+	lock = min(lock, l.Max)
+
+	f.lockedUntil = now.Add(lock)
+	return 0, lock
+}
+
+// Reset forgets the failures of key, after a success.
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+}
+
+// Undo takes back the failure counted by the last Attempt for key, and the
+// lock it set, if any. Attempts are only counted while key is not locked, so
+// any lock before it had already expired.
+func (l *Lockout) Undo(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.keys[key]
+	if !ok {
+		return
+	}
+
+	f.count--
+	f.lockedUntil = time.Time{}
+	if f.count <= 0 {
+		delete(l.keys, key)
+	}
+}
+
+func (l *Lockout) sweep(now time.Time) {
+	for key, f := range l.keys {
+		if now.Sub(f.last) >= l.Window && now.After(f.lockedUntil) {
+			delete(l.keys, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLockout(threshold int) (*Lockout, *clock) {
+	c := &clock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	l := NewLockout(threshold, time.Minute, 4*time.Minute, time.Hour)
+	l.now = c.now
+	return l, c
+}
+
+func TestLockoutAttempt(t *testing.T) {
+	l, c := newTestLockout(3)
+
+	for i := 1; i <= 2; i++ {
+		if wait, lock := l.Attempt("a"); wait != 0 || lock != 0 {
+			t.Fatalf("attempt %d: wait = %s, lock = %s", i, wait, lock)
+		}
+	}
+
+	// The attempt that reaches the threshold is let through and locks the
+	// key unless it succeeds.
+	if wait, lock := l.Attempt("a"); wait != 0 || lock != time.Minute {
+		t.Fatalf("third attempt: wait = %s, lock = %s, want a lock of 1m", wait, lock)
+	}
+
+	c.advance(20 * time.Second)
+	if wait, lock := l.Attempt("a"); wait != 40*time.Second || lock != 0 {
+		t.Errorf("while locked: wait = %s, lock = %s, want to wait 40s", wait, lock)
+	}
+
+	tests := []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for i, want := range tests {
+		c.advance(5 * time.Minute)
+		if _, lock := l.Attempt("a"); lock != want {
+			t.Errorf("failure %d after the threshold: lock = %s, want %s", i+1, lock, want)
+		}
+	}
+
+	if wait, _ := l.Attempt("b"); wait != 0 {
+		t.Errorf("other key: wait = %s", wait)
+	}
+}
+
+func TestLockoutReset(t *testing.T) {
+	l, _ := newTestLockout(2)
+
+	l.Attempt("a")
+	l.Reset("a")
+	if _, lock := l.Attempt("a"); lock != 0 {
+		t.Errorf("lock = %s after a success, want the count to start over", lock)
+	}
+}
+
+func TestLockoutUndo(t *testing.T) {
+	l, c := newTestLockout(2)
+
+	l.Attempt("a")
+	l.Attempt("a")
+	l.Undo("a")
+	if wait, _ := l.Attempt("a"); wait != 0 {
+		t.Fatalf("wait = %s, want the undone attempt not to lock", wait)
+	}
+
+	// The earlier failures still count.
+	c.advance(5 * time.Minute)
+	if _, lock := l.Attempt("a"); lock != 2*time.Minute {
+		t.Errorf("lock = %s, want 2m", lock)
+	}
+
+	l.Attempt("b")
+	l.Undo("b")
+	l.Undo("b")
+	if _, lock := l.Attempt("b"); lock != 0 {
+		t.Errorf("lock = %s, want the count to start over", lock)
+	}
+}
+
+func TestLockoutWindow(t *testing.T) {
+	l, c := newTestLockout(2)
+
+	l.Attempt("a")
+	c.advance(time.Hour)
+	if _, lock := l.Attempt("a"); lock != 0 {
+		t.Errorf("lock = %s, want failures older than the window forgotten", lock)
+	}
+}
+
+func TestLockoutConcurrentAttempts(t *testing.T) {
+	l := NewLockout(5, time.Minute, time.Hour, time.Hour)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if wait, _ := l.Attempt("a"); wait == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 5 {
+		t.Errorf("%d concurrent attempts got through, want 5", got)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	c := &clock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	l := New(1, 2)
+	l.now = c.now
+
+	for i := 0; i < 2; i++ {
+		if result := l.Allow("a"); !result.Allowed {
+			t.Fatalf("request %d refused", i+1)
+		}
+	}
+
+	result := l.Allow("a")
+	if result.Allowed || result.RetryAfter != time.Second || result.Remaining != 0 {
+		t.Errorf("over the burst: %+v", result)
+	}
+
+	c.advance(time.Second)
+	if result := l.Allow("a"); !result.Allowed {
+		t.Errorf("refused after a refill: %+v", result)
+	}
+}
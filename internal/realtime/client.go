@@ -0,0 +1,213 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type Client struct {
+	ConversationID int64
+	UserID         int64
+
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	valid     func() bool
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+
+	// While the client replays the event log, live frames are held in
+	// backlog, guarded by the hub lock, and sent once the replay catches up.
+	// Events up to replayedID were sent by the replay and are skipped when
+	// the room delivers them late.
+	replaying  bool
+	backlog    []pending
+	replayedID int64
+}
+
+type pending struct {
+	id int64
+	js []byte
+}
+
+// Send queues msg for this client only.
+func (c *Client) Send(msg Outbound) {
+	js, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	c.enqueue(js)
+}
+
+// Broadcast sends msg to the other connections of the same conversation.
+func (c *Client) Broadcast(msg Outbound) {
+	c.hub.Broadcast(c.ConversationID, msg, c)
+}
+
+// enqueue must be called with the hub lock held.
+func (c *Client) enqueue(js []byte) {
+	select {
+	case <-c.done:
+	case c.send <- js:
+	default:
+		c.close(websocket.ClosePolicyViolation, "cliente lento demais")
+	}
+}
+
+// hold keeps a live frame until the replay is over. It must be called with
+// the hub lock held.
+func (c *Client) hold(id int64, js []byte) {
+	if len(c.backlog) >= sendBuffer {
+		c.close(websocket.ClosePolicyViolation, "cliente lento demais")
+		return
+	}
+	c.backlog = append(c.backlog, pending{id: id, js: js})
+}
+
+// resume sends the conversation's events after r.LastEventID, then the live
+// frames held meanwhile that the replay did not already cover. These frames
+// wait for the write pump instead of dropping a client whose buffer fills
+// up, since the missed events may be more than it holds.
+func (c *Client) resume(r *Resume) {
+	lastID := r.LastEventID
+	for {
+		events, err := r.List(lastID)
+		if err != nil {
+			c.close(websocket.CloseInternalServerErr, "falha ao recuperar eventos")
+			return
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for _, event := range events {
+			js, err := json.Marshal(eventOutbound(*event))
+			if err != nil {
+				continue
+			}
+			if !c.wait(js) {
+				return
+			}
+			lastID = event.ID
+		}
+	}
+
+	// Frames keep arriving while the backlog is sent, so it is drained
+	// until a check under the lock finds it empty and ends the replay.
+	for {
+		c.hub.mu.Lock()
+		backlog := c.backlog
+		c.backlog = nil
+		c.replayedID = lastID
+		if len(backlog) == 0 {
+			c.replaying = false
+		}
+		c.hub.mu.Unlock()
+
+		if len(backlog) == 0 {
+			return
+		}
+		for _, p := range backlog {
+			if p.id != 0 && p.id <= lastID {
+				continue
+			}
+			if !c.wait(p.js) {
+				return
+			}
+		}
+	}
+}
+
+// wait queues js, blocking until the write pump takes it or the client is
+// closed.
+func (c *Client) wait(js []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	case c.send <- js:
+		return true
+	}
+}
+
+func (c *Client) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.leave(c)
+		c.close(websocket.CloseNormalClosure, "")
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		msg := Inbound{}
+		err := c.conn.ReadJSON(&msg)
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.Send(Outbound{Type: TypeError, Error: "Mensagem JSON malformada"})
+				continue
+			}
+			return
+		}
+
+		c.hub.handler.HandleInbound(c, msg)
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case js := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, js); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-ticker.C:
+			if c.valid != nil && !c.valid() {
+				c.close(websocket.ClosePolicyViolation, "sessão encerrada")
+				continue
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/events"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 8 * 1024
+	sendBuffer     = 64
+)
+
+// Handler processes the frames received from clients.
+type Handler interface {
+	HandleInbound(c *Client, msg Inbound)
+}
+
+// Hub keeps one room per conversation with at least one connected client.
+// Each room subscribes to the conversation's events on the broker and fans
+// them out, together with ephemeral signals such as typing indicators, to
+// every connection of the conversation (multiple tabs and devices).
+type Hub struct {
+	broker  *events.Broker
+	handler Handler
+
+	mu       sync.Mutex
+	rooms    map[int64]*room
+	shutdown bool
+}
+
+type room struct {
+	conversationID int64
+	sub            *events.Subscription
+	clients        map[*Client]struct{}
+}
+
+func NewHub(broker *events.Broker, handler Handler) *Hub {
+	return &Hub{
+		broker:  broker,
+		handler: handler,
+		rooms:   map[int64]*room{},
+	}
+}
+
+// Resume asks Serve to replay the conversation's event log from after
+// LastEventID before the client gets live events. List returns the next
+// batch of events after the given ID, and none once the log is exhausted.
+type Resume struct {
+	LastEventID int64
+	List        func(afterID int64) ([]*conversations.Event, error)
+}
+
+// Serve registers an upgraded connection and blocks until it is closed.
+// valid is called periodically and the connection is closed once it reports
+// that the session which opened it has ended. With a non-nil resume, the
+// events the client missed are replayed first.
+func (h *Hub) Serve(conn *websocket.Conn, conversationID, userID int64, resume *Resume, valid func() bool) {
+	c := &Client{
+		hub:            h,
+		conn:           conn,
+		send:           make(chan []byte, sendBuffer),
+		done:           make(chan struct{}),
+		valid:          valid,
+		replaying:      resume != nil,
+		ConversationID: conversationID,
+		UserID:         userID,
+	}
+
+	if !h.join(c) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "servidor encerrando"), time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	go c.writePump()
+	if resume != nil {
+		// The client joined the room first, so events published during the
+		// replay are held rather than lost.
+		c.resume(resume)
+	}
+	c.readPump()
+}
+
+func (h *Hub) join(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.shutdown {
+		return false
+	}
+
+	r, ok := h.rooms[c.ConversationID]
+	if !ok {
+		r = &room{
+			conversationID: c.ConversationID,
+			sub:            h.broker.Subscribe(c.ConversationID),
+			clients:        map[*Client]struct{}{},
+		}
+		h.rooms[c.ConversationID] = r
+		go h.pump(r)
+	}
+
+	r.clients[c] = struct{}{}
+	return true
+}
+
+func (h *Hub) leave(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[c.ConversationID]
+	if !ok {
+		return
+	}
+
+	delete(r.clients, c)
+	if len(r.clients) == 0 {
+		delete(h.rooms, c.ConversationID)
+		r.sub.Close()
+	}
+}
+
+// pump forwards the room's broker events to its clients until the
+// subscription ends.
+func (h *Hub) pump(r *room) {
+	for event := range r.sub.C {
+		h.Broadcast(r.conversationID, eventOutbound(event), nil)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The broker drops subscriptions that fall behind. Closing the clients
+	// makes them reconnect and resume from the event log with the ID of the
+	// last event they received as last_event_id.
+	if h.rooms[r.conversationID] == r {
+		delete(h.rooms, r.conversationID)
+	}
+	for c := range r.clients {
+		c.close(websocket.CloseTryAgainLater, "assinatura de eventos encerrada")
+	}
+}
+
+// Broadcast sends msg to every client of the conversation except skip. A
+// client that cannot keep up is disconnected rather than slowing everyone
+// else down.
+func (h *Hub) Broadcast(conversationID int64, msg Outbound, skip *Client) {
+	js, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[conversationID]
+	if !ok {
+		return
+	}
+
+	for c := range r.clients {
+		if c == skip {
+			continue
+		}
+		if msg.ID != 0 && msg.ID <= c.replayedID {
+			continue
+		}
+		if c.replaying {
+			c.hold(msg.ID, js)
+			continue
+		}
+		c.enqueue(js)
+	}
+}
+
+// Shutdown sends a going-away close frame to every client and refuses new
+// connections. It is meant to be registered with http.Server.RegisterOnShutdown,
+// since hijacked connections are not tracked by the server.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.shutdown = true
+	for _, r := range h.rooms {
+		for c := range r.clients {
+			c.close(websocket.CloseGoingAway, "servidor encerrando")
+		}
+	}
+}
+
+// CloseUser closes every connection of the user, e.g. after the user logs out
+// everywhere, changes password or is disabled.
+func (h *Hub) CloseUser(userID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, r := range h.rooms {
+		for c := range r.clients {
+			if c.UserID == userID {
+				c.close(websocket.ClosePolicyViolation, "sessão encerrada")
+			}
+		}
+	}
+}
+
+func eventOutbound(event conversations.Event) Outbound {
+	return Outbound{ID: event.ID, Type: string(event.Type), Data: event.Data}
+}
@@ -0,0 +1,31 @@
+package realtime
+
+import "encoding/json"
+
+const (
+	TypeMessageSend = "message.send"
+	TypeTyping      = "typing"
+	TypeRead        = "read"
+	TypeError       = "error"
+)
+
+// Inbound is a frame sent by a client. Only the fields relevant to its type
+// are set.
+type Inbound struct {
+	Type      string `json:"type"`
+	Content   string `json:"content,omitempty"`
+	Typing    bool   `json:"typing,omitempty"`
+	MessageID int64  `json:"messageId,omitempty,string"`
+}
+
+// Outbound is a frame sent to clients. Conversation events keep the ID they
+// have in the event log, so clients can resume after reconnecting by passing
+// the last one as the last_event_id query parameter.
+type Outbound struct {
+	ID     int64           `json:"id,omitempty,string"`
+	Type   string          `json:"type"`
+	UserID int64           `json:"userId,omitempty,string"`
+	Typing *bool           `json:"typing,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
@@ -0,0 +1,131 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Candidate is a collaborator that may receive a conversation, along with
+// their current workload.
+type Candidate struct {
+	UserID         int64
+	Capacity       int
+	Open           int
+	Skills         []string
+	LastAssignedAt time.Time
+}
+
+func (c Candidate) Available() bool {
+	return c.Open < c.Capacity
+}
+
+// Request describes the conversation being routed.
+type Request struct {
+	Tags []string
+}
+
+// Strategy picks the collaborator who should receive a conversation among
+// online candidates. Candidates at capacity are never picked.
+type Strategy interface {
+	Name() string
+	Pick(candidates []Candidate, req Request) (Candidate, bool)
+}
+
+func New(name string) (Strategy, error) {
+	switch name {
+	case "round-robin":
+		return RoundRobin{}, nil
+	case "least-busy":
+		return LeastBusy{}, nil
+	case "skills":
+		return SkillBased{}, nil
+	default:
+		return nil, fmt.Errorf("Estratégia de roteamento desconhecida: %s", name)
+	}
+}
+
+// RoundRobin cycles through collaborators by giving the conversation to the
+// one who has gone the longest without an assignment. Using the persisted
+// last assignment time keeps the rotation fair across restarts and
+// instances.
+type RoundRobin struct{}
+
+func (RoundRobin) Name() string { return "round-robin" }
+
+func (rr RoundRobin) Pick(candidates []Candidate, req Request) (Candidate, bool) {
+	return pick(candidates, rr.less)
+}
+
+// LeastBusy gives the conversation to the collaborator with the fewest open
+// conversations, breaking ties in round-robin order.
+type LeastBusy struct{}
+
+func (LeastBusy) Name() string { return "least-busy" }
+
+func (LeastBusy) Pick(candidates []Candidate, req Request) (Candidate, bool) {
+	return pick(candidates, lessBusy)
+}
+
+// SkillBased prefers collaborators whose skills cover the most tags of the
+// conversation, then the least busy among them. When nobody has a matching
+// skill it falls back to LeastBusy.
+type SkillBased struct{}
+
+func (SkillBased) Name() string { return "skills" }
+
+func (SkillBased) Pick(candidates []Candidate, req Request) (Candidate, bool) {
+	return pick(candidates, func(a, b Candidate) bool {
+		ma, mb := matches(a.Skills, req.Tags), matches(b.Skills, req.Tags)
+		if ma != mb {
+			return ma > mb
+		}
+		return lessBusy(a, b)
+	})
+}
+
+func lessBusy(a, b Candidate) bool {
+	if a.Open != b.Open {
+		return a.Open < b.Open
+	}
+	return RoundRobin{}.less(a, b)
+}
+
+// less orders collaborators who were never assigned anything first, since
+// their zero LastAssignedAt is before any real time.
+func (RoundRobin) less(a, b Candidate) bool {
+	if !a.LastAssignedAt.Equal(b.LastAssignedAt) {
+		return a.LastAssignedAt.Before(b.LastAssignedAt)
+	}
+	return a.UserID < b.UserID
+}
+
+func pick(candidates []Candidate, less func(a, b Candidate) bool) (Candidate, bool) {
+	var best Candidate
+	found := false
+
+	for _, c := range candidates {
+		if !c.Available() {
+			continue
+		}
+		if !found || less(c, best) {
+			best = c
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+func matches(skills, tags []string) int {
+	n := 0
+	for _, tag := range tags {
+		for _, skill := range skills {
+			if strings.EqualFold(tag, skill) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
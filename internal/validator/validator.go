@@ -2,6 +2,38 @@ package validator
 
 import "regexp"
 
+// Patterns for the structured data users type in conversations.
+const (
+	emailPattern  = `[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+`
+	cpfPattern    = `\d{3}\.?\d{3}\.?\d{3}[-.]?\d{2}`
+	phonePattern  = `(?:\+?55[\s-]?)?(?:\(?\d{2}\)?[\s-]?)?(?:9[\s-]?)?\d{4}[\s-]?\d{4}`
+	datePattern   = `\d{1,2}[/.-]\d{1,2}(?:[/.-]\d{2}(?:\d{2})?)?|\d{4}-\d{2}-\d{2}`
+	numberPattern = `[-+]?(?:\d{1,3}(?:\.\d{3})+(?:,\d+)?|\d+,\d+|\d+\.\d+|\d+)`
+)
+
+// These match the whole value.
+var (
+	EmailRX  = regexp.MustCompile(`^(?:` + emailPattern + `)$`)
+	CPFRX    = regexp.MustCompile(`^(?:` + cpfPattern + `)$`)
+	PhoneRX  = regexp.MustCompile(`^(?:` + phonePattern + `)$`)
+	DateRX   = regexp.MustCompile(`^(?:` + datePattern + `)$`)
+	NumberRX = regexp.MustCompile(`^(?:` + numberPattern + `)$`)
+)
+
+// These find the same values inside free text, preceded by the start of the
+// text, a space or punctuation, and capture them in the first group. The
+// character after the value is left to the caller, since consuming it would
+// hide a value that starts right after it.
+const finderPrefix = `(?:^|[\s(<:;,])`
+
+var (
+	EmailFinderRX  = regexp.MustCompile(finderPrefix + `(` + emailPattern + `)`)
+	CPFFinderRX    = regexp.MustCompile(finderPrefix + `(` + cpfPattern + `)`)
+	PhoneFinderRX  = regexp.MustCompile(finderPrefix + `(` + phonePattern + `)`)
+	DateFinderRX   = regexp.MustCompile(finderPrefix + `(` + datePattern + `)`)
+	NumberFinderRX = regexp.MustCompile(finderPrefix + `(` + numberPattern + `)`)
+)
+
 type Validator struct {
 	Errors map[string]string
 }
@@ -46,3 +78,40 @@ func Unique(values []string) bool {
 	}
 	return len(values) == len(uniqueValues)
 }
+
+// ValidCPF checks the two check digits of a CPF, given with or without
+// punctuation. Sequences of a single repeated digit pass the checksum but
+// are not valid CPFs.
+func ValidCPF(value string) bool {
+	digits := make([]int, 0, 11)
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) != 11 {
+		return false
+	}
+
+	repeated := true
+	for _, d := range digits[1:] {
+		if d != digits[0] {
+			repeated = false
+		}
+	}
+	if repeated {
+		return false
+	}
+
+	for n := 9; n <= 10; n++ {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += digits[i] * (n + 1 - i)
+		}
+		check := sum * 10 % 11 % 10
+		if check != digits[n] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestPatternsAndFinders(t *testing.T) {
+	tests := []struct {
+		name   string
+		rx     *regexp.Regexp
+		finder *regexp.Regexp
+		value  string
+	}{
+		{"email", EmailRX, EmailFinderRX, "fulano@example.com"},
+		{"cpf", CPFRX, CPFFinderRX, "529.982.247-25"},
+		{"phone", PhoneRX, PhoneFinderRX, "(11) 98765-4321"},
+		{"short date", DateRX, DateFinderRX, "10/03"},
+		{"iso date", DateRX, DateFinderRX, "2024-03-10"},
+		{"number", NumberRX, NumberFinderRX, "1.234,5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.rx.MatchString(tt.value) {
+				t.Errorf("%s does not match %q", tt.rx, tt.value)
+			}
+			if tt.rx.MatchString("valor " + tt.value) {
+				t.Errorf("%s matches a value with a prefix", tt.rx)
+			}
+
+			m := tt.finder.FindStringSubmatch("valor: " + tt.value)
+			if m == nil || m[1] != tt.value {
+				t.Errorf("finder found %q, want %q", m, tt.value)
+			}
+		})
+	}
+}
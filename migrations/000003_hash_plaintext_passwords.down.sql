@@ -0,0 +1,2 @@
+-- Password hashes cannot be reverted to plaintext.
+SELECT 1;
@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+-- Existing rows hold plaintext passwords. pgcrypto's blowfish crypt produces
+-- standard $2a$ bcrypt hashes, which the API verifies and transparently
+-- rehashes to the configured algorithm on the next successful signin.
+UPDATE users
+SET password = crypt(password, gen_salt('bf', 12))
+WHERE password NOT LIKE '$2_$%' AND password NOT LIKE '$argon2id$%';
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ;
+UPDATE users SET password_changed_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+ALTER TABLE users ALTER COLUMN password_changed_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ALTER COLUMN password_changed_at SET NOT NULL;
+
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMPTZ;
+
+CREATE TABLE revoked_tokens (
+    jti UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX revoked_tokens_expires_at_idx ON revoked_tokens (expires_at);
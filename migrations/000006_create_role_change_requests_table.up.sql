@@ -0,0 +1,19 @@
+CREATE TABLE role_change_requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    previous_role VARCHAR(20) NOT NULL,
+    requested_role VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    review_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMPTZ,
+    CONSTRAINT valid_requested_role CHECK (requested_role IN ('admin', 'collaborator', 'user')),
+    CONSTRAINT valid_status CHECK (status IN ('pending', 'approved', 'rejected'))
+);
+
+CREATE INDEX role_change_requests_status_idx ON role_change_requests (status);
+
+CREATE UNIQUE INDEX role_change_requests_one_pending_idx
+    ON role_change_requests (user_id) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_created_at_idx;
+DROP INDEX IF EXISTS users_role_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS must_reset_password;
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN must_reset_password BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX users_role_idx ON users (role);
+CREATE INDEX users_created_at_idx ON users (created_at);
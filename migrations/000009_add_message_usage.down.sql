@@ -0,0 +1,4 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS cost;
+ALTER TABLE messages DROP COLUMN IF EXISTS completion_tokens;
+ALTER TABLE messages DROP COLUMN IF EXISTS prompt_tokens;
+ALTER TABLE messages DROP COLUMN IF EXISTS model;
@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN model VARCHAR(100);
+ALTER TABLE messages ADD COLUMN prompt_tokens INTEGER;
+ALTER TABLE messages ADD COLUMN completion_tokens INTEGER;
+ALTER TABLE messages ADD COLUMN cost NUMERIC(12, 6);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS handoff_events;
+DROP TABLE IF EXISTS handoffs;
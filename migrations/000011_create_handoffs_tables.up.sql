@@ -0,0 +1,28 @@
+CREATE TABLE handoffs (
+    id BIGSERIAL PRIMARY KEY,
+    conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    assigned_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    CONSTRAINT valid_handoff_status CHECK (status IN ('queued', 'assigned', 'closed'))
+);
+
+CREATE UNIQUE INDEX handoffs_one_active_per_conversation_idx
+    ON handoffs (conversation_id) WHERE status <> 'closed';
+CREATE INDEX handoffs_status_created_at_idx ON handoffs (status, created_at);
+CREATE INDEX handoffs_assigned_to_idx ON handoffs (assigned_to) WHERE status = 'assigned';
+
+CREATE TABLE handoff_events (
+    id BIGSERIAL PRIMARY KEY,
+    handoff_id BIGINT NOT NULL REFERENCES handoffs(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    target_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX handoff_events_handoff_id_idx ON handoff_events (handoff_id);
@@ -0,0 +1,3 @@
+ALTER TABLE handoffs DROP COLUMN IF EXISTS tags;
+DROP TABLE IF EXISTS assignments;
+DROP TABLE IF EXISTS collaborator_availability;
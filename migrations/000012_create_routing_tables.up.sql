@@ -0,0 +1,24 @@
+CREATE TABLE collaborator_availability (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'offline',
+    capacity INTEGER NOT NULL DEFAULT 5,
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    last_assigned_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_availability_status CHECK (status IN ('online', 'away', 'offline')),
+    CONSTRAINT valid_capacity CHECK (capacity >= 0)
+);
+
+CREATE TABLE assignments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    handoff_id BIGINT NOT NULL REFERENCES handoffs(id) ON DELETE CASCADE,
+    strategy VARCHAR(20) NOT NULL,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMPTZ
+);
+
+CREATE INDEX assignments_user_id_open_idx ON assignments (user_id) WHERE released_at IS NULL;
+CREATE UNIQUE INDEX assignments_handoff_id_open_idx ON assignments (handoff_id) WHERE released_at IS NULL;
+
+ALTER TABLE handoffs ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS faq_articles;
+DROP FUNCTION IF EXISTS faq_articles_search_update();
+DROP FUNCTION IF EXISTS faq_search_config(VARCHAR);
@@ -0,0 +1,40 @@
+CREATE TABLE faq_articles (
+    id BIGSERIAL PRIMARY KEY,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    language VARCHAR(5) NOT NULL DEFAULT 'pt',
+    search TSVECTOR NOT NULL DEFAULT ''::tsvector,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT valid_faq_language CHECK (language IN ('pt', 'en', 'es'))
+);
+
+-- The text search configuration depends on the article language, so the
+-- document is built by a trigger rather than a generated column.
+CREATE FUNCTION faq_search_config(language VARCHAR) RETURNS regconfig AS $$
+    SELECT CASE language
+        WHEN 'en' THEN 'english'::regconfig
+        WHEN 'es' THEN 'spanish'::regconfig
+        ELSE 'portuguese'::regconfig
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE FUNCTION faq_articles_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search :=
+        setweight(to_tsvector(faq_search_config(NEW.language), NEW.question), 'A') ||
+        setweight(to_tsvector(faq_search_config(NEW.language), array_to_string(NEW.tags, ' ')), 'B') ||
+        setweight(to_tsvector(faq_search_config(NEW.language), NEW.answer), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER faq_articles_search_update
+BEFORE INSERT OR UPDATE OF question, answer, tags, language ON faq_articles
+FOR EACH ROW EXECUTE FUNCTION faq_articles_search_update();
+
+CREATE INDEX faq_articles_search_idx ON faq_articles USING GIN (search);
+CREATE INDEX faq_articles_tags_idx ON faq_articles USING GIN (tags);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS faq_articles_language_question_key;
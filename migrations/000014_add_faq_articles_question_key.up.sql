@@ -0,0 +1 @@
+CREATE UNIQUE INDEX faq_articles_language_question_key ON faq_articles (language, question);
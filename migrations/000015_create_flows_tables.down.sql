@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS flow_sessions;
+DROP TABLE IF EXISTS flows;
@@ -0,0 +1,27 @@
+CREATE TABLE flows (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    definition JSONB NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE flow_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    flow_id BIGINT NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    current_node VARCHAR(64) NOT NULL,
+    variables JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+    version INTEGER NOT NULL DEFAULT 1,
+    CONSTRAINT valid_flow_session_status CHECK (status IN ('active', 'completed', 'cancelled'))
+);
+
+CREATE UNIQUE INDEX flow_sessions_one_active_per_user_idx ON flow_sessions (user_id) WHERE status = 'active';
+CREATE INDEX flow_sessions_flow_id_idx ON flow_sessions (flow_id);
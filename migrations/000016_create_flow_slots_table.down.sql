@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS flow_slots;
+ALTER TABLE flow_sessions DROP COLUMN IF EXISTS attempts;
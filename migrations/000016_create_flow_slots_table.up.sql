@@ -0,0 +1,15 @@
+ALTER TABLE flow_sessions ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE flow_slots (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES flow_sessions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    value TEXT NOT NULL,
+    raw_text TEXT NOT NULL,
+    filled_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (session_id, name)
+);
+
+CREATE INDEX flow_slots_user_id_idx ON flow_slots (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS nlu_models;
+DROP TABLE IF EXISTS intent_examples;
+DROP TABLE IF EXISTS intents;
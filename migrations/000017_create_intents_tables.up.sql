@@ -0,0 +1,25 @@
+CREATE TABLE intents (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE intent_examples (
+    id BIGSERIAL PRIMARY KEY,
+    intent_id BIGINT NOT NULL REFERENCES intents(id) ON DELETE CASCADE,
+    text TEXT NOT NULL
+);
+
+CREATE INDEX intent_examples_intent_id_idx ON intent_examples (intent_id);
+
+CREATE TABLE nlu_models (
+    id BIGSERIAL PRIMARY KEY,
+    model JSONB NOT NULL,
+    intents INTEGER NOT NULL,
+    examples INTEGER NOT NULL,
+    trained_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    trained_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
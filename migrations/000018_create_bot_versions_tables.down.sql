@@ -0,0 +1,6 @@
+ALTER TABLE flow_sessions DROP COLUMN IF EXISTS bot_version_id;
+DELETE FROM flow_sessions WHERE flow_id NOT IN (SELECT id FROM flows);
+ALTER TABLE flow_sessions ADD CONSTRAINT flow_sessions_flow_id_fkey FOREIGN KEY (flow_id) REFERENCES flows(id) ON DELETE CASCADE;
+ALTER TABLE intents DROP COLUMN IF EXISTS responses;
+DROP TABLE IF EXISTS bot_version_events;
+DROP TABLE IF EXISTS bot_versions;
@@ -0,0 +1,37 @@
+CREATE TABLE bot_versions (
+    id BIGSERIAL PRIMARY KEY,
+    number INTEGER NOT NULL UNIQUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    notes TEXT NOT NULL DEFAULT '',
+    snapshot JSONB NOT NULL,
+    model JSONB,
+    validation_errors JSONB,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    validated_at TIMESTAMPTZ,
+    published_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    published_at TIMESTAMPTZ,
+    CONSTRAINT valid_bot_version_status CHECK (status IN ('draft', 'invalid', 'validated', 'published'))
+);
+
+CREATE UNIQUE INDEX bot_versions_one_active_idx ON bot_versions (active) WHERE active;
+
+CREATE TABLE bot_version_events (
+    id BIGSERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL REFERENCES bot_versions(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    previous_version_id BIGINT REFERENCES bot_versions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX bot_version_events_created_at_idx ON bot_version_events (created_at DESC);
+
+ALTER TABLE intents ADD COLUMN responses TEXT[] NOT NULL DEFAULT '{}';
+
+-- Sessions pinned to a published version keep running from its snapshot
+-- even after the flow is removed from the editable definitions.
+ALTER TABLE flow_sessions DROP CONSTRAINT flow_sessions_flow_id_fkey;
+ALTER TABLE flow_sessions ADD COLUMN bot_version_id BIGINT REFERENCES bot_versions(id) ON DELETE SET NULL;
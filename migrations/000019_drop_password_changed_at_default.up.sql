@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN password_changed_at DROP DEFAULT;
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/filters"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filter := users.ListFilter{
+		Role:          users.UserRole(app.readString(qs, "role", "")),
+		Email:         app.readString(qs, "email", ""),
+		CreatedAfter:  app.readTime(qs, "created_after", v),
+		CreatedBefore: app.readTime(qs, "created_before", v),
+	}
+
+	f := filters.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafelist: []string{"id", "email", "name", "role", "created_at", "-id", "-email", "-name", "-role", "-created_at"},
+	}
+
+	if filter.Role != "" {
+		v.Check(filter.Role.Valid(), "role", "deve ser uma das opções (admin|collaborator|user)")
+	}
+
+	if f.Validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, metadata, err := app.models.Users.List(filter, f)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	viewer := app.viewer(r)
+	responses := make([]users.UserResponse, 0, len(list))
+	for _, user := range list {
+		responses = append(responses, users.NewUserResponse(user, viewer))
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"users": responses, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.Get(id)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, users.NewUserResponse(user, app.viewer(r)), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) disableUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.setUserDisabled(w, r, true)
+}
+
+func (app *application) enableUserHandler(w http.ResponseWriter, r *http.Request) {
+	app.setUserDisabled(w, r, false)
+}
+
+func (app *application) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	viewer := app.viewer(r)
+	if disabled && id == viewer.ID {
+		app.badRequestResponse(w, r, errors.New("Administradores não podem desativar a própria conta"))
+		return
+	}
+
+	user, err := app.models.Users.SetDisabled(id, disabled)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if disabled {
+		err = app.revokeSessions(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		app.models.Revocations.InvalidateUser(id)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, users.NewUserResponse(user, viewer), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) forcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user, err := app.models.Users.RequirePasswordReset(id)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.revokeSessions(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, users.NewUserResponse(user, app.viewer(r)), nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/tokens"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) issueTokens(user *users.User) (map[string]any, error) {
+	accessToken, err := app.generateJWT(user.ID, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := tokens.NewRefreshToken(user.ID, app.config.auth.refreshTokenTTL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.models.RefreshTokens.Insert(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string]any{
+		"token":        accessToken,
+		"refreshToken": refreshToken.Plaintext,
+	}
+	return response, nil
+}
+
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	payload := tokens.RefreshTokenDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	refreshToken, err := app.models.RefreshTokens.Rotate(payload.RefreshToken, app.config.auth.refreshTokenTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, tokens.ErrRefreshTokenReused):
+			app.logError(r, err)
+			app.unauthorizedResponse(w, r, "Token de atualização reutilizado, todas as sessões derivadas dele foram encerradas")
+		case errors.Is(err, tokens.ErrInvalidRefreshToken):
+			app.unauthorizedResponse(w, r, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(refreshToken.UserID)
+	if err != nil {
+		app.unauthorizedResponse(w, r, "Usuário do token de atualização não foi encontrado")
+		return
+	}
+
+	if user.DisabledAt != nil {
+		app.unauthorizedResponse(w, r, users.ErrUserDisabled.Error())
+		return
+	}
+
+	accessToken, err := app.generateJWT(user.ID, user.Role)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"token":        accessToken,
+		"refreshToken": refreshToken.Plaintext,
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*Claims)
+	if !ok {
+		app.unauthorizedResponse(w, r, "Alegações do token não foram encontradas no contexto da requisição")
+		return
+	}
+
+	if r.ContentLength != 0 {
+		payload := tokens.RefreshTokenDTO{}
+		err := app.readJSON(w, r, &payload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if payload.RefreshToken != "" {
+			err = app.models.RefreshTokens.RevokeFamilyOf(payload.RefreshToken, claims.UserID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	err := app.models.Revocations.Revoke(claims.Id, claims.UserID, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) logoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	err := app.revokeSessions(userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) revokeSessions(userID int64) error {
+	err := app.models.RefreshTokens.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+	err = app.models.Revocations.RevokeAllSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	app.disconnect(userID)
+	return nil
+}
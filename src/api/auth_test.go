@@ -0,0 +1,313 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+type storedRefreshToken struct {
+	id        int64
+	userID    int64
+	familyID  string
+	hash      []byte
+	expiresAt time.Time
+	usedAt    *time.Time
+	revokedAt *time.Time
+}
+
+// refreshTokenStore keeps the refresh_tokens table of a test in memory.
+type refreshTokenStore struct {
+	mu     sync.Mutex
+	tokens []*storedRefreshToken
+}
+
+func nullable(t *time.Time) driver.Value {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func newRefreshTokenStore(f *fakeDB) *refreshTokenStore {
+	s := &refreshTokenStore{}
+
+	f.on(`INSERT INTO refresh_tokens`, func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		token := &storedRefreshToken{
+			id:        int64(len(s.tokens) + 1),
+			userID:    args[0].(int64),
+			familyID:  fmt.Sprint(args[1]),
+			hash:      args[2].([]byte),
+			expiresAt: args[3].(time.Time),
+		}
+		s.tokens = append(s.tokens, token)
+		return row(token.id, time.Now())
+	})
+	f.on(`SELECT id, user_id, family_id, expires_at, used_at, revoked_at`, func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for _, token := range s.tokens {
+			if string(token.hash) == string(args[0].([]byte)) {
+				return row(token.id, token.userID, token.familyID, token.expiresAt, nullable(token.usedAt), nullable(token.revokedAt))
+			}
+		}
+		return noRows(6)
+	})
+	f.on(`SET used_at = CURRENT_TIMESTAMP`, func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		now := time.Now()
+		s.tokens[args[1].(int64)-1].usedAt = &now
+		return fakeResult{RowsAffected: 1}
+	})
+	f.on(`SET revoked_at = CURRENT_TIMESTAMP\s+WHERE family_id = \$1`, func(args []driver.Value) fakeResult {
+		return s.revoke(func(token *storedRefreshToken) bool { return token.familyID == fmt.Sprint(args[0]) })
+	})
+	f.on(`SET revoked_at = CURRENT_TIMESTAMP\s+WHERE user_id = \$1`, func(args []driver.Value) fakeResult {
+		return s.revoke(func(token *storedRefreshToken) bool { return token.userID == args[0].(int64) })
+	})
+	f.on(`SET sessions_revoked_at`, func([]driver.Value) fakeResult {
+		return fakeResult{RowsAffected: 1}
+	})
+	return s
+}
+
+func (s *refreshTokenStore) revoke(match func(*storedRefreshToken) bool) fakeResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	revoked := int64(0)
+	for _, token := range s.tokens {
+		if token.revokedAt == nil && match(token) {
+			token.revokedAt = &now
+			revoked++
+		}
+	}
+	return fakeResult{RowsAffected: revoked}
+}
+
+func (s *refreshTokenStore) get(id int64) *storedRefreshToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[id-1]
+}
+
+func (s *refreshTokenStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tokens)
+}
+
+// signinForRefresh signs the user in and returns its access and refresh
+// tokens.
+func signinForRefresh(t *testing.T, h http.Handler) (string, string) {
+	t.Helper()
+
+	body := map[string]string{"email": "user@example.com", "password": testPassword}
+	status, response := do(t, h, http.MethodPost, "/v1/auth/signin", "", body)
+	if status != http.StatusOK {
+		t.Fatalf("signin: status = %d: %v", status, response)
+	}
+	return response["token"].(string), response["refreshToken"].(string)
+}
+
+func refresh(t *testing.T, h http.Handler, token string) (int, map[string]any) {
+	t.Helper()
+	return do(t, h, http.MethodPost, "/v1/auth/refresh", "", map[string]string{"refreshToken": token})
+}
+
+func newRefreshTestApp(t *testing.T) (*application, *fakeDB, *refreshTokenStore, http.Handler) {
+	t.Helper()
+
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	store := newRefreshTokenStore(f)
+	userDB(t, app, f, 7, users.RoleUser)
+	return app, f, store, app.routes()
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	_, _, store, h := newRefreshTestApp(t)
+	_, first := signinForRefresh(t, h)
+
+	status, response := refresh(t, h, first)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, response)
+	}
+
+	second, _ := response["refreshToken"].(string)
+	if second == "" || second == first || response["token"] == "" {
+		t.Fatalf("response = %v, want a new pair of tokens", response)
+	}
+	if store.len() != 2 {
+		t.Fatalf("stored %d tokens, want 2", store.len())
+	}
+	if store.get(1).usedAt == nil {
+		t.Error("the exchanged token was not marked as used")
+	}
+	if store.get(2).familyID != store.get(1).familyID {
+		t.Error("the new token is not in the family of the exchanged one")
+	}
+
+	if status, response := refresh(t, h, second); status != http.StatusOK {
+		t.Errorf("refreshing with the new token: status = %d: %v", status, response)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	_, f, store, h := newRefreshTestApp(t)
+	_, first := signinForRefresh(t, h)
+	_, other := signinForRefresh(t, h)
+
+	status, response := refresh(t, h, first)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, response)
+	}
+	second := response["refreshToken"].(string)
+	commits, _ := f.transactions()
+
+	status, response = refresh(t, h, first)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("reusing a token: status = %d, want %d: %v", status, http.StatusUnauthorized, response)
+	}
+	if after, _ := f.transactions(); after != commits+1 {
+		t.Errorf("the family revocation was not committed: %d commits, want %d", after, commits+1)
+	}
+
+	for _, id := range []int64{1, 3} {
+		if store.get(id).revokedAt == nil {
+			t.Errorf("token %d of the reused family was not revoked", id)
+		}
+	}
+	if store.get(2).revokedAt != nil {
+		t.Error("a token of another family was revoked")
+	}
+
+	if status, _ := refresh(t, h, second); status != http.StatusUnauthorized {
+		t.Errorf("refreshing with the revoked successor: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status, _ := refresh(t, h, other); status != http.StatusOK {
+		t.Errorf("refreshing another session: status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRefreshExpiredToken(t *testing.T) {
+	_, f, store, h := newRefreshTestApp(t)
+	_, token := signinForRefresh(t, h)
+	store.get(1).expiresAt = time.Now().Add(-time.Second)
+	_, rollbacks := f.transactions()
+
+	status, response := refresh(t, h, token)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusUnauthorized, response)
+	}
+	if store.len() != 1 || store.get(1).usedAt != nil {
+		t.Error("an expired token was exchanged")
+	}
+	if _, after := f.transactions(); after != rollbacks+1 {
+		t.Errorf("the transaction was not rolled back")
+	}
+}
+
+func TestRefreshAfterLogoutAll(t *testing.T) {
+	_, _, store, h := newRefreshTestApp(t)
+	access, first := signinForRefresh(t, h)
+	_, second := signinForRefresh(t, h)
+
+	status, response := do(t, h, http.MethodPost, "/v1/auth/logout-all", "Bearer "+access, nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("logout-all: status = %d: %v", status, response)
+	}
+
+	for i, token := range []string{first, second} {
+		if status, _ := refresh(t, h, token); status != http.StatusUnauthorized {
+			t.Errorf("refresh %d after logout-all: status = %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+	if store.len() != 2 {
+		t.Errorf("stored %d tokens, want no new token after logout-all", store.len())
+	}
+}
+
+// TestTokenIssuedRightAfterCutoff stands in for a database whose clock runs a
+// second ahead of the API: a cutoff taken from CURRENT_TIMESTAMP would be in
+// the future and reject the token issued right after it.
+func TestTokenIssuedRightAfterCutoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{"logout-all", http.MethodPost, "/v1/auth/logout-all", nil},
+		{"password change", http.MethodPatch, "/v1/user", map[string]string{"password": "senha-nova-456"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+
+			var mu sync.Mutex
+			cutoff := time.Now().Add(-time.Hour)
+			write := func(at driver.Value) {
+				mu.Lock()
+				defer mu.Unlock()
+				if written, ok := at.(time.Time); ok {
+					cutoff = written
+				} else {
+					cutoff = time.Now().Add(time.Second)
+				}
+			}
+
+			f.on(`SET sessions_revoked_at`, func(args []driver.Value) fakeResult {
+				var at driver.Value
+				if len(args) > 1 {
+					at = args[1]
+				}
+				write(at)
+				return fakeResult{RowsAffected: 1}
+			})
+			f.on(`UPDATE users\s+SET email`, func(args []driver.Value) fakeResult {
+				var at driver.Value
+				if len(args) > 6 {
+					at = args[6]
+				}
+				write(at)
+				return row(int64(7), args[0], args[2], string(users.RoleUser), "", time.Now(), nil, false)
+			})
+			f.on(`disabled_at IS NOT NULL`, func([]driver.Value) fakeResult {
+				mu.Lock()
+				defer mu.Unlock()
+				return row(cutoff, false, false)
+			})
+			newRefreshTokenStore(f)
+			userDB(t, app, f, 7, users.RoleUser)
+			h := app.routes()
+
+			access, _ := signinForRefresh(t, h)
+			if status, response := do(t, h, tt.method, tt.path, "Bearer "+access, tt.body); status >= 300 {
+				t.Fatalf("status = %d: %v", status, response)
+			}
+			if status, _ := do(t, h, http.MethodGet, "/v1/user", "Bearer "+access, nil); status != http.StatusUnauthorized {
+				t.Errorf("token issued before the cutoff: status = %d, want %d", status, http.StatusUnauthorized)
+			}
+
+			access, _ = signinForRefresh(t, h)
+			if status, response := do(t, h, http.MethodGet, "/v1/user", "Bearer "+access, nil); status != http.StatusOK {
+				t.Errorf("token issued right after the cutoff: status = %d: %v", status, response)
+			}
+		})
+	}
+}
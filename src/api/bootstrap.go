@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
+)
+
+// bootstrapAdmin makes sure a fresh installation has an administrator. It is
+// a no-op when no bootstrap email is configured or an admin already exists;
+// otherwise the account with that email is promoted, or created if missing.
+// An existing account is only promoted when the bootstrap password is its
+// password, so whoever registers that email first cannot become admin.
+func (app *application) bootstrapAdmin() error {
+	if app.config.bootstrap.email == "" {
+		return nil
+	}
+
+	count, err := app.models.Users.CountByRole(users.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if app.config.bootstrap.password == "" {
+		return errors.New("BOOTSTRAP_ADMIN_PASSWORD é obrigatório para criar o primeiro administrador")
+	}
+
+	user, err := app.models.Users.GetByEmail(app.config.bootstrap.email)
+	switch {
+	case errors.Is(err, users.ErrUserNotFound):
+		user = &users.User{
+			Email:    app.config.bootstrap.email,
+			Password: app.config.bootstrap.password,
+			Name:     "Administrador",
+			Role:     users.RoleUser,
+		}
+		err = app.models.Users.Insert(user)
+	case err == nil:
+		user, err = app.models.Users.Authenticate(app.config.bootstrap.email, app.config.bootstrap.password)
+		if errors.Is(err, password.ErrMismatch) || errors.Is(err, users.ErrUserDisabled) || errors.Is(err, users.ErrUserNotFound) {
+			return errors.New("A conta de BOOTSTRAP_ADMIN_EMAIL já existe e não aceita BOOTSTRAP_ADMIN_PASSWORD; promova um administrador manualmente")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = app.models.RoleRequests.ChangeRole(user.ID, user.ID, users.RoleAdmin, "Criação do primeiro administrador")
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Usuário promovido a administrador", "email", user.Email)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestBootstrapAdminExistingAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		promoted bool
+	}{
+		{"right password", testPassword, true},
+		{"wrong password", "senha-de-outra-pessoa", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+			app.config.bootstrap.email = "user@example.com"
+			app.config.bootstrap.password = tt.password
+
+			promoted := false
+			f.on(`SELECT count\(\*\)\s+FROM users`, func([]driver.Value) fakeResult {
+				return row(int64(0))
+			})
+			f.on(`SELECT role FROM users WHERE id = \$1 FOR UPDATE`, func([]driver.Value) fakeResult {
+				return row(string(users.RoleUser))
+			})
+			f.on(`UPDATE users SET role`, func(args []driver.Value) fakeResult {
+				promoted = fmt.Sprint(args[0]) == string(users.RoleAdmin)
+				return fakeResult{RowsAffected: 1}
+			})
+			f.on(`UPDATE role_change_requests`, func([]driver.Value) fakeResult {
+				return fakeResult{}
+			})
+			f.on(`INSERT INTO role_change_requests`, func(args []driver.Value) fakeResult {
+				return row(int64(1), args[0], "user", "admin", args[3], "approved", args[4], "", time.Now(), time.Now())
+			})
+			userDB(t, app, f, 7, users.RoleUser)
+
+			err := app.bootstrapAdmin()
+			if tt.promoted && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.promoted && err == nil {
+				t.Error("started with a bootstrap password that does not match the account")
+			}
+			if promoted != tt.promoted {
+				t.Errorf("promoted = %t, want %t", promoted, tt.promoted)
+			}
+		})
+	}
+}
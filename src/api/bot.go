@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/bot"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+const botHistorySize = 20
+
+// botContext builds the context handed to the responder from the most recent
+// messages of the conversation, excluding the message being answered.
+func (app *application) botContext(conversation *conversations.Conversation, current *conversations.Message) (bot.Conversation, error) {
+	messages, _, err := app.models.Messages.List(conversation.ID, current.ID, botHistorySize)
+	if err != nil {
+		return bot.Conversation{}, err
+	}
+
+	history := make([]bot.Message, 0, len(messages))
+	for i := len(messages) - 1; i >= 0; i-- {
+		history = append(history, bot.Message{
+			Role:    bot.Role(messages[i].Sender),
+			Content: messages[i].Content,
+		})
+	}
+
+	return bot.Conversation{
+		ID:      conversation.ID,
+		UserID:  conversation.UserID,
+		History: history,
+	}, nil
+}
+
+// reply asks the responder to answer message and persists every reply as a
+// bot message of the conversation. The bot stays quiet while the
+// conversation is handed off to a human.
+func (app *application) reply(ctx context.Context, conversation *conversations.Conversation, message *conversations.Message) ([]*conversations.Message, error) {
+	_, err := app.models.Handoffs.Active(conversation.ID)
+	if err == nil {
+		return []*conversations.Message{}, nil
+	} else if !errors.Is(err, handoffs.ErrHandoffNotFound) {
+		return nil, err
+	}
+
+	botConversation, err := app.botContext(conversation, message)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := bot.Stream(ctx, app.bot, botConversation, message.Content, func(index int, chunk string) error {
+		data := map[string]any{
+			"reply_to": strconv.FormatInt(message.ID, 10),
+			"index":    index,
+			"content":  chunk,
+		}
+		return app.publish(conversation.ID, conversations.EventReplyChunk, data)
+	})
+	if err != nil {
+		data := map[string]any{
+			"reply_to": strconv.FormatInt(message.ID, 10),
+			"error":    "Não foi possível gerar uma resposta",
+		}
+		if publishErr := app.publish(conversation.ID, conversations.EventReplyError, data); publishErr != nil {
+			return nil, publishErr
+		}
+		return nil, err
+	}
+
+	saved := make([]*conversations.Message, 0, len(replies))
+	for _, reply := range replies {
+		botMessage := &conversations.Message{
+			ConversationID: conversation.ID,
+			Sender:         conversations.SenderBot,
+			Content:        reply.Content,
+		}
+		if reply.Usage != nil {
+			botMessage.Usage = &conversations.Usage{
+				Model:            reply.Usage.Model,
+				PromptTokens:     reply.Usage.PromptTokens,
+				CompletionTokens: reply.Usage.CompletionTokens,
+				Cost:             reply.Usage.Cost,
+			}
+		}
+
+		err = app.models.Messages.Insert(botMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		err = app.publish(conversation.ID, conversations.EventMessage, botMessage)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, botMessage)
+	}
+
+	return saved, nil
+}
+
+// replyWriteMargin is left after the reply budget to store the replies and
+// write the response.
+const replyWriteMargin = 5 * time.Second
+
+// replyBudget is how long the bot may take to answer: one LLM timeout for the
+// first attempt and for each retry.
+func (app *application) replyBudget() time.Duration {
+	return app.config.llm.timeout * time.Duration(app.config.llm.maxRetries+1)
+}
+
+func (app *application) botReplyHandler(w http.ResponseWriter, r *http.Request) {
+	payload := conversations.BotReplyDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	conversation, err := app.models.Conversations.Get(payload.ConversationID)
+	if err != nil {
+		if errors.Is(err, conversations.ErrConversationNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Only the owner talks to the bot. Staff reply as agents through the
+	// messages endpoint, which checks the handoff assignment.
+	viewer := app.viewer(r)
+	if conversation.UserID != viewer.ID {
+		if viewer.Role.Can(users.PermConversationsManage) {
+			app.errorResponse(w, r, http.StatusForbidden, "Apenas o autor da conversa pode pedir respostas do bot")
+			return
+		}
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if conversation.Status == conversations.StatusClosed {
+		app.badRequestResponse(w, r, errors.New("A conversa está encerrada"))
+		return
+	}
+
+	message := &conversations.Message{
+		ConversationID: conversation.ID,
+		Sender:         conversations.SenderUser,
+		UserID:         &viewer.ID,
+		Content:        payload.Message,
+	}
+
+	err = app.models.Messages.Insert(message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.publish(conversation.ID, conversations.EventMessage, message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// A slow LLM may need longer than the server's WriteTimeout, so the
+	// deadline is pushed out to cover every attempt the client may make and
+	// the reply is cut off before the connection is.
+	budget := app.replyBudget()
+	ctx, cancel := context.WithTimeout(r.Context(), budget)
+	defer cancel()
+
+	err = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(budget + replyWriteMargin))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	replies, err := app.reply(ctx, conversation, message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"message": message,
+		"replies": replies,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
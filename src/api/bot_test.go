@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/bot"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+// conversationDB answers the queries of /v1/bot/reply for a single
+// conversation and records the messages and events written.
+type conversationDB struct {
+	mu       sync.Mutex
+	messages []conversations.Sender
+	contents []string
+	events   []conversations.EventType
+}
+
+func newConversationDB(f *fakeDB, id, owner int64, status conversations.Status) *conversationDB {
+	c := &conversationDB{}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	allowSessions(f)
+	f.on(`FROM conversations\s+WHERE id`, func([]driver.Value) fakeResult {
+		return row(id, owner, "Suporte", string(status), created, created)
+	})
+	f.on(`INSERT INTO messages`, func(args []driver.Value) fakeResult {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.messages = append(c.messages, conversations.Sender(fmt.Sprint(args[1])))
+		c.contents = append(c.contents, fmt.Sprint(args[3]))
+		return row(int64(100+len(c.messages)), time.Now())
+	})
+	f.on(`UPDATE conversations SET updated_at`, func([]driver.Value) fakeResult {
+		return fakeResult{RowsAffected: 1}
+	})
+	f.on(`INSERT INTO conversation_events`, func(args []driver.Value) fakeResult {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.events = append(c.events, conversations.EventType(fmt.Sprint(args[1])))
+		return row(int64(len(c.events)), time.Now())
+	})
+	f.on(`FROM handoffs`, func([]driver.Value) fakeResult { return noRows(10) })
+	f.on(`FROM messages\s+WHERE conversation_id = \$1 AND \(id <`, func([]driver.Value) fakeResult { return noRows(10) })
+	return c
+}
+
+func newBotTestApp(t *testing.T) (*application, *fakeDB) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	app.bot = bot.EchoResponder{}
+	return app, f
+}
+
+func TestBotReplyEchoesMessage(t *testing.T) {
+	app, f := newBotTestApp(t)
+	store := newConversationDB(f, 42, 7, conversations.StatusOpen)
+
+	sub := app.broker.Subscribe(42)
+	defer sub.Close()
+
+	body := map[string]any{"conversationId": "42", "message": "Olá, bot"}
+	status, response := do(t, app.routes(), http.MethodPost, "/v1/bot/reply", bearer(t, app, 7, users.RoleUser), body)
+	if status != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusCreated, response)
+	}
+
+	message, _ := response["message"].(map[string]any)
+	if message["sender"] != "user" || message["content"] != "Olá, bot" || message["user_id"] != "7" {
+		t.Errorf("message = %v", message)
+	}
+
+	replies, _ := response["replies"].([]any)
+	if len(replies) != 1 {
+		t.Fatalf("replies = %v, want one reply", replies)
+	}
+	reply := replies[0].(map[string]any)
+	if reply["sender"] != "bot" || reply["content"] != "Olá, bot" || reply["conversation_id"] != "42" {
+		t.Errorf("reply = %v", reply)
+	}
+	if _, ok := reply["user_id"]; ok {
+		t.Errorf("bot reply has a user_id: %v", reply)
+	}
+
+	wantMessages := []conversations.Sender{conversations.SenderUser, conversations.SenderBot}
+	if len(store.messages) != len(wantMessages) || store.messages[0] != wantMessages[0] || store.messages[1] != wantMessages[1] {
+		t.Errorf("stored senders = %v, want %v", store.messages, wantMessages)
+	}
+	for _, content := range store.contents {
+		if content != "Olá, bot" {
+			t.Errorf("stored content = %q, want the echoed message", content)
+		}
+	}
+
+	wantEvents := []conversations.EventType{conversations.EventMessage, conversations.EventReplyChunk, conversations.EventMessage}
+	if len(store.events) != len(wantEvents) {
+		t.Fatalf("events = %v, want %v", store.events, wantEvents)
+	}
+	for i, want := range wantEvents {
+		if store.events[i] != want {
+			t.Errorf("event %d = %s, want %s", i, store.events[i], want)
+		}
+
+		select {
+		case event := <-sub.C:
+			if event.Type != want {
+				t.Errorf("published event %d = %s, want %s", i, event.Type, want)
+			}
+		default:
+			t.Errorf("event %d was not published", i)
+		}
+	}
+}
+
+func TestBotReplyRefusals(t *testing.T) {
+	tests := []struct {
+		name   string
+		owner  int64
+		role   users.UserRole
+		state  conversations.Status
+		body   map[string]any
+		status int
+	}{
+		{"missing message", 7, users.RoleUser, conversations.StatusOpen, map[string]any{"conversationId": "42"}, http.StatusUnprocessableEntity},
+		{"conversation of another user", 8, users.RoleUser, conversations.StatusOpen, map[string]any{"conversationId": "42", "message": "oi"}, http.StatusNotFound},
+		{"staff on a conversation of another user", 8, users.RoleCollaborator, conversations.StatusOpen, map[string]any{"conversationId": "42", "message": "oi"}, http.StatusForbidden},
+		{"admin on a conversation of another user", 8, users.RoleAdmin, conversations.StatusOpen, map[string]any{"conversationId": "42", "message": "oi"}, http.StatusForbidden},
+		{"closed conversation", 7, users.RoleUser, conversations.StatusClosed, map[string]any{"conversationId": "42", "message": "oi"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, f := newBotTestApp(t)
+			store := newConversationDB(f, 42, tt.owner, tt.state)
+
+			status, response := do(t, app.routes(), http.MethodPost, "/v1/bot/reply", bearer(t, app, 7, tt.role), tt.body)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if _, ok := response["erro"]; !ok {
+				t.Errorf("response has no error: %v", response)
+			}
+			if len(store.messages) != 0 || len(store.events) != 0 {
+				t.Errorf("refused request wrote messages %v and events %v", store.messages, store.events)
+			}
+		})
+	}
+}
+
+func TestBotReplyAnswersFromFAQ(t *testing.T) {
+	tests := []struct {
+		name  string
+		faq   bool
+		score float64
+		want  string
+	}{
+		{"relevant article", true, 0.25, "Acesse o seu perfil e escolha Alterar senha."},
+		{"article below the threshold", true, 0.15, "como trocar a senha"},
+		{"knowledge base disabled", false, 0.9, "como trocar a senha"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, f := newBotTestApp(t)
+			app.config.bot.faq = tt.faq
+			app.config.bot.faqLanguage = "pt"
+			app.config.bot.faqThreshold = 0.2
+			app.bot = app.faqResponder(app.bot)
+
+			newConversationDB(f, 42, 7, conversations.StatusOpen)
+			f.on(`ts_rank\(search`, func(args []driver.Value) fakeResult {
+				if args[1] != "pt" {
+					t.Errorf("searched language %v, want pt", args[1])
+				}
+				created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+				return row(tt.score, int64(1), "Como trocar a senha?", "Acesse o seu perfil e escolha Alterar senha.",
+					[]byte("{conta}"), "pt", int64(1), created, created, int64(1))
+			})
+
+			body := map[string]any{"conversationId": "42", "message": "como trocar a senha"}
+			status, response := do(t, app.routes(), http.MethodPost, "/v1/bot/reply", bearer(t, app, 7, users.RoleUser), body)
+			if status != http.StatusCreated {
+				t.Fatalf("status = %d: %v", status, response)
+			}
+
+			replies, _ := response["replies"].([]any)
+			if len(replies) != 1 || replies[0].(map[string]any)["content"] != tt.want {
+				t.Errorf("replies = %v, want %q", replies, tt.want)
+			}
+		})
+	}
+}
+
+// slowResponder answers after delay, or gives up when the context is done.
+type slowResponder struct {
+	delay time.Duration
+}
+
+func (s slowResponder) Respond(ctx context.Context, conversation bot.Conversation, message string) ([]bot.Reply, error) {
+	select {
+	case <-time.After(s.delay):
+		return []bot.Reply{{Content: "Resposta demorada"}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestBotReplyOutlivesWriteTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		delay  time.Duration
+		status int
+	}{
+		{"reply slower than the write timeout", 200 * time.Millisecond, http.StatusCreated},
+		{"reply slower than the budget", time.Minute, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, f := newBotTestApp(t)
+			newConversationDB(f, 42, 7, conversations.StatusOpen)
+			app.bot = slowResponder{delay: tt.delay}
+			app.config.llm.timeout = 300 * time.Millisecond
+			app.config.llm.maxRetries = 0
+
+			srv := httptest.NewUnstartedServer(app.routes())
+			srv.Config.WriteTimeout = 100 * time.Millisecond
+			srv.Start()
+			defer srv.Close()
+
+			body := strings.NewReader(`{"conversationId": "42", "message": "Olá, bot"}`)
+			r, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/bot/reply", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Authorization", bearer(t, app, 7, users.RoleUser))
+
+			resp, err := srv.Client().Do(r)
+			if err != nil {
+				t.Fatalf("the connection was closed before the reply: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/filters"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+// conversationFromRequest loads the conversation named by the :id parameter
+// and checks that the caller may access it. When it returns false a response
+// has already been written.
+func (app *application) conversationFromRequest(w http.ResponseWriter, r *http.Request) (*conversations.Conversation, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	conversation, err := app.models.Conversations.Get(id)
+	if err != nil {
+		if errors.Is(err, conversations.ErrConversationNotFound) {
+			app.notFoundResponse(w, r)
+			return nil, false
+		}
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	viewer := app.viewer(r)
+	if conversation.UserID != viewer.ID && !viewer.Role.Can(users.PermConversationsManage) {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return conversation, true
+}
+
+func (app *application) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	payload := conversations.CreateConversationDTO{}
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &payload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	conversation := &conversations.Conversation{
+		UserID: userID,
+		Title:  payload.Title,
+	}
+
+	err := app.models.Conversations.Insert(conversation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, conversation, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	list, err := app.models.Conversations.ListForUser(userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"conversations": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showConversationHandler(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, conversation, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createMessageHandler(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	payload := conversations.CreateMessageDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if conversation.Status == conversations.StatusClosed {
+		app.badRequestResponse(w, r, errors.New("A conversa está encerrada"))
+		return
+	}
+
+	viewer := app.viewer(r)
+	sender := conversations.SenderUser
+	if conversation.UserID != viewer.ID {
+		sender = conversations.SenderAgent
+
+		allowed, err := app.canSendAsAgent(conversation, viewer)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !allowed {
+			app.errorResponse(w, r, http.StatusForbidden, handoffs.ErrNotAssignedToAgent.Error())
+			return
+		}
+	}
+
+	message := &conversations.Message{
+		ConversationID: conversation.ID,
+		Sender:         sender,
+		UserID:         &viewer.ID,
+		Content:        payload.Content,
+	}
+
+	err = app.models.Messages.Insert(message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.publish(conversation.ID, conversations.EventMessage, message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, message, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", 50, v)
+	v.Check(limit > 0, "limit", "deve ser maior que zero")
+	v.Check(limit <= 100, "limit", "deve ser no máximo 100")
+
+	before, err := filters.DecodeCursor(app.readString(qs, "cursor", ""))
+	if err != nil {
+		v.AddError("cursor", err.Error())
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	messages, next, err := app.models.Messages.List(conversation.ID, before, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"messages": messages,
+		"cursor":   filters.Cursor{Next: filters.EncodeCursor(next)},
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
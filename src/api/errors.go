@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
+// logError logs err with the request it happened in; the request id and
+// user are added from the context by the log handler.
 func (app application) logError(r *http.Request, err error) {
-	app.logger.Println(err)
+	app.logger.ErrorContext(r.Context(), err.Error(), "method", r.Method, "path", r.URL.Path)
 }
 
 func (app application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
@@ -17,10 +20,11 @@ func (app application) errorResponse(w http.ResponseWriter, r *http.Request, sta
 	}
 }
 
+const serverErrorMessage = "O servidor encontrou um problema e não foi capaz de processar a sua requisição"
+
 func (app application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.logError(r, err)
-	msg := "O servidor encontrou um problema e não foi capaz de processar a sua requisição"
-	app.errorResponse(w, r, http.StatusInternalServerError, msg)
+	app.errorResponse(w, r, http.StatusInternalServerError, serverErrorMessage)
 
 }
 func (app application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
@@ -44,3 +48,14 @@ func (app application) failedValidationResponse(w http.ResponseWriter, r *http.R
 func (app application) unauthorizedResponse(w http.ResponseWriter, r *http.Request, message string) {
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
+
+func (app application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", seconds(retryAfter))
+	msg := "Limite de requisições excedido, tente novamente mais tarde"
+	app.errorResponse(w, r, http.StatusTooManyRequests, msg)
+}
+
+func (app application) forbiddenResponse(w http.ResponseWriter, r *http.Request) {
+	msg := "Você não tem permissão para acessar este recurso"
+	app.errorResponse(w, r, http.StatusForbidden, msg)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+)
+
+// publish appends an event to the conversation's event log and delivers it to
+// the conversation's live subscribers, in the order of the log.
+func (app *application) publish(conversationID int64, eventType conversations.EventType, data any) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	event := &conversations.Event{
+		ConversationID: conversationID,
+		Type:           eventType,
+		Data:           js,
+	}
+
+	return app.broker.Append(event, app.models.Events.Insert)
+}
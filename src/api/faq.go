@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/bot"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/faq"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/filters"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+// faqKnowledgeBase lets the bot answer from the FAQ articles, matching any of
+// the words of the user's message.
+type faqKnowledgeBase struct {
+	articles faq.ArticleModel
+	language string
+}
+
+// faqResponder puts the knowledge base in front of responder when the bot is
+// configured to answer from it.
+func (app *application) faqResponder(responder bot.Responder) bot.Responder {
+	if !app.config.bot.faq {
+		return responder
+	}
+
+	return bot.FAQResponder{
+		KnowledgeBase: faqKnowledgeBase{
+			articles: app.models.Articles,
+			language: app.config.bot.faqLanguage,
+		},
+		Threshold: app.config.bot.faqThreshold,
+		Fallback:  responder,
+	}
+}
+
+func (kb faqKnowledgeBase) Search(ctx context.Context, message string, limit int) ([]bot.Article, error) {
+	results, err := kb.articles.Search(faq.SearchFilter{
+		Query:    message,
+		Language: kb.language,
+		Limit:    limit,
+		MatchAny: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	articles := make([]bot.Article, 0, len(results))
+	for _, result := range results {
+		articles = append(articles, bot.Article{
+			ID:       result.ID,
+			Question: result.Question,
+			Answer:   result.Answer,
+			Score:    result.Rank,
+		})
+	}
+	return articles, nil
+}
+
+func (app *application) faqErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, faq.ErrArticleNotFound):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, faq.ErrEditConflict), errors.Is(err, faq.ErrDuplicateQuestion):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	language := app.readString(qs, "language", "")
+	tag := app.readString(qs, "tag", "")
+
+	f := filters.Filters{
+		Page:         app.readInt(qs, "page", 1, v),
+		PageSize:     app.readInt(qs, "page_size", 20, v),
+		Sort:         app.readString(qs, "sort", "id"),
+		SortSafelist: []string{"id", "question", "updated_at", "-id", "-question", "-updated_at"},
+	}
+
+	if language != "" {
+		v.Check(validator.In(language, faq.Languages...), "language", "deve ser uma das opções (pt|en|es)")
+	}
+
+	if f.Validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, metadata, err := app.models.Articles.List(language, tag, f)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"articles": list, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) searchArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filter := faq.SearchFilter{
+		Query:    app.readString(qs, "q", ""),
+		Language: app.readString(qs, "language", ""),
+		Tag:      app.readString(qs, "tag", ""),
+		Limit:    app.readInt(qs, "limit", 10, v),
+	}
+
+	v.Check(filter.Query != "", "q", "é obrigatório")
+	v.Check(len(filter.Query) <= 500, "q", "não deve ter mais de 500 caracteres")
+	v.Check(filter.Limit > 0 && filter.Limit <= 50, "limit", "deve estar entre 1 e 50")
+	if filter.Language != "" {
+		v.Check(validator.In(filter.Language, faq.Languages...), "language", "deve ser uma das opções (pt|en|es)")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Articles.Search(filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showArticleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	article, err := app.models.Articles.Get(id)
+	if err != nil {
+		app.faqErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, article, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createArticleHandler(w http.ResponseWriter, r *http.Request) {
+	payload := faq.ArticleDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Normalize()
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	viewerID := app.viewer(r).ID
+	article := &faq.Article{
+		Question:  payload.Question,
+		Answer:    payload.Answer,
+		Tags:      payload.Tags,
+		Language:  payload.Language,
+		CreatedBy: &viewerID,
+	}
+
+	err = app.models.Articles.Insert(article)
+	if err != nil {
+		app.faqErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, article, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateArticleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	article, err := app.models.Articles.Get(id)
+	if err != nil {
+		app.faqErrorResponse(w, r, err)
+		return
+	}
+
+	payload := faq.ArticleDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Normalize()
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	article.Question = payload.Question
+	article.Answer = payload.Answer
+	article.Tags = payload.Tags
+	article.Language = payload.Language
+
+	err = app.models.Articles.Update(article)
+	if err != nil {
+		app.faqErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, article, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteArticleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Articles.Delete(id)
+	if err != nil {
+		app.faqErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
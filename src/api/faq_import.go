@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/faq"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+type faqImportFile struct {
+	Articles []faq.ArticleDTO `json:"articles"`
+}
+
+// importFormat picks the format of an upload from the format query
+// parameter, falling back to the content type of the request or, for
+// multipart uploads, to the extension of the file.
+func importFormat(r *http.Request, contentType, filename string) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "text/csv", strings.EqualFold(filepath.Ext(filename), ".csv"):
+		return "csv"
+	case mediaType == "application/json", strings.EqualFold(filepath.Ext(filename), ".json"):
+		return "json"
+	}
+	return ""
+}
+
+// readFAQImport returns the rows of the upload along with the errors of rows
+// that could not be parsed at all.
+func (app *application) readFAQImport(w http.ResponseWriter, r *http.Request) ([]faq.ArticleDTO, []faq.RowError, error) {
+	maxBytes := app.config.faq.importMaxBytes
+	body := io.Reader(r.Body)
+	contentType := r.Header.Get("Content-Type")
+	filename := ""
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		err := r.ParseMultipartForm(maxBytes)
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				return nil, nil, fmt.Errorf("O corpo da requisição não deve ser maior que %d bytes", maxBytes)
+			}
+			return nil, nil, fmt.Errorf("Formulário inválido: %v", err)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("O campo file com o arquivo a importar é obrigatório")
+		}
+		defer file.Close()
+
+		body = file
+		contentType = header.Header.Get("Content-Type")
+		filename = header.Filename
+	}
+
+	switch importFormat(r, contentType, filename) {
+	case "csv":
+		rows, rowErrors, err := faq.ReadCSV(http.MaxBytesReader(w, io.NopCloser(body), maxBytes))
+		if err != nil && strings.Contains(err.Error(), "http: request body too large") {
+			return nil, nil, fmt.Errorf("O corpo da requisição não deve ser maior que %d bytes", maxBytes)
+		}
+		return rows, rowErrors, err
+	case "json":
+		file := faqImportFile{}
+		r.Body = io.NopCloser(body)
+		err := app.readJSONLimit(w, r, &file, maxBytes)
+		return file.Articles, nil, err
+	default:
+		return nil, nil, errors.New("Formato não suportado, envie um arquivo CSV ou JSON")
+	}
+}
+
+// importArticlesHandler validates every row of the upload before saving
+// anything: a single invalid row rejects the whole import, reporting the
+// errors of each offending row.
+func (app *application) importArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	rows, rowErrors, err := app.readFAQImport(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if len(rows) == 0 {
+		app.badRequestResponse(w, r, errors.New("O arquivo não contém artigos"))
+		return
+	}
+
+	malformed := map[int]bool{}
+	for _, rowError := range rowErrors {
+		malformed[rowError.Row] = true
+	}
+
+	viewerID := app.viewer(r).ID
+	articles := make([]*faq.Article, 0, len(rows))
+	seen := map[string]int{}
+
+	for i, row := range rows {
+		if malformed[i+1] {
+			continue
+		}
+		row.Normalize()
+
+		v := validator.New()
+		row.Validate(v)
+
+		key := row.Language + "\x00" + row.Question
+		if first, exists := seen[key]; exists {
+			v.AddError("question", fmt.Sprintf("repete a pergunta da linha %d", first))
+		} else {
+			seen[key] = i + 1
+		}
+
+		if !v.Valid() {
+			rowErrors = append(rowErrors, faq.RowError{Row: i + 1, Errors: v.Errors})
+			continue
+		}
+
+		articles = append(articles, &faq.Article{
+			Question:  row.Question,
+			Answer:    row.Answer,
+			Tags:      row.Tags,
+			Language:  row.Language,
+			CreatedBy: &viewerID,
+		})
+	}
+
+	if len(rowErrors) > 0 {
+		sort.Slice(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, map[string]any{"rows": rowErrors})
+		return
+	}
+
+	result, err := app.models.Articles.Import(articles)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, result, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) exportArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	format := app.readString(r.URL.Query(), "format", "json")
+
+	v := validator.New()
+	v.Check(validator.In(format, "csv", "json"), "format", "deve ser uma das opções (csv|json)")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	articles, err := app.models.Articles.All()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("faq-%s.%s", time.Now().Format("20060102"), format)
+	headers := http.Header{}
+	headers.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		for key, value := range headers {
+			w.Header()[key] = value
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+
+		err = faq.WriteCSV(w, articles)
+		if err != nil {
+			app.logError(r, err)
+		}
+		return
+	}
+
+	file := faqImportFile{Articles: make([]faq.ArticleDTO, 0, len(articles))}
+	for _, a := range articles {
+		file.Articles = append(file.Articles, faq.ArticleDTO{
+			Question: a.Question,
+			Answer:   a.Answer,
+			Tags:     a.Tags,
+			Language: a.Language,
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, file, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
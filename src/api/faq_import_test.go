@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestImportReportsMalformedCSVRows(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	app.config.faq.importMaxBytes = 1 << 20
+	allowSessions(f)
+
+	file := "question,answer\n" +
+		"Como trocar a senha?,Acesse o perfil.\n" +
+		"Pergunta sem resposta\n" +
+		",Resposta sem pergunta\n" +
+		"Qual o horário?,Das 8h às 18h.,extra\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/faq/import?format=csv", strings.NewReader(file))
+	req.Header.Set("Authorization", bearer(t, app, 1, users.RoleAdmin))
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusUnprocessableEntity, rr.Body)
+	}
+
+	response := struct {
+		Erro struct {
+			Rows []struct {
+				Row    int               `json:"row"`
+				Errors map[string]string `json:"errors"`
+			} `json:"rows"`
+		} `json:"erro"`
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := response.Erro.Rows
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v, want errors for rows 2, 3 and 4", rows)
+	}
+	for i, want := range []struct {
+		row   int
+		field string
+	}{{2, "columns"}, {3, "question"}, {4, "columns"}} {
+		if rows[i].Row != want.row || rows[i].Errors[want.field] == "" {
+			t.Errorf("error %d = %+v, want row %d with a %q error", i, rows[i], want.row, want.field)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestCreateArticleDuplicateQuestion(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	allowSessions(f)
+	f.on(`INSERT INTO faq_articles`, func([]driver.Value) fakeResult {
+		return fakeResult{Err: errors.New(`pq: duplicate key value violates unique constraint "faq_articles_language_question_key"`)}
+	})
+
+	body := map[string]any{"question": "Como trocar a senha?", "answer": "Acesse o perfil.", "language": "pt"}
+	status, response := do(t, app.routes(), http.MethodPost, "/v1/admin/faq/articles", bearer(t, app, 1, users.RoleAdmin), body)
+	if status != http.StatusConflict {
+		t.Fatalf("status = %d, want %d: %v", status, http.StatusConflict, response)
+	}
+	if response["erro"] != "Já existe um artigo com esta pergunta neste idioma" {
+		t.Errorf("erro = %v", response["erro"])
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/flows"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/versions"
+	"github.com/pedro-git-projects/chatbot-back/internal/flow"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) flowErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var runtimeErr *flow.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, flows.ErrFlowNotFound), errors.Is(err, flows.ErrSessionNotFound), errors.Is(err, versions.ErrVersionNotFound):
+		app.errorResponse(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, flows.ErrEditConflict), errors.Is(err, flows.ErrDuplicateName), errors.Is(err, flow.ErrFlowFinished):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFlowsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.models.Flows.List()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"flows": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showFlowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	f, err := app.models.Flows.Get(id)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, f, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createFlowHandler(w http.ResponseWriter, r *http.Request) {
+	payload := flows.FlowDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	viewerID := app.viewer(r).ID
+	f := &flows.Flow{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Definition:  payload.Definition,
+		CreatedBy:   &viewerID,
+	}
+
+	err = app.models.Flows.Insert(f)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, f, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateFlowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	f, err := app.models.Flows.Get(id)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	payload := flows.FlowDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	f.Name = payload.Name
+	f.Description = payload.Description
+	f.Definition = payload.Definition
+
+	err = app.models.Flows.Update(f)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, f, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteFlowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Flows.Delete(id)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) writeFlowSession(w http.ResponseWriter, r *http.Request, status int, session *flows.Session, out flow.Output) {
+	slots, err := app.models.FlowSessions.Slots(session.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, status, map[string]any{"session": session, "messages": out.Messages, "slots": slots}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// publishedFlow returns the definition of the flow in the published bot
+// version along with the version id, or the editable definition and a nil
+// id while no version is published.
+func (app *application) publishedFlow(id int64) (*flow.Definition, *int64, error) {
+	active, err := app.models.Versions.Active()
+	if errors.Is(err, versions.ErrNoActiveVersion) {
+		f, err := app.models.Flows.Get(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &f.Definition, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	f, ok := active.Snapshot.Flow(id)
+	if !ok {
+		return nil, nil, flows.ErrFlowNotFound
+	}
+	return &f.Definition, &active.ID, nil
+}
+
+// sessionFlow returns the definition the session runs on: the snapshot of
+// the version it was pinned to when it started, so publishing a new version
+// does not change conversations already in progress.
+func (app *application) sessionFlow(session *flows.Session) (*flow.Definition, error) {
+	if session.BotVersionID == nil {
+		f, err := app.models.Flows.Get(session.FlowID)
+		if err != nil {
+			return nil, err
+		}
+		return &f.Definition, nil
+	}
+
+	version, err := app.models.Versions.Get(*session.BotVersionID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := version.Snapshot.Flow(session.FlowID)
+	if !ok {
+		return nil, flows.ErrFlowNotFound
+	}
+	return &f.Definition, nil
+}
+
+// startFlowHandler starts the flow for the caller, cancelling the session
+// they had in progress, if any. The flow is taken from the published bot
+// version, to which the session stays pinned.
+func (app *application) startFlowHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	definition, versionID, err := app.publishedFlow(id)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	state, out, err := definition.Begin()
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	session := &flows.Session{
+		UserID:       app.viewer(r).ID,
+		FlowID:       id,
+		BotVersionID: versionID,
+		Status:       flows.SessionActive,
+	}
+	session.SetState(state)
+
+	err = app.models.FlowSessions.Start(session)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeFlowSession(w, r, http.StatusCreated, session, out)
+}
+
+func (app *application) showFlowSessionHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := app.models.FlowSessions.Active(app.viewer(r).ID)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	slots, err := app.models.FlowSessions.Slots(session.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"session": session, "slots": slots}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) flowInputHandler(w http.ResponseWriter, r *http.Request) {
+	payload := flows.FlowInputDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	session, err := app.models.FlowSessions.Active(app.viewer(r).ID)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	definition, err := app.sessionFlow(session)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	state, out, err := definition.Advance(session.State(), payload.Input)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+	session.SetState(state)
+
+	err = app.models.FlowSessions.Save(session, out.Slots)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeFlowSession(w, r, http.StatusOK, session, out)
+}
+
+func (app *application) cancelFlowSessionHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.models.FlowSessions.Cancel(app.viewer(r).ID)
+	if err != nil {
+		app.flowErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
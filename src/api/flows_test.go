@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/flows"
+	"github.com/pedro-git-projects/chatbot-back/internal/flow"
+)
+
+func TestFlowErrorResponse(t *testing.T) {
+	_, db := newFakeDB(t)
+	app := newTestApp(t, db)
+
+	loop := &flow.Definition{
+		Start: "a",
+		Nodes: []flow.Node{{ID: "a", Type: flow.NodeMessage, Transitions: []flow.Transition{{Target: "a"}}}},
+	}
+	_, _, err := loop.Begin()
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"flow runtime error", err, http.StatusUnprocessableEntity},
+		{"finished flow", flow.ErrFlowFinished, http.StatusConflict},
+		{"missing session", flows.ErrSessionNotFound, http.StatusNotFound},
+		{"database failure", errors.New("conexão perdida"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			app.flowErrorResponse(rr, httptest.NewRequest(http.MethodPost, "/v1/flows/1/start", nil), tt.err)
+			if rr.Code != tt.status {
+				t.Errorf("status = %d, want %d", rr.Code, tt.status)
+			}
+		})
+	}
+}
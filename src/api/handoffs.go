@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+// notifyHandoff tells everyone following the conversation about a change in
+// the handoff, both as a typed event and as a system message visible in the
+// conversation history.
+func (app *application) notifyHandoff(h *handoffs.Handoff, eventType conversations.EventType, agent *users.User, text string) error {
+	data := map[string]any{"handoff": h}
+	if agent != nil {
+		data["agent"] = map[string]string{
+			"id":   strconv.FormatInt(agent.ID, 10),
+			"name": agent.Name,
+		}
+	}
+
+	err := app.publish(h.ConversationID, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	message := &conversations.Message{
+		ConversationID: h.ConversationID,
+		Sender:         conversations.SenderSystem,
+		Content:        text,
+	}
+
+	err = app.models.Messages.Insert(message)
+	if err != nil {
+		return err
+	}
+
+	return app.publish(h.ConversationID, conversations.EventMessage, message)
+}
+
+// canSendAsAgent reports whether a staff member may write in a conversation
+// that is not theirs: admins always can, collaborators only while the
+// conversation's handoff is assigned to them.
+func (app *application) canSendAsAgent(conversation *conversations.Conversation, viewer users.Viewer) (bool, error) {
+	if viewer.Role == users.RoleAdmin {
+		return true, nil
+	}
+
+	h, err := app.models.Handoffs.Active(conversation.ID)
+	if err != nil {
+		if errors.Is(err, handoffs.ErrHandoffNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return h.AssignedToUser(viewer.ID), nil
+}
+
+func (app *application) handoffErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, handoffs.ErrHandoffNotFound):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, handoffs.ErrNotAssignedToAgent):
+		app.errorResponse(w, r, http.StatusForbidden, err.Error())
+	case errors.Is(err, handoffs.ErrActiveHandoff), errors.Is(err, handoffs.ErrInvalidTransition), errors.Is(err, handoffs.ErrCapacityReached):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) requestHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	payload := handoffs.RequestHandoffDTO{}
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &payload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if conversation.Status == conversations.StatusClosed {
+		app.badRequestResponse(w, r, errors.New("A conversa está encerrada"))
+		return
+	}
+
+	h, err := app.models.Handoffs.Request(conversation.ID, app.viewer(r).ID, payload.Reason, payload.Tags)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.notifyHandoff(h, conversations.EventHandoffQueued, nil, "Sua conversa foi encaminhada para um atendente. Aguarde um momento.")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	assigned, err := app.autoAssign(h, 0)
+	if err != nil {
+		app.logError(r, err)
+	}
+	if assigned {
+		if h, err = app.models.Handoffs.Get(h.ID); err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listHandoffsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	status := handoffs.Status(app.readString(qs, "status", ""))
+
+	v := validator.New()
+	v.Check(validator.In(string(status), "", string(handoffs.StatusQueued), string(handoffs.StatusAssigned), string(handoffs.StatusClosed)),
+		"status", "deve ser uma das opções (queued|assigned|closed)")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var assignedTo int64
+	if qs.Get("mine") == "true" {
+		assignedTo = app.viewer(r).ID
+	}
+
+	list, err := app.models.Handoffs.List(status, assignedTo)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"handoffs": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	h, err := app.models.Handoffs.Get(id)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) claimHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	viewer := app.viewer(r)
+	agent, err := app.models.Users.Get(viewer.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	h, err := app.models.Handoffs.Claim(id, agent.ID, viewer.Role == users.RoleAdmin)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.notifyHandoff(h, conversations.EventAgentJoined, agent, agent.Name+" entrou na conversa.")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) releaseHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	viewer := app.viewer(r)
+	agent, err := app.models.Users.Get(viewer.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	h, err := app.models.Handoffs.Release(id, agent.ID, viewer.Role == users.RoleAdmin)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.notifyHandoff(h, conversations.EventAgentLeft, agent, agent.Name+" saiu da conversa. Outro atendente irá assumir em breve.")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if _, err = app.autoAssign(h, agent.ID); err != nil {
+		app.logError(r, err)
+	}
+	if err = app.drainQueue(); err != nil {
+		app.logError(r, err)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) transferHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payload := handoffs.TransferHandoffDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	target, err := app.models.Users.Get(payload.CollaboratorID)
+	if err != nil && !errors.Is(err, users.ErrUserNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if target == nil || target.DisabledAt != nil || !target.Role.Can(users.PermConversationsManage) {
+		v.AddError("collaboratorId", "deve ser um colaborador ativo")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	viewer := app.viewer(r)
+	h, err := app.models.Handoffs.Transfer(id, viewer.ID, target.ID, viewer.Role == users.RoleAdmin)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.notifyHandoff(h, conversations.EventAgentJoined, target, "Sua conversa foi transferida para "+target.Name+".")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) closeHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	viewer := app.viewer(r)
+	h, err := app.models.Handoffs.Close(id, viewer.ID, viewer.Role == users.RoleAdmin)
+	if err != nil {
+		app.handoffErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.notifyHandoff(h, conversations.EventHandoffClosed, nil, "O atendimento humano foi encerrado. O assistente virtual voltou a responder.")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err = app.drainQueue(); err != nil {
+		app.logError(r, err)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, h, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+// Staff of the handoff tests.
+const (
+	handoffAdmin  int64 = 1
+	handoffAgent  int64 = 2
+	handoffOther  int64 = 3
+	handoffClient int64 = 7
+)
+
+var handoffStaff = map[int64]struct {
+	name string
+	role users.UserRole
+}{
+	handoffAdmin:  {"Admin", users.RoleAdmin},
+	handoffAgent:  {"Ana", users.RoleCollaborator},
+	handoffOther:  {"Bruno", users.RoleCollaborator},
+	handoffClient: {"Cliente", users.RoleUser},
+}
+
+// handoffDB keeps a single handoff of conversation 42 in memory and answers
+// the queries of its transitions.
+type handoffDB struct {
+	mu         sync.Mutex
+	status     handoffs.Status
+	assignedTo *int64
+
+	// capacity and open describe the availability of the collaborators; one
+	// without a capacity is offline.
+	capacity map[int64]int
+	open     map[int64]int
+}
+
+func newHandoffDB(f *fakeDB, status handoffs.Status, assignedTo int64) *handoffDB {
+	h := &handoffDB{
+		status:   status,
+		capacity: map[int64]int{handoffAgent: 2, handoffOther: 2},
+		open:     map[int64]int{},
+	}
+	if assignedTo != 0 {
+		h.assignedTo = &assignedTo
+	}
+
+	f.on(`FROM handoffs WHERE id = \$1 FOR UPDATE`, func([]driver.Value) fakeResult {
+		return h.row()
+	})
+	f.on(`UPDATE handoffs SET status = 'assigned'`, func(args []driver.Value) fakeResult {
+		return h.update(handoffs.StatusAssigned, args[1])
+	})
+	f.on(`UPDATE handoffs SET status = 'queued'`, func([]driver.Value) fakeResult {
+		return h.update(handoffs.StatusQueued, nil)
+	})
+	f.on(`UPDATE handoffs SET status = 'closed'`, func([]driver.Value) fakeResult {
+		return h.update(handoffs.StatusClosed, h.assignedTo)
+	})
+	f.on(`FROM collaborator_availability a`, func(args []driver.Value) fakeResult {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		id := args[0].(int64)
+		capacity, online := h.capacity[id]
+		if !online {
+			return noRows(2)
+		}
+		return row(int64(capacity), int64(h.open[id]))
+	})
+	f.on(`UPDATE assignments SET released_at`, func([]driver.Value) fakeResult {
+		return fakeResult{}
+	})
+	f.on(`INSERT INTO (assignments|collaborator_availability|handoff_events)`, func([]driver.Value) fakeResult {
+		return fakeResult{RowsAffected: 1}
+	})
+	f.on(`SELECT id, email, name, role, image_url, created_at, disabled_at, must_reset_password\s+FROM users\s+WHERE id = \$1`, func(args []driver.Value) fakeResult {
+		staff, ok := handoffStaff[args[0].(int64)]
+		if !ok {
+			return noRows(8)
+		}
+		return row(args[0], strings.ToLower(staff.name)+"@example.com", staff.name, string(staff.role), "", time.Now(), nil, false)
+	})
+	return h
+}
+
+func (h *handoffDB) row() fakeResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var assignedTo driver.Value
+	if h.assignedTo != nil {
+		assignedTo = *h.assignedTo
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return row(int64(5), int64(42), handoffClient, "", []byte("{}"), string(h.status), assignedTo, created, nil, nil)
+}
+
+func (h *handoffDB) update(status handoffs.Status, assignedTo any) fakeResult {
+	h.mu.Lock()
+	h.status = status
+	h.assignedTo = nil
+	switch id := assignedTo.(type) {
+	case int64:
+		h.assignedTo = &id
+	case *int64:
+		h.assignedTo = id
+	}
+	h.mu.Unlock()
+
+	return h.row()
+}
+
+func (h *handoffDB) state() (handoffs.Status, int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.assignedTo == nil {
+		return h.status, 0
+	}
+	return h.status, *h.assignedTo
+}
+
+func TestHandoffTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     handoffs.Status
+		assignedTo int64
+		actor      int64
+		action     string
+		body       any
+		code       int
+		wantStatus handoffs.Status
+		wantAgent  int64
+		event      conversations.EventType
+	}{
+		{"claim a queued handoff", handoffs.StatusQueued, 0, handoffAgent, "claim", nil, http.StatusOK, handoffs.StatusAssigned, handoffAgent, conversations.EventAgentJoined},
+		{"claim an assigned handoff", handoffs.StatusAssigned, handoffOther, handoffAgent, "claim", nil, http.StatusConflict, handoffs.StatusAssigned, handoffOther, ""},
+
+		{"release own handoff", handoffs.StatusAssigned, handoffAgent, handoffAgent, "release", nil, http.StatusOK, handoffs.StatusQueued, 0, conversations.EventAgentLeft},
+		{"release a handoff of another agent", handoffs.StatusAssigned, handoffOther, handoffAgent, "release", nil, http.StatusForbidden, handoffs.StatusAssigned, handoffOther, ""},
+		{"admin forces a release", handoffs.StatusAssigned, handoffOther, handoffAdmin, "release", nil, http.StatusOK, handoffs.StatusQueued, 0, conversations.EventAgentLeft},
+		{"release a queued handoff", handoffs.StatusQueued, 0, handoffAdmin, "release", nil, http.StatusConflict, handoffs.StatusQueued, 0, ""},
+
+		{"transfer own handoff", handoffs.StatusAssigned, handoffAgent, handoffAgent, "transfer", map[string]string{"collaboratorId": "3"}, http.StatusOK, handoffs.StatusAssigned, handoffOther, conversations.EventAgentJoined},
+		{"transfer a handoff of another agent", handoffs.StatusAssigned, handoffOther, handoffAgent, "transfer", map[string]string{"collaboratorId": "2"}, http.StatusForbidden, handoffs.StatusAssigned, handoffOther, ""},
+		{"admin forces a transfer", handoffs.StatusQueued, 0, handoffAdmin, "transfer", map[string]string{"collaboratorId": "3"}, http.StatusOK, handoffs.StatusAssigned, handoffOther, conversations.EventAgentJoined},
+		{"transfer to a user", handoffs.StatusAssigned, handoffAgent, handoffAgent, "transfer", map[string]string{"collaboratorId": "7"}, http.StatusUnprocessableEntity, handoffs.StatusAssigned, handoffAgent, ""},
+
+		{"close own handoff", handoffs.StatusAssigned, handoffAgent, handoffAgent, "close", nil, http.StatusOK, handoffs.StatusClosed, handoffAgent, conversations.EventHandoffClosed},
+		{"close a handoff of another agent", handoffs.StatusAssigned, handoffOther, handoffAgent, "close", nil, http.StatusForbidden, handoffs.StatusAssigned, handoffOther, ""},
+		{"admin forces a close", handoffs.StatusQueued, 0, handoffAdmin, "close", nil, http.StatusOK, handoffs.StatusClosed, 0, conversations.EventHandoffClosed},
+		{"close a closed handoff", handoffs.StatusClosed, 0, handoffAdmin, "close", nil, http.StatusConflict, handoffs.StatusClosed, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+			store := newHandoffDB(f, tt.status, tt.assignedTo)
+			conversation := newConversationDB(f, 42, handoffClient, conversations.StatusOpen)
+
+			auth := bearer(t, app, tt.actor, handoffStaff[tt.actor].role)
+			code, response := do(t, app.routes(), http.MethodPost, "/v1/handoffs/5/"+tt.action, auth, tt.body)
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d: %v", code, tt.code, response)
+			}
+			if code == http.StatusForbidden && response["erro"] != handoffs.ErrNotAssignedToAgent.Error() {
+				t.Errorf("erro = %v, want %q", response["erro"], handoffs.ErrNotAssignedToAgent)
+			}
+
+			status, agent := store.state()
+			if status != tt.wantStatus || agent != tt.wantAgent {
+				t.Errorf("handoff is %s and assigned to %d, want %s and %d", status, agent, tt.wantStatus, tt.wantAgent)
+			}
+
+			if tt.event == "" {
+				if len(conversation.events) != 0 {
+					t.Errorf("events = %v, want none", conversation.events)
+				}
+				return
+			}
+			want := []conversations.EventType{tt.event, conversations.EventMessage}
+			if len(conversation.events) != 2 || conversation.events[0] != want[0] || conversation.events[1] != want[1] {
+				t.Errorf("events = %v, want %v", conversation.events, want)
+			}
+			if len(conversation.messages) != 1 || conversation.messages[0] != conversations.SenderSystem {
+				t.Errorf("messages = %v, want a system message", conversation.messages)
+			}
+		})
+	}
+}
+
+func TestHandoffCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     handoffs.Status
+		assignedTo int64
+		actor      int64
+		action     string
+		body       any
+		full       bool
+		offline    bool
+		code       int
+	}{
+		{"claim below capacity", handoffs.StatusQueued, 0, handoffAgent, "claim", nil, false, false, http.StatusOK},
+		{"claim at capacity", handoffs.StatusQueued, 0, handoffAgent, "claim", nil, true, false, http.StatusConflict},
+		{"claim while offline", handoffs.StatusQueued, 0, handoffAgent, "claim", nil, false, true, http.StatusConflict},
+		{"admin claims without availability", handoffs.StatusQueued, 0, handoffAdmin, "claim", nil, false, false, http.StatusOK},
+		{"transfer to a full collaborator", handoffs.StatusAssigned, handoffAgent, handoffAgent, "transfer", map[string]string{"collaboratorId": "3"}, true, false, http.StatusConflict},
+		{"transfer to an offline collaborator", handoffs.StatusAssigned, handoffAgent, handoffAgent, "transfer", map[string]string{"collaboratorId": "3"}, false, true, http.StatusConflict},
+		{"admin forces a transfer to a full collaborator", handoffs.StatusAssigned, handoffAgent, handoffAdmin, "transfer", map[string]string{"collaboratorId": "3"}, true, false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+			store := newHandoffDB(f, tt.status, tt.assignedTo)
+			newConversationDB(f, 42, handoffClient, conversations.StatusOpen)
+
+			// The collaborator who would receive the handoff.
+			target := handoffAgent
+			if tt.action == "transfer" {
+				target = handoffOther
+			}
+			if tt.full {
+				store.open[target] = store.capacity[target]
+			}
+			if tt.offline {
+				delete(store.capacity, target)
+			}
+
+			auth := bearer(t, app, tt.actor, handoffStaff[tt.actor].role)
+			code, response := do(t, app.routes(), http.MethodPost, "/v1/handoffs/5/"+tt.action, auth, tt.body)
+			if code != tt.code {
+				t.Fatalf("status = %d, want %d: %v", code, tt.code, response)
+			}
+			if code == http.StatusConflict && response["erro"] != handoffs.ErrCapacityReached.Error() {
+				t.Errorf("erro = %v, want %q", response["erro"], handoffs.ErrCapacityReached)
+			}
+
+			status, agent := store.state()
+			if code == http.StatusConflict && (status != tt.status || agent != tt.assignedTo) {
+				t.Errorf("handoff is %s and assigned to %d, want it untouched", status, agent)
+			}
+		})
+	}
+}
+
+func TestHandoffAgentJoinedNotification(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	newHandoffDB(f, handoffs.StatusQueued, 0)
+
+	var data string
+	f.on(`INSERT INTO conversation_events`, func(args []driver.Value) fakeResult {
+		if fmt.Sprint(args[1]) == string(conversations.EventAgentJoined) {
+			data = string(args[2].([]byte))
+		}
+		return row(int64(1), time.Now())
+	})
+	conversation := newConversationDB(f, 42, handoffClient, conversations.StatusOpen)
+
+	code, response := do(t, app.routes(), http.MethodPost, "/v1/handoffs/5/claim", bearer(t, app, handoffAgent, users.RoleCollaborator), nil)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d: %v", code, response)
+	}
+
+	if !strings.Contains(data, `"agent":{"id":"2","name":"Ana"}`) || !strings.Contains(data, `"status":"assigned"`) {
+		t.Errorf("agent.joined data = %s", data)
+	}
+	if len(conversation.contents) != 1 || conversation.contents[0] != "Ana entrou na conversa." {
+		t.Errorf("system messages = %q", conversation.contents)
+	}
+}
+
+func TestHandoffRoutesRequireStaff(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	store := newHandoffDB(f, handoffs.StatusQueued, 0)
+	allowSessions(f)
+	h := app.routes()
+
+	for _, action := range []string{"claim", "release", "transfer", "close"} {
+		code, _ := do(t, h, http.MethodPost, "/v1/handoffs/5/"+action, bearer(t, app, handoffClient, users.RoleUser), map[string]string{"collaboratorId": "2"})
+		if code != http.StatusForbidden {
+			t.Errorf("%s by a user: status = %d, want %d", action, code, http.StatusForbidden)
+		}
+	}
+	if status, _ := store.state(); status != handoffs.StatusQueued {
+		t.Errorf("handoff is %s, want it untouched", status)
+	}
+}
@@ -9,9 +9,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
 )
 
 func (app application) writeJSON(w http.ResponseWriter, status int, data any, headers http.Header) error {
@@ -33,9 +39,13 @@ func (app application) writeJSON(w http.ResponseWriter, status int, data any, he
 }
 
 func (app application) readJSON(w http.ResponseWriter, r *http.Request, target any) error {
+	return app.readJSONLimit(w, r, target, 1_048_576)
+}
 
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+// readJSONLimit is readJSON for the few endpoints, such as bulk imports, that
+// accept bodies larger than the default 1 MiB.
+func (app application) readJSONLimit(w http.ResponseWriter, r *http.Request, target any, maxBytes int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	err := decoder.Decode(target)
@@ -83,6 +93,59 @@ func (app application) readJSON(w http.ResponseWriter, r *http.Request, target a
 	return nil
 }
 
+func (app application) viewer(r *http.Request) users.Viewer {
+	userID, _ := r.Context().Value("userID").(int64)
+	role, _ := r.Context().Value("role").(string)
+	return users.Viewer{ID: userID, Role: users.UserRole(role)}
+}
+
+func (app application) readIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("Parâmetro id inválido")
+	}
+
+	return id, nil
+}
+
+func (app application) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+func (app application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		v.AddError(key, "deve ser um número inteiro")
+		return defaultValue
+	}
+	return i
+}
+
+func (app application) readTime(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "deve ser uma data no formato RFC 3339")
+		return nil
+	}
+	return &t
+}
+
 func loadEnv(filename string) (map[string]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/bot"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/intents"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/versions"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) intentErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, intents.ErrIntentNotFound):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, intents.ErrEditConflict), errors.Is(err, intents.ErrDuplicateName):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, nlu.ErrNotTrained):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// intentClassifier recognizes intents with the classifier in use.
+type intentClassifier struct {
+	store *nlu.Store
+}
+
+// intentResponder puts the replies of the recognized intents in front of
+// responder when the bot is configured to answer with them.
+func (app *application) intentResponder(responder bot.Responder) bot.Responder {
+	if !app.config.bot.intents {
+		return responder
+	}
+
+	return bot.IntentResponder{
+		Classifier: intentClassifier{store: app.nlu},
+		Threshold:  app.config.bot.intentScore,
+		Fallback:   responder,
+	}
+}
+
+func (ic intentClassifier) Classify(ctx context.Context, message string) (bot.Intent, bool, error) {
+	c, err := ic.store.Classifier()
+	if errors.Is(err, nlu.ErrNotTrained) {
+		return bot.Intent{}, false, nil
+	} else if err != nil {
+		return bot.Intent{}, false, err
+	}
+
+	if !c.Known(message) {
+		return bot.Intent{}, false, nil
+	}
+
+	best := c.Classify(message)[0]
+	return bot.Intent{Name: best.Intent, Confidence: best.Confidence, Replies: c.Responses[best.Intent]}, true, nil
+}
+
+// loadClassifier puts the classifier of the published bot version in use or,
+// while nothing was published, the most recently trained one.
+func (app *application) loadClassifier() error {
+	active, err := app.models.Versions.Active()
+	if err != nil && !errors.Is(err, versions.ErrNoActiveVersion) {
+		return err
+	}
+	if active != nil {
+		app.activateVersion(active)
+		return nil
+	}
+
+	c, err := app.models.Intents.LatestModel()
+	if err != nil {
+		if errors.Is(err, intents.ErrModelNotFound) {
+			app.logger.Warn("Nenhum modelo de intenções treinado, use /v1/admin/nlu/train")
+			return nil
+		}
+		return err
+	}
+
+	app.nlu.Set(c)
+	return nil
+}
+
+func (app *application) listIntentsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.models.Intents.List()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"intents": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showIntentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	intent, err := app.models.Intents.Get(id)
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, intent, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createIntentHandler(w http.ResponseWriter, r *http.Request) {
+	payload := intents.IntentDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Normalize()
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	intent := &intents.Intent{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Examples:    payload.Examples,
+		Responses:   payload.Responses,
+	}
+
+	err = app.models.Intents.Insert(intent)
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, intent, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateIntentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	intent, err := app.models.Intents.Get(id)
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	payload := intents.IntentDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	payload.Normalize()
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	intent.Name = payload.Name
+	intent.Description = payload.Description
+	intent.Examples = payload.Examples
+	intent.Responses = payload.Responses
+
+	err = app.models.Intents.Update(intent)
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, intent, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteIntentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Intents.Delete(id)
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trainIntentsHandler retrains the classifier from the current examples.
+// The model is stored so that it survives restarts and is put in use right
+// away unless a bot version is published, in which case the published model
+// stays in use until a new version is.
+func (app *application) trainIntentsHandler(w http.ResponseWriter, r *http.Request) {
+	examples, err := app.models.Intents.Examples()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	c, err := nlu.Train(examples)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	c.Responses, err = app.models.Intents.Responses()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Intents.SaveModel(c, app.viewer(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	_, err = app.models.Versions.Active()
+	live := errors.Is(err, versions.ErrNoActiveVersion)
+	if err != nil && !live {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if live {
+		app.nlu.Set(c)
+	}
+
+	response := map[string]any{
+		"intents":    c.Intents,
+		"examples":   c.Examples,
+		"vocabulary": len(c.Vocabulary),
+		"trained_at": c.TrainedAt,
+		"live":       live,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"model": response}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) testIntentHandler(w http.ResponseWriter, r *http.Request) {
+	payload := intents.TestSentenceDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	c, err := app.nlu.Classifier()
+	if err != nil {
+		app.intentErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]any{
+		"tokens":  nlu.Tokenize(payload.Text),
+		"intents": c.Classify(payload.Text),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
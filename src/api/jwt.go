@@ -6,21 +6,44 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/tokens"
 	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
 )
 
+// Claims carries, besides iat, the issue time in microseconds: iat only has
+// whole seconds, which cannot tell a token issued just before a password
+// change or logout-all from one issued just after it.
 type Claims struct {
-	UserID int64          `json:"id"`
-	Role   users.UserRole `json:"role"`
+	UserID         int64          `json:"id"`
+	Role           users.UserRole `json:"role"`
+	IssuedAtMicros int64          `json:"iat_us,omitempty"`
 	jwt.StandardClaims
 }
 
+// issuedBefore reports whether the token predates cutoff. Tokens without
+// iat_us are refused when issued in the same second as the cutoff.
+func (c *Claims) issuedBefore(cutoff time.Time) bool {
+	if c.IssuedAtMicros == 0 {
+		return c.IssuedAt <= cutoff.Unix()
+	}
+	return time.UnixMicro(c.IssuedAtMicros).Before(cutoff)
+}
+
 func (app application) generateJWT(userID int64, role users.UserRole) (string, error) {
+	jti, err := tokens.NewUUID()
+	if err != nil {
+		return "", err
+	}
+
+	now := jwt.TimeFunc()
 	claims := Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:         userID,
+		Role:           role,
+		IssuedAtMicros: now.UnixMicro(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: jwt.TimeFunc().Add(time.Hour * 24).Unix(),
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(app.config.auth.accessTokenTTL).Unix(),
 		},
 	}
 
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/llm"
+)
+
+func newLLMProvider(cfg config) (llm.Provider, error) {
+	pricing := llm.Pricing{
+		PromptPer1K:     cfg.llm.promptCost,
+		CompletionPer1K: cfg.llm.completionCost,
+	}
+
+	switch cfg.llm.provider {
+	case "openai":
+		return llm.NewOpenAIClient(cfg.llm.baseURL, cfg.llm.apiKey, cfg.llm.timeout, cfg.llm.maxRetries, pricing), nil
+	default:
+		return nil, fmt.Errorf("Provedor de LLM desconhecido: %s", cfg.llm.provider)
+	}
+}
@@ -2,34 +2,116 @@ package main
 
 import (
 	"flag"
-	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/pedro-git-projects/chatbot-back/internal/bot"
 	"github.com/pedro-git-projects/chatbot-back/internal/data"
+	"github.com/pedro-git-projects/chatbot-back/internal/events"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
+	"github.com/pedro-git-projects/chatbot-back/internal/realtime"
+	"github.com/pedro-git-projects/chatbot-back/internal/routing"
 )
 
 const version = "1.0.0"
 
 type config struct {
-	port      int
-	env       string
-	jwtSecret string
-	db        struct {
+	port            int
+	env             string
+	jwtSecret       string
+	shutdownTimeout time.Duration
+	logLevel        slog.Level
+	db              struct {
 		dsn          string
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
 	}
+	auth struct {
+		accessTokenTTL  time.Duration
+		refreshTokenTTL time.Duration
+	}
+	bootstrap struct {
+		email    string
+		password string
+	}
+	password struct {
+		algorithm  string
+		bcryptCost int
+	}
+	bot struct {
+		responder    string
+		rules        string
+		faq          bool
+		faqLanguage  string
+		faqThreshold float64
+		intents      bool
+		intentScore  float64
+	}
+	ws struct {
+		allowedOrigins []string
+	}
+	routing struct {
+		strategy string
+	}
+	metrics struct {
+		enabled bool
+		addr    string
+	}
+	limiter struct {
+		enabled          bool
+		trustedHeaders   []string
+		ipRPS            float64
+		ipBurst          int
+		authRPS          float64
+		authBurst        int
+		refreshRPS       float64
+		refreshBurst     int
+		userRPS          float64
+		userBurst        int
+		lockoutThreshold int
+		lockoutBase      time.Duration
+		lockoutMax       time.Duration
+	}
+	faq struct {
+		importMaxBytes int64
+	}
+	sse struct {
+		heartbeat    time.Duration
+		writeTimeout time.Duration
+	}
+	llm struct {
+		provider       string
+		baseURL        string
+		apiKey         string
+		model          string
+		systemPrompt   string
+		maxTokens      int
+		temperature    float64
+		timeout        time.Duration
+		maxRetries     int
+		promptCost     float64
+		completionCost float64
+	}
 }
 
 type application struct {
-	config config
-	logger *log.Logger
-	models data.Models
+	config  config
+	logger  *slog.Logger
+	models  data.Models
+	bot     bot.Responder
+	broker  *events.Broker
+	hub     *realtime.Hub
+	routing routing.Strategy
+	nlu     *nlu.Store
+	limits  limiters
+	metrics *appMetrics
+	wg      *sync.WaitGroup
 }
 
 func main() {
@@ -37,57 +119,184 @@ func main() {
 
 	flag.IntVar(&cfg.port, "port", 4000, "Porta do servidor da API")
 	flag.StringVar(&cfg.env, "env", "desenvolvimento", "Ambiente (desenvolvimento|homologação|produção)")
+	flag.TextVar(&cfg.logLevel, "log-level", slog.LevelInfo, "Nível mínimo dos logs (debug|info|warn|error)")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 30*time.Second, "Tempo máximo para concluir requisições e tarefas em andamento ao encerrar o servidor")
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "Número máximo de conexões abertas no PostgreSQL")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "Número máximo de conexões inativas no PostgreSQL")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "Tempo máximo de conexão inativa no PostgreSQL")
+	flag.DurationVar(&cfg.auth.accessTokenTTL, "access-token-ttl", 15*time.Minute, "Tempo de validade dos tokens de acesso")
+	flag.DurationVar(&cfg.auth.refreshTokenTTL, "refresh-token-ttl", 30*24*time.Hour, "Tempo de validade dos tokens de atualização")
+	flag.StringVar(&cfg.password.algorithm, "password-hasher", "argon2id", "Algoritmo de hash de senhas (argon2id|bcrypt)")
+	flag.IntVar(&cfg.password.bcryptCost, "bcrypt-cost", 12, "Custo do bcrypt quando usado como algoritmo de hash de senhas")
+
+	flag.StringVar(&cfg.bot.responder, "bot-responder", "rules", "Responder do bot (rules|echo|llm)")
+	flag.StringVar(&cfg.bot.rules, "bot-rules", "", "Arquivo JSON com as regras do responder rules (opcional)")
+	flag.BoolVar(&cfg.bot.faq, "bot-faq", true, "Responder com artigos da base de conhecimento antes de consultar o responder do bot")
+	flag.StringVar(&cfg.bot.faqLanguage, "bot-faq-language", "pt", "Idioma dos artigos usados nas respostas do bot (pt|en|es)")
+	flag.Float64Var(&cfg.bot.faqThreshold, "bot-faq-threshold", 0.2, "Relevância mínima (0 a 1) para o bot responder com um artigo da base de conhecimento")
+	flag.BoolVar(&cfg.bot.intents, "bot-intents", true, "Responder com as respostas da intenção reconhecida antes de consultar a base de conhecimento")
+	flag.Float64Var(&cfg.bot.intentScore, "bot-intent-threshold", 0.7, "Confiança mínima (0 a 1) para o bot responder com as respostas de uma intenção")
+	flag.Int64Var(&cfg.faq.importMaxBytes, "faq-import-max-bytes", 10<<20, "Tamanho máximo em bytes dos arquivos de importação da base de conhecimento")
+	flag.StringVar(&cfg.routing.strategy, "routing-strategy", "least-busy", "Estratégia de atribuição automática de atendimentos (round-robin|least-busy|skills|none)")
+	flag.StringVar(&cfg.llm.provider, "llm-provider", "openai", "Provedor de LLM (openai)")
+	flag.StringVar(&cfg.llm.baseURL, "llm-base-url", "https://api.openai.com/v1", "URL base da API compatível com OpenAI")
+	flag.StringVar(&cfg.llm.model, "llm-model", "gpt-4o-mini", "Modelo usado nas respostas do bot")
+	flag.StringVar(&cfg.llm.systemPrompt, "llm-system-prompt", "Você é um assistente prestativo. Responda sempre em português.", "Prompt de sistema enviado ao LLM")
+	flag.IntVar(&cfg.llm.maxTokens, "llm-max-tokens", 512, "Número máximo de tokens por resposta do LLM")
+	flag.Float64Var(&cfg.llm.temperature, "llm-temperature", 0.3, "Temperatura de amostragem do LLM")
+	flag.DurationVar(&cfg.llm.timeout, "llm-timeout", 30*time.Second, "Tempo limite de cada requisição ao LLM")
+	flag.IntVar(&cfg.llm.maxRetries, "llm-max-retries", 2, "Número máximo de novas tentativas em falhas transitórias do LLM")
+	flag.Float64Var(&cfg.llm.promptCost, "llm-prompt-cost", 0, "Custo em dólares por 1000 tokens de prompt")
+	flag.Float64Var(&cfg.llm.completionCost, "llm-completion-cost", 0, "Custo em dólares por 1000 tokens de resposta")
+	flag.DurationVar(&cfg.sse.heartbeat, "sse-heartbeat", 15*time.Second, "Intervalo entre comentários de heartbeat nos streams SSE")
+	flag.DurationVar(&cfg.sse.writeTimeout, "sse-write-timeout", 10*time.Second, "Tempo limite de cada escrita nos streams SSE")
+	flag.BoolVar(&cfg.metrics.enabled, "metrics", true, "Expor métricas no formato do Prometheus em /metrics")
+	flag.StringVar(&cfg.metrics.addr, "metrics-addr", "localhost:9090", "Endereço da porta administrativa de /metrics; vazio serve /metrics na porta da API apenas para administradores")
+	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Habilitar limite de requisições")
+	flag.Float64Var(&cfg.limiter.ipRPS, "limiter-ip-rps", 10, "Requisições por segundo permitidas por IP em todas as rotas")
+	flag.IntVar(&cfg.limiter.ipBurst, "limiter-ip-burst", 40, "Rajada máxima de requisições por IP em todas as rotas")
+	flag.Float64Var(&cfg.limiter.authRPS, "limiter-auth-rps", 0.1, "Requisições por segundo permitidas por IP nas rotas de cadastro e login")
+	flag.IntVar(&cfg.limiter.authBurst, "limiter-auth-burst", 10, "Rajada máxima de requisições por IP nas rotas de cadastro e login")
+	flag.Float64Var(&cfg.limiter.refreshRPS, "limiter-refresh-rps", 1, "Requisições por segundo permitidas por IP na renovação de tokens")
+	flag.IntVar(&cfg.limiter.refreshBurst, "limiter-refresh-burst", 30, "Rajada máxima de requisições por IP na renovação de tokens")
+	flag.Float64Var(&cfg.limiter.userRPS, "limiter-user-rps", 5, "Requisições por segundo permitidas por usuário autenticado")
+	flag.IntVar(&cfg.limiter.userBurst, "limiter-user-burst", 20, "Rajada máxima de requisições por usuário autenticado")
+	flag.IntVar(&cfg.limiter.lockoutThreshold, "signin-lockout-threshold", 5, "Tentativas de login malsucedidas seguidas antes de bloquear o e-mail")
+	flag.DurationVar(&cfg.limiter.lockoutBase, "signin-lockout-base", time.Minute, "Duração do primeiro bloqueio de login, dobrada a cada nova falha")
+	flag.DurationVar(&cfg.limiter.lockoutMax, "signin-lockout-max", time.Hour, "Duração máxima do bloqueio de login")
+	flag.Func("limiter-trusted-headers", "Cabeçalhos com o IP do cliente definidos pelo proxy reverso, separados por espaço, ex.: X-Forwarded-For (padrão: nenhum)", func(val string) error {
+		cfg.limiter.trustedHeaders = strings.Fields(val)
+		return nil
+	})
+	flag.Func("ws-allowed-origins", "Origens permitidas no WebSocket, separadas por espaço (padrão: mesma origem)", func(val string) error {
+		cfg.ws.allowedOrigins = strings.Fields(val)
+		return nil
+	})
 
 	flag.Parse()
 
+	logger := slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.logLevel})})
+	slog.SetDefault(logger)
+
 	env, err := loadEnv(".env")
 	if err != nil {
-		log.Fatal("falha ao ler arquivo .env:", err)
-		return
+		logger.Error("falha ao ler arquivo .env", "error", err)
+		os.Exit(1)
 	}
 
 	key := "DATABASE_URL"
 	if value, exists := getEnvValue(env, key); exists {
 		cfg.db.dsn = value
 	} else {
-		fmt.Printf("Chave %s não foi encontrada no arquivo .env\n", key)
+		logger.Warn("Chave não foi encontrada no arquivo .env", "key", key)
 	}
 
 	key = "JWT_SECRET"
 	if value, exists := getEnvValue(env, key); exists {
 		cfg.jwtSecret = value
 	} else {
-		fmt.Printf("Chave %s não foi encontrada no arquivo .env\n", key)
+		logger.Warn("Chave não foi encontrada no arquivo .env", "key", key)
+	}
+
+	if value, exists := getEnvValue(env, "LLM_API_KEY"); exists {
+		cfg.llm.apiKey = value
+	}
+
+	if value, exists := getEnvValue(env, "BOOTSTRAP_ADMIN_EMAIL"); exists {
+		cfg.bootstrap.email = value
+	}
+	if value, exists := getEnvValue(env, "BOOTSTRAP_ADMIN_PASSWORD"); exists {
+		cfg.bootstrap.password = value
 	}
 
-	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	hasher, err := password.New(cfg.password.algorithm, cfg.password.bcryptCost)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	provider, err := newLLMProvider(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	responder, err := bot.New(bot.Options{
+		Responder: cfg.bot.responder,
+		RulesFile: cfg.bot.rules,
+		LLM: bot.LLMResponder{
+			Provider:     provider,
+			Model:        cfg.llm.model,
+			SystemPrompt: cfg.llm.systemPrompt,
+			MaxTokens:    cfg.llm.maxTokens,
+			Temperature:  cfg.llm.temperature,
+		},
+	})
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	var strategy routing.Strategy
+	if cfg.routing.strategy != "none" {
+		strategy, err = routing.New(cfg.routing.strategy)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
 
 	db, err := openDB(cfg)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
-	defer db.Close()
-	logger.Printf("Conexão com o banco de dados estabelecida\n")
+	logger.Info("Conexão com o banco de dados estabelecida")
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db, hasher),
+		bot:     responder,
+		broker:  events.NewBroker(64),
+		routing: strategy,
+		nlu:     &nlu.Store{},
+		wg:      &sync.WaitGroup{},
+		limits:  newLimiters(cfg),
+		metrics: newMetrics(db),
+	}
+
+	app.bot = app.intentResponder(app.faqResponder(app.bot))
+	app.hub = realtime.NewHub(app.broker, app)
+
+	err = app.bootstrapAdmin()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	err = app.loadClassifier()
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	err = app.serve()
+	if err != nil {
+		logger.Error(err.Error())
 	}
 
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.port),
-		Handler:      app.routes(),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+	// Everything that could still log or touch the database has stopped by
+	// now: flush what was logged, then release the connection pool.
+	os.Stdout.Sync()
+
+	if closeErr := db.Close(); closeErr != nil {
+		logger.Error(closeErr.Error())
+		err = closeErr
 	}
 
-	logger.Printf("Inicializando servidor em modo de %s na porta %s", cfg.env, srv.Addr)
-	err = srv.ListenAndServe()
-	log.Fatal(err)
+	if err != nil {
+		os.Exit(1)
+	}
 }
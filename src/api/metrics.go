@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/metrics"
+)
+
+type appMetrics struct {
+	registry     *metrics.Registry
+	requests     *metrics.CounterVec
+	duration     *metrics.HistogramVec
+	signups      *metrics.CounterVec
+	signins      *metrics.CounterVec
+	failedLogins *metrics.CounterVec
+}
+
+func newMetrics(db *sql.DB) *appMetrics {
+	r := metrics.NewRegistry()
+
+	m := &appMetrics{
+		registry:     r,
+		requests:     r.NewCounter("http_requests_total", "Requisições HTTP atendidas.", "method", "route", "status"),
+		duration:     r.NewHistogram("http_request_duration_seconds", "Duração das requisições HTTP em segundos.", metrics.DefaultBuckets, "method", "route", "status"),
+		signups:      r.NewCounter("chatbot_signups_total", "Cadastros de usuários concluídos."),
+		signins:      r.NewCounter("chatbot_signins_total", "Logins bem-sucedidos."),
+		failedLogins: r.NewCounter("chatbot_failed_logins_total", "Logins recusados, por motivo.", "reason"),
+	}
+
+	if db != nil {
+		r.Collect(dbStats(db))
+	}
+	r.Collect(runtimeStats)
+
+	return m
+}
+
+func dbStats(db *sql.DB) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		s := db.Stats()
+		return []metrics.Sample{
+			{Name: "go_sql_max_open_connections", Help: "Número máximo de conexões abertas com o banco de dados.", Type: metrics.Gauge, Value: float64(s.MaxOpenConnections)},
+			{Name: "go_sql_open_connections", Help: "Conexões abertas com o banco de dados, em uso e inativas.", Type: metrics.Gauge, Value: float64(s.OpenConnections)},
+			{Name: "go_sql_in_use_connections", Help: "Conexões com o banco de dados em uso.", Type: metrics.Gauge, Value: float64(s.InUse)},
+			{Name: "go_sql_idle_connections", Help: "Conexões inativas com o banco de dados.", Type: metrics.Gauge, Value: float64(s.Idle)},
+			{Name: "go_sql_wait_count_total", Help: "Vezes em que foi preciso esperar por uma conexão.", Type: metrics.Counter, Value: float64(s.WaitCount)},
+			{Name: "go_sql_wait_duration_seconds_total", Help: "Tempo total esperando por conexões.", Type: metrics.Counter, Value: s.WaitDuration.Seconds()},
+			{Name: "go_sql_max_idle_closed_total", Help: "Conexões fechadas por excederem o limite de conexões inativas.", Type: metrics.Counter, Value: float64(s.MaxIdleClosed)},
+			{Name: "go_sql_max_idle_time_closed_total", Help: "Conexões fechadas por excederem o tempo máximo de inatividade.", Type: metrics.Counter, Value: float64(s.MaxIdleTimeClosed)},
+			{Name: "go_sql_max_lifetime_closed_total", Help: "Conexões fechadas por excederem o tempo máximo de vida.", Type: metrics.Counter, Value: float64(s.MaxLifetimeClosed)},
+		}
+	}
+}
+
+var startTime = time.Now()
+
+func runtimeStats() []metrics.Sample {
+	ms := runtime.MemStats{}
+	runtime.ReadMemStats(&ms)
+
+	return []metrics.Sample{
+		{Name: "go_goroutines", Help: "Goroutines em execução.", Type: metrics.Gauge, Value: float64(runtime.NumGoroutine())},
+		{Name: "go_memstats_alloc_bytes", Help: "Bytes alocados e ainda em uso no heap.", Type: metrics.Gauge, Value: float64(ms.HeapAlloc)},
+		{Name: "go_memstats_heap_objects", Help: "Objetos alocados no heap.", Type: metrics.Gauge, Value: float64(ms.HeapObjects)},
+		{Name: "go_memstats_sys_bytes", Help: "Bytes obtidos do sistema operacional.", Type: metrics.Gauge, Value: float64(ms.Sys)},
+		{Name: "go_memstats_alloc_bytes_total", Help: "Bytes alocados no heap desde o início.", Type: metrics.Counter, Value: float64(ms.TotalAlloc)},
+		{Name: "go_gc_cycles_total", Help: "Ciclos de coleta de lixo concluídos.", Type: metrics.Counter, Value: float64(ms.NumGC)},
+		{Name: "go_gc_pause_seconds_total", Help: "Tempo total de pausa para coleta de lixo.", Type: metrics.Counter, Value: float64(ms.PauseTotalNs) / 1e9},
+		{Name: "process_start_time_seconds", Help: "Início do processo em segundos desde a época Unix.", Type: metrics.Gauge, Value: float64(startTime.Unix())},
+	}
+}
+
+// routePattern rebuilds the route pattern matched by the request, such as
+// /v1/conversations/:id, so that ids do not become label values. Requests
+// that match no route are grouped together for the same reason.
+func routePattern(router *httprouter.Router, r *http.Request) string {
+	handle, ps, _ := router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return "unmatched"
+	}
+	if len(ps) == 0 {
+		return r.URL.Path
+	}
+
+	segments := strings.Split(r.URL.Path, "/")
+	next := 0
+	for i, segment := range segments {
+		if next < len(ps) && segment == ps[next].Value && isParam(router, r.Method, segments, i, ps[next].Key) {
+			segments[i] = ":" + ps[next].Key
+			next++
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isParam reports whether segment i of the path holds the parameter key and
+// not a literal part of the route that happens to have the same value, as in
+// /v1/admin/users/users, by checking that the route takes another value
+// there.
+func isParam(router *httprouter.Router, method string, segments []string, i int, key string) bool {
+	probe := append([]string{}, segments...)
+	probe[i] = "\x00"
+
+	_, ps, _ := router.Lookup(method, strings.Join(probe, "/"))
+	return ps.ByName(key) == probe[i]
+}
+
+// streaming reports whether the response was a WebSocket upgrade or an SSE
+// stream, which last as long as the client stays connected.
+func streaming(rec *responseRecorder) bool {
+	return rec.status == http.StatusSwitchingProtocols ||
+		strings.HasPrefix(rec.Header().Get("Content-Type"), "text/event-stream")
+}
+
+// instrument counts and times every request by route pattern and status.
+// Streams are counted but not timed, since their duration says nothing about
+// latency.
+func (app *application) instrument(router *httprouter.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		route := routePattern(router, r)
+		status := strconv.Itoa(rec.status)
+		app.metrics.requests.Inc(r.Method, route, status)
+		if !streaming(rec) {
+			app.metrics.duration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestRoutePattern(t *testing.T) {
+	router := httprouter.New()
+	for _, path := range []string{
+		"/v1/healthcheck",
+		"/v1/conversations/:id",
+		"/v1/conversations/:id/messages",
+		"/v1/admin/users",
+		"/v1/admin/users/:id",
+		"/v1/admin/users/:id/role",
+	} {
+		router.GET(path, func(http.ResponseWriter, *http.Request, httprouter.Params) {})
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/v1/healthcheck", "/v1/healthcheck"},
+		{http.MethodGet, "/v1/conversations/42", "/v1/conversations/:id"},
+		{http.MethodGet, "/v1/conversations/42/messages", "/v1/conversations/:id/messages"},
+		{http.MethodGet, "/v1/admin/users", "/v1/admin/users"},
+		{http.MethodGet, "/v1/admin/users/users", "/v1/admin/users/:id"},
+		{http.MethodGet, "/v1/admin/users/role/role", "/v1/admin/users/:id/role"},
+		{http.MethodGet, "/v1/conversations/v1", "/v1/conversations/:id"},
+		{http.MethodGet, "/v1/conversations/42/unknown", "unmatched"},
+		{http.MethodGet, "/v1/conversations/42/", "unmatched"},
+		{http.MethodPost, "/v1/conversations/42", "unmatched"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := routePattern(router, r); got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	t.Run("on the API port", func(t *testing.T) {
+		f, db := newFakeDB(t)
+		app := newTestApp(t, db)
+		app.config.metrics.enabled = true
+		allowSessions(f)
+		h := app.routes()
+
+		tests := []struct {
+			name   string
+			auth   string
+			status int
+		}{
+			{"anonymous", "", http.StatusUnauthorized},
+			{"user", bearer(t, app, 7, users.RoleUser), http.StatusForbidden},
+			{"admin", bearer(t, app, 1, users.RoleAdmin), http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+				if tt.auth != "" {
+					r.Header.Set("Authorization", tt.auth)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+
+				if w.Code != tt.status {
+					t.Fatalf("status = %d, want %d", w.Code, tt.status)
+				}
+				if tt.status == http.StatusOK && !strings.Contains(w.Body.String(), "# TYPE http_requests_total counter") {
+					t.Errorf("body = %q", w.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("on a separate port", func(t *testing.T) {
+		_, db := newFakeDB(t)
+		app := newTestApp(t, db)
+		app.config.metrics.enabled = true
+		app.config.metrics.addr = "localhost:9090"
+
+		w := httptest.NewRecorder()
+		app.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+// hijackableRecorder lets handlers take over the connection, as WebSocket
+// upgrades do, without a server.
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (rec hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, peer := net.Pipe()
+	go io.Copy(io.Discard, peer)
+	return conn, bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)), nil
+}
+
+func TestInstrumentDoesNotTimeStreams(t *testing.T) {
+	_, db := newFakeDB(t)
+	app := newTestApp(t, db)
+
+	router := httprouter.New()
+	router.GET("/plain", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Write([]byte("ok"))
+	})
+	router.GET("/sse", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: ok\n\n"))
+	})
+	router.GET("/ws", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+	})
+
+	h := app.instrument(router, router)
+	for _, path := range []string{"/plain", "/sse", "/ws"} {
+		h.ServeHTTP(hijackableRecorder{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	b := strings.Builder{}
+	if err := app.metrics.registry.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/plain",status="200"} 1`,
+		`http_requests_total{method="GET",route="/sse",status="200"} 1`,
+		`http_requests_total{method="GET",route="/ws",status="101"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/plain",status="200"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	for _, route := range []string{"/sse", "/ws"} {
+		if strings.Contains(out, `http_request_duration_seconds_count{method="GET",route="`+route+`"`) {
+			t.Errorf("%s was timed", route)
+		}
+	}
+}
@@ -2,14 +2,71 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/tokens"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
 )
 
+// recoverPanic turns a panic in a handler into the standard 500 response
+// instead of dropping the connection, which is what net/http does. The
+// connection is closed afterwards since the handler may have left it in an
+// unknown state.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				app.panicResponse(w, r, err)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// panicResponse is also httprouter's PanicHandler. http.ErrAbortHandler is
+// re-raised since it is the documented way to abort a response on purpose.
+// When the response has already started, as with SSE streams and hijacked
+// WebSocket connections, no error can be written anymore and the response
+// is aborted instead.
+func (app *application) panicResponse(w http.ResponseWriter, r *http.Request, err any) {
+	if err == http.ErrAbortHandler {
+		panic(err)
+	}
+
+	app.logger.ErrorContext(r.Context(), fmt.Sprintf("panic: %v", err),
+		"method", r.Method, "path", r.URL.Path, "stack", string(debug.Stack()))
+
+	if responseStarted(w) {
+		panic(http.ErrAbortHandler)
+	}
+
+	w.Header().Set("Connection", "close")
+	app.errorResponse(w, r, http.StatusInternalServerError, serverErrorMessage)
+}
+
+// responseStarted reports whether the status line has already been sent, as
+// seen by the responseRecorder wrapping w.
+func responseStarted(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case *responseRecorder:
+			return rw.status != 0
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
 func (app application) jwtMiddleware(next http.Handler) httprouter.Handle {
 	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		authHeader := r.Header.Get("Authorization")
@@ -24,48 +81,172 @@ func (app application) jwtMiddleware(next http.Handler) httprouter.Handle {
 			return
 		}
 
-		tokenString := tokenParts[1]
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Método de assinatura ineseprada: %v", token.Header["alg"])
-			}
-
-			return []byte(app.config.jwtSecret), nil
-		})
+		app.authenticate(w, r, ps, tokenParts[1], next)
+	})
+}
 
-		if err != nil {
-			app.unauthorizedResponse(w, r, "Token inválido: "+err.Error())
+// queryTokenMiddleware is jwtMiddleware for clients that cannot set the
+// Authorization header, such as browser WebSocket and EventSource APIs: the
+// token may also be passed in the access_token query parameter.
+func (app application) queryTokenMiddleware(next http.Handler) httprouter.Handle {
+	return httprouter.Handle(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		tokenString := r.URL.Query().Get("access_token")
+		if tokenString == "" {
+			app.jwtMiddleware(next)(w, r, ps)
 			return
 		}
 
-		if !token.Valid {
-			app.unauthorizedResponse(w, r, "Token inválido")
-			return
-		}
+		app.authenticate(w, r, ps, tokenString, next)
+	})
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			app.unauthorizedResponse(w, r, "Alegações de token inválidas")
-			return
+func (app application) authenticate(w http.ResponseWriter, r *http.Request, ps httprouter.Params, tokenString string, next http.Handler) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Método de assinatura ineseprada: %v", token.Header["alg"])
 		}
 
-		userID, ok := claims["id"].(float64)
-		if !ok {
-			app.unauthorizedResponse(w, r, "ID de usuário inválido nas alegações do token")
-			return
-		}
+		return []byte(app.config.jwtSecret), nil
+	})
 
-		role, ok := claims["role"].(string)
-		if !ok {
-			app.unauthorizedResponse(w, r, "Papel de usuário inválido nas alegações do token")
-			return
+	if err != nil {
+		app.unauthorizedResponse(w, r, "Token inválido: "+err.Error())
+		return
+	}
+
+	if !token.Valid {
+		app.unauthorizedResponse(w, r, "Token inválido")
+		return
+	}
+
+	if claims.UserID == 0 {
+		app.unauthorizedResponse(w, r, "ID de usuário inválido nas alegações do token")
+		return
+	}
+
+	if claims.Role == "" {
+		app.unauthorizedResponse(w, r, "Papel de usuário inválido nas alegações do token")
+		return
+	}
+
+	if claims.Id == "" {
+		app.unauthorizedResponse(w, r, "Identificador do token ausente nas alegações do token")
+		return
+	}
+
+	session, reason, err := app.sessionEnded(claims)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if reason != "" {
+		app.unauthorizedResponse(w, r, reason)
+		return
+	}
+	if session.MustResetPassword && !allowedDuringPasswordReset(r) {
+		app.errorResponse(w, r, http.StatusForbidden, "É necessário redefinir a senha antes de continuar")
+		return
+	}
+
+	userID := claims.UserID
+	role := string(claims.Role)
+
+	if !app.allow(w, r, app.limits.user, strconv.FormatInt(userID, 10)) {
+		return
+	}
+
+	if info := requestInfoFrom(r.Context()); info != nil {
+		info.UserID = userID
+	}
+
+	ctx := context.WithValue(r.Context(), "userID", userID)
+	ctx = context.WithValue(ctx, "role", role)
+	ctx = context.WithValue(ctx, "claims", claims)
+	ctx = context.WithValue(ctx, httprouter.ParamsKey, ps)
+	r = r.WithContext(ctx)
+
+	next.ServeHTTP(w, r)
+}
+
+// sessionEnded returns why the session of the token has ended, or an empty
+// reason while the token may still be used.
+func (app application) sessionEnded(claims *Claims) (tokens.SessionState, string, error) {
+	revoked, err := app.models.Revocations.IsRevoked(claims.Id)
+	if err != nil {
+		return tokens.SessionState{}, "", err
+	}
+	if revoked {
+		return tokens.SessionState{}, "Token revogado", nil
+	}
+
+	session, err := app.models.Revocations.SessionState(claims.UserID)
+	if err != nil {
+		if errors.Is(err, tokens.ErrSessionUserNotFound) {
+			return tokens.SessionState{}, "Usuário do token não existe mais", nil
 		}
+		return tokens.SessionState{}, "", err
+	}
+	if claims.issuedBefore(session.Cutoff) {
+		return session, "Sessão encerrada, faça login novamente", nil
+	}
+	if session.Disabled {
+		return session, "Conta de usuário desativada", nil
+	}
+	return session, "", nil
+}
 
-		ctx := context.WithValue(r.Context(), "userID", int64(userID))
-		ctx = context.WithValue(ctx, "role", role)
-		r = r.WithContext(ctx)
+// allowedDuringPasswordReset lists the only requests accepted from a user
+// whose password reset was forced by an admin: changing the password itself
+// and logging out.
+func allowedDuringPasswordReset(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/v1/user":
+		return r.Method == http.MethodGet || r.Method == http.MethodPut || r.Method == http.MethodPatch
+	case "/v1/auth/logout", "/v1/auth/logout-all":
+		return true
+	}
+	return false
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (app application) requireRole(roles ...users.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value("role").(string)
+			if !ok {
+				app.unauthorizedResponse(w, r, "Papel do usuário não foi encontrado no contexto da requisição")
+				return
+			}
+
+			for _, allowed := range roles {
+				if users.UserRole(role) == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			app.forbiddenResponse(w, r)
+		})
+	}
+}
+
+func (app application) requirePermission(perms ...users.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value("role").(string)
+			if !ok {
+				app.unauthorizedResponse(w, r, "Papel do usuário não foi encontrado no contexto da requisição")
+				return
+			}
+
+			for _, perm := range perms {
+				if !users.UserRole(role).Can(perm) {
+					app.forbiddenResponse(w, r)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
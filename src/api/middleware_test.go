@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestSessionCutoff(t *testing.T) {
+	issued := time.Date(2024, 3, 1, 12, 0, 0, 600_000_000, time.UTC)
+
+	tests := []struct {
+		name   string
+		cutoff time.Time
+		status int
+	}{
+		{"cutoff in an earlier second", issued.Add(-time.Second), http.StatusOK},
+		{"cutoff earlier in the same second", issued.Add(-500 * time.Millisecond), http.StatusOK},
+		{"cutoff later in the same second", issued.Add(500 * time.Millisecond), http.StatusUnauthorized},
+		{"cutoff after the token", issued.Add(2 * time.Second), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+
+			f.on(`FROM revoked_tokens`, func([]driver.Value) fakeResult {
+				return fakeResult{Columns: []string{"jti", "expires_at"}}
+			})
+			f.on(`disabled_at IS NOT NULL`, func([]driver.Value) fakeResult {
+				return row(tt.cutoff, false, false)
+			})
+			f.on(`SELECT id, email, name, role`, func([]driver.Value) fakeResult {
+				return row(int64(7), "fulano@example.com", "Fulano", "user", "", issued, nil, false)
+			})
+
+			jwt.TimeFunc = func() time.Time { return issued }
+			defer func() { jwt.TimeFunc = time.Now }()
+
+			status, response := do(t, app.routes(), http.MethodGet, "/v1/user", bearer(t, app, 7, users.RoleUser), nil)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d: %v", status, tt.status, response)
+			}
+		})
+	}
+}
+
+func TestLegacyTokenCutoff(t *testing.T) {
+	cutoff := time.Date(2024, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		issuedAt int64
+		want     bool
+	}{
+		{cutoff.Unix() - 1, true},
+		{cutoff.Unix(), true},
+		{cutoff.Unix() + 1, false},
+	}
+
+	for _, tt := range tests {
+		claims := Claims{StandardClaims: jwt.StandardClaims{IssuedAt: tt.issuedAt}}
+		if got := claims.issuedBefore(cutoff); got != tt.want {
+			t.Errorf("token without iat_us issued at %d: issuedBefore = %v, want %v", tt.issuedAt, got, tt.want)
+		}
+	}
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	userDB(t, app, f, 7, users.RoleUser)
+	f.on(`FROM flows`, func([]driver.Value) fakeResult {
+		return fakeResult{Columns: make([]string, 8)}
+	})
+	h := app.routes()
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		allowed []users.UserRole
+	}{
+		{"users:read", http.MethodGet, "/v1/admin/users", []users.UserRole{users.RoleCollaborator, users.RoleAdmin}},
+		{"users:read", http.MethodGet, "/v1/admin/users/7", []users.UserRole{users.RoleCollaborator, users.RoleAdmin}},
+		{"users:manage", http.MethodPost, "/v1/admin/users/7/disable", []users.UserRole{users.RoleAdmin}},
+		{"users:manage", http.MethodPut, "/v1/admin/users/7/role", []users.UserRole{users.RoleAdmin}},
+		{"admin role", http.MethodGet, "/v1/admin/flows", []users.UserRole{users.RoleAdmin}},
+	}
+
+	for _, tt := range tests {
+		for _, role := range []users.UserRole{users.RoleUser, users.RoleCollaborator, users.RoleAdmin} {
+			t.Run(tt.name+" "+tt.path+" "+string(role), func(t *testing.T) {
+				allowed := false
+				for _, r := range tt.allowed {
+					allowed = allowed || r == role
+				}
+
+				status, _ := do(t, h, tt.method, tt.path, bearer(t, app, 7, role), nil)
+				if allowed && status == http.StatusForbidden {
+					t.Errorf("status = %d, want the request let through", status)
+				}
+				if !allowed && status != http.StatusForbidden {
+					t.Errorf("status = %d, want %d", status, http.StatusForbidden)
+				}
+			})
+		}
+	}
+}
+
+func TestPanicResponse(t *testing.T) {
+	_, db := newFakeDB(t)
+	app := newTestApp(t, db)
+
+	logs := &bytes.Buffer{}
+	app.logger = slog.New(slog.NewJSONHandler(logs, nil))
+
+	router := httprouter.New()
+	router.GET("/plain", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		panic("falha")
+	})
+	router.GET("/sse", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: ok\n\n"))
+		http.NewResponseController(w).Flush()
+		panic("falha")
+	})
+	h := app.instrument(router, app.recoverPanic(router))
+
+	serve := func(path string) (rr *httptest.ResponseRecorder, recovered any) {
+		rr = httptest.NewRecorder()
+		defer func() { recovered = recover() }()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr, nil
+	}
+
+	checkLog := func(path string) {
+		t.Helper()
+		entry := map[string]any{}
+		if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: log %q: %v", path, logs.String(), err)
+		}
+		if entry["msg"] != "panic: falha" {
+			t.Errorf("%s: logged %q, want %q", path, entry["msg"], "panic: falha")
+		}
+		if stack, _ := entry["stack"].(string); !strings.Contains(stack, "runtime/debug.Stack") {
+			t.Errorf("%s: stack = %q", path, stack)
+		}
+		logs.Reset()
+	}
+
+	rr, recovered := serve("/plain")
+	if recovered != nil {
+		t.Fatalf("/plain: panic %v escaped", recovered)
+	}
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Connection") != "close" {
+		t.Errorf("/plain: status %d, Connection %q", rr.Code, rr.Header().Get("Connection"))
+	}
+	if !strings.Contains(rr.Body.String(), serverErrorMessage) {
+		t.Errorf("/plain: body %q", rr.Body.String())
+	}
+	checkLog("/plain")
+
+	rr, recovered = serve("/sse")
+	if recovered != http.ErrAbortHandler {
+		t.Fatalf("/sse: recovered %v, want %v", recovered, http.ErrAbortHandler)
+	}
+	if rr.Code != http.StatusOK || rr.Body.String() != "data: ok\n\n" {
+		t.Errorf("/sse: status %d, body %q", rr.Code, rr.Body.String())
+	}
+	checkLog("/sse")
+}
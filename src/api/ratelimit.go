@@ -0,0 +1,100 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/ratelimit"
+)
+
+// limiters holds the request budgets of each route group. A nil limiter
+// means the group is not limited.
+type limiters struct {
+	ip      *ratelimit.Limiter
+	auth    *ratelimit.Limiter
+	refresh *ratelimit.Limiter
+	user    *ratelimit.Limiter
+	signin  *ratelimit.Lockout
+}
+
+func newLimiters(cfg config) limiters {
+	if !cfg.limiter.enabled {
+		return limiters{}
+	}
+
+	return limiters{
+		ip:      ratelimit.New(cfg.limiter.ipRPS, cfg.limiter.ipBurst),
+		auth:    ratelimit.New(cfg.limiter.authRPS, cfg.limiter.authBurst),
+		refresh: ratelimit.New(cfg.limiter.refreshRPS, cfg.limiter.refreshBurst),
+		user:    ratelimit.New(cfg.limiter.userRPS, cfg.limiter.userBurst),
+		signin:  ratelimit.NewLockout(cfg.limiter.lockoutThreshold, cfg.limiter.lockoutBase, cfg.limiter.lockoutMax, time.Hour),
+	}
+}
+
+// clientIP returns the address of the client. The configured proxy headers
+// are only trusted when the API runs behind a proxy that sets them; for
+// X-Forwarded-For the last entry is used, the one appended by that proxy.
+func (app application) clientIP(r *http.Request) string {
+	for _, header := range app.config.limiter.trustedHeaders {
+		value := r.Header.Get(header)
+		if value == "" {
+			continue
+		}
+
+		parts := strings.Split(value, ",")
+		ip := strings.TrimSpace(parts[len(parts)-1])
+		if net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// allow takes a token from the bucket of key and reports the budget in the
+// RateLimit-* headers. When no token is left it responds with 429.
+func (app application) allow(w http.ResponseWriter, r *http.Request, l *ratelimit.Limiter, key string) bool {
+	if l == nil {
+		return true
+	}
+
+	result := l.Allow(key)
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+
+	if !result.Allowed {
+		app.rateLimitExceededResponse(w, r, result.RetryAfter)
+		return false
+	}
+	return true
+}
+
+// rateLimitIP limits requests by client IP using the given budget.
+func (app *application) rateLimitIP(l *ratelimit.Limiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.allow(w, r, l, app.clientIP(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signinKey identifies the account targeted by a signin for the lockout, so
+// spreading attempts over several IPs does not help.
+func signinKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/ratelimit"
+)
+
+func TestRefreshHasItsOwnBudget(t *testing.T) {
+	_, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	app.limits = limiters{
+		auth:    ratelimit.New(0, 2),
+		refresh: ratelimit.New(0, 3),
+	}
+	h := app.routes()
+
+	for i := 0; i < 2; i++ {
+		if status, _ := do(t, h, http.MethodPost, "/v1/auth/signin", "", map[string]string{}); status == http.StatusTooManyRequests {
+			t.Fatalf("signin %d was limited", i+1)
+		}
+	}
+	if status, _ := do(t, h, http.MethodPost, "/v1/auth/signin", "", map[string]string{}); status != http.StatusTooManyRequests {
+		t.Fatalf("signin over the budget: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+
+	for i := 0; i < 3; i++ {
+		if status, _ := do(t, h, http.MethodPost, "/v1/auth/refresh", "", map[string]string{}); status == http.StatusTooManyRequests {
+			t.Fatalf("refresh %d was limited by the signin budget", i+1)
+		}
+	}
+	if status, _ := do(t, h, http.MethodPost, "/v1/auth/refresh", "", map[string]string{}); status != http.StatusTooManyRequests {
+		t.Errorf("refresh over its budget: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
+
+func TestSigninLockout(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	app.limits = limiters{signin: ratelimit.NewLockout(2, time.Minute, time.Hour, time.Hour)}
+	userDB(t, app, f, 7, users.RoleUser)
+	h := app.routes()
+
+	signin := func(password string) int {
+		status, _ := do(t, h, http.MethodPost, "/v1/auth/signin", "", map[string]string{"email": "user@example.com", "password": password})
+		return status
+	}
+
+	if status := signin(testPassword); status != http.StatusOK {
+		t.Fatalf("signin: status = %d", status)
+	}
+
+	// A success clears the attempt it counted, so two failures are needed
+	// after it to lock the account.
+	for i := 0; i < 2; i++ {
+		if status := signin("senha-errada-000"); status != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want %d", i+1, status, http.StatusUnauthorized)
+		}
+	}
+
+	if status := signin(testPassword); status != http.StatusTooManyRequests {
+		t.Errorf("signin while locked: status = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
+
+func TestSigninServerErrorIsNotAFailure(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	app.limits = limiters{signin: ratelimit.NewLockout(2, time.Minute, time.Hour, time.Hour)}
+
+	hash, err := app.models.Users.Hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := true
+	f.on(`FROM users\s+WHERE email = \$1`, func([]driver.Value) fakeResult {
+		if down {
+			return fakeResult{Err: errors.New("connection refused")}
+		}
+		return row(int64(7), "user@example.com", hash, "Fulano", string(users.RoleUser), "", time.Now(), nil, false)
+	})
+	userDB(t, app, f, 7, users.RoleUser)
+	h := app.routes()
+
+	signin := func(password string) int {
+		status, _ := do(t, h, http.MethodPost, "/v1/auth/signin", "", map[string]string{"email": "user@example.com", "password": password})
+		return status
+	}
+
+	for i := 0; i < 3; i++ {
+		if status := signin(testPassword); status != http.StatusInternalServerError {
+			t.Fatalf("signin %d with the database down: status = %d, want %d", i+1, status, http.StatusInternalServerError)
+		}
+	}
+
+	down = false
+	if status := signin("senha-errada-000"); status != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := signin(testPassword); status != http.StatusOK {
+		t.Errorf("signin after the database is back: status = %d, want %d", status, http.StatusOK)
+	}
+
+	b := strings.Builder{}
+	if err := app.metrics.registry.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := `chatbot_failed_logins_total{reason="invalid_credentials"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf("metrics do not contain %s:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) createRoleRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	payload := users.ChangeRoleDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.Get(userID)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if user.Role == payload.Role {
+		app.badRequestResponse(w, r, errors.New("O usuário já possui o papel solicitado"))
+		return
+	}
+
+	req := &users.RoleChangeRequest{
+		UserID:        userID,
+		PreviousRole:  user.Role,
+		RequestedRole: payload.Role,
+		Reason:        payload.Reason,
+	}
+
+	err = app.models.RoleRequests.Insert(req)
+	if err != nil {
+		if errors.Is(err, users.ErrRoleRequestDuplicate) {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, req, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listOwnRoleRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	requests, err := app.models.RoleRequests.List(userID, "")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"requests": requests}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listRoleRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	status := users.RoleRequestStatus(r.URL.Query().Get("status"))
+
+	v := validator.New()
+	v.Check(validator.In(string(status), "", string(users.RoleRequestPending), string(users.RoleRequestApproved), string(users.RoleRequestRejected)),
+		"status", "deve ser uma das opções (pending|approved|rejected)")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	requests, err := app.models.RoleRequests.List(0, status)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"requests": requests}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) approveRoleRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewRoleRequest(w, r, true)
+}
+
+func (app *application) rejectRoleRequestHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewRoleRequest(w, r, false)
+}
+
+func (app *application) reviewRoleRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	adminID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payload := users.ReviewRoleRequestDTO{}
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &payload)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	req, err := app.models.RoleRequests.Review(id, adminID, approve, payload.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrRoleRequestNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, users.ErrRoleRequestNotPending):
+			app.badRequestResponse(w, r, err)
+		case errors.Is(err, users.ErrRoleRequestStale):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if approve {
+		err = app.models.Revocations.RevokeAllSessions(req.UserID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.disconnect(req.UserID)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, req, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) changeUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("userID").(int64)
+	if !ok {
+		app.unauthorizedResponse(w, r, "ID do usuário não foi encontrado no contexto da requisição")
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payload := users.ChangeRoleDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	v.Check(id != adminID, "id", "administradores não podem alterar o próprio papel")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	req, err := app.models.RoleRequests.ChangeRole(id, adminID, payload.Role, payload.Reason)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Revocations.RevokeAllSessions(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.disconnect(id)
+
+	err = app.writeJSON(w, http.StatusOK, req, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
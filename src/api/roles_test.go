@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+func TestApproveRoleRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   users.UserRole
+		status int
+	}{
+		{"role unchanged", users.RoleUser, http.StatusOK},
+		{"role changed since the request", users.RoleAdmin, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+			allowSessions(f)
+
+			created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			f.on(`FROM role_change_requests WHERE id = \$1 FOR UPDATE`, func([]driver.Value) fakeResult {
+				return row(int64(3), int64(9), "user", "collaborator", "Atendo clientes", "pending", nil, "", created, nil)
+			})
+			f.on(`UPDATE users SET role = \$1 WHERE id = \$2 AND role = \$3`, func(args []driver.Value) fakeResult {
+				if fmt.Sprint(args[2]) != string(tt.role) {
+					return fakeResult{RowsAffected: 0}
+				}
+				return fakeResult{RowsAffected: 1}
+			})
+			f.on(`UPDATE role_change_requests`, func(args []driver.Value) fakeResult {
+				return row(int64(3), int64(9), "user", "collaborator", "Atendo clientes", "approved", int64(1), "", created, time.Now())
+			})
+			f.on(`SET sessions_revoked_at`, func([]driver.Value) fakeResult {
+				return fakeResult{RowsAffected: 1}
+			})
+
+			status, response := do(t, app.routes(), http.MethodPost, "/v1/admin/role-requests/3/approve", bearer(t, app, 1, users.RoleAdmin), nil)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+			}
+			if status == http.StatusOK && response["previous_role"] != "user" {
+				t.Errorf("previous_role = %v, want user", response["previous_role"])
+			}
+			if commits, _ := f.transactions(); status == http.StatusConflict && commits != 0 {
+				t.Error("a stale approval was committed")
+			}
+		})
+	}
+}
@@ -4,21 +4,111 @@ import (
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
 )
 
-func (app *application) routes() *httprouter.Router {
+func (app *application) routes() http.Handler {
 	router := httprouter.New()
 
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	router.PanicHandler = app.panicResponse
+
+	readProfile := app.requirePermission(users.PermProfileRead)
+	writeProfile := app.requirePermission(users.PermProfileWrite)
+	admin := app.requireRole(users.RoleAdmin)
+	readConversations := app.requirePermission(users.PermConversationsRead)
+	writeConversations := app.requirePermission(users.PermConversationsWrite)
+	manageConversations := app.requirePermission(users.PermConversationsManage)
+	readUsers := app.requirePermission(users.PermUsersRead)
+	manageUsers := app.requirePermission(users.PermUsersManage)
 
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthCheckHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/auth/signup", app.createUserHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/auth/signin", app.signinUserHandler)
-	router.Handle(http.MethodGet, "/v1/user", app.jwtMiddleware(http.HandlerFunc(app.getUserHandler)))
-	router.Handle(http.MethodPut, "/v1/user", app.jwtMiddleware(http.HandlerFunc(app.updateUserHandler)))
-	router.Handle(http.MethodPatch, "/v1/user", app.jwtMiddleware(http.HandlerFunc(app.updateUserHandler)))
-	router.Handle(http.MethodDelete, "/v1/user", app.jwtMiddleware(http.HandlerFunc(app.deleteUserHandler)))
-
-	return router
+	router.Handler(http.MethodPost, "/v1/auth/signup", app.rateLimitIP(app.limits.auth, http.HandlerFunc(app.createUserHandler)))
+	router.Handler(http.MethodPost, "/v1/auth/signin", app.rateLimitIP(app.limits.auth, http.HandlerFunc(app.signinUserHandler)))
+	router.Handler(http.MethodPost, "/v1/auth/refresh", app.rateLimitIP(app.limits.refresh, http.HandlerFunc(app.refreshTokenHandler)))
+	router.Handle(http.MethodPost, "/v1/auth/logout", app.jwtMiddleware(http.HandlerFunc(app.logoutHandler)))
+	router.Handle(http.MethodPost, "/v1/auth/logout-all", app.jwtMiddleware(http.HandlerFunc(app.logoutAllHandler)))
+	router.Handle(http.MethodGet, "/v1/user", app.jwtMiddleware(readProfile(http.HandlerFunc(app.getUserHandler))))
+	router.Handle(http.MethodPut, "/v1/user", app.jwtMiddleware(writeProfile(http.HandlerFunc(app.updateUserHandler))))
+	router.Handle(http.MethodPatch, "/v1/user", app.jwtMiddleware(writeProfile(http.HandlerFunc(app.updateUserHandler))))
+	router.Handle(http.MethodDelete, "/v1/user", app.jwtMiddleware(writeProfile(http.HandlerFunc(app.deleteUserHandler))))
+	router.Handle(http.MethodGet, "/v1/user/role-requests", app.jwtMiddleware(readProfile(http.HandlerFunc(app.listOwnRoleRequestsHandler))))
+	router.Handle(http.MethodPost, "/v1/user/role-requests", app.jwtMiddleware(writeProfile(http.HandlerFunc(app.createRoleRequestHandler))))
+
+	router.Handle(http.MethodGet, "/v1/conversations", app.jwtMiddleware(readConversations(http.HandlerFunc(app.listConversationsHandler))))
+	router.Handle(http.MethodPost, "/v1/conversations", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.createConversationHandler))))
+	router.Handle(http.MethodGet, "/v1/conversations/:id", app.jwtMiddleware(readConversations(http.HandlerFunc(app.showConversationHandler))))
+	router.Handle(http.MethodGet, "/v1/conversations/:id/messages", app.jwtMiddleware(readConversations(http.HandlerFunc(app.listMessagesHandler))))
+	router.Handle(http.MethodPost, "/v1/conversations/:id/messages", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.createMessageHandler))))
+	router.Handle(http.MethodGet, "/v1/conversations/:id/stream", app.queryTokenMiddleware(readConversations(http.HandlerFunc(app.streamConversationHandler))))
+	router.Handle(http.MethodGet, "/v1/conversations/:id/ws", app.queryTokenMiddleware(writeConversations(http.HandlerFunc(app.websocketHandler))))
+	router.Handle(http.MethodPost, "/v1/conversations/:id/handoff", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.requestHandoffHandler))))
+
+	router.Handle(http.MethodGet, "/v1/handoffs", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.listHandoffsHandler))))
+	router.Handle(http.MethodGet, "/v1/handoffs/:id", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.showHandoffHandler))))
+	router.Handle(http.MethodPost, "/v1/handoffs/:id/claim", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.claimHandoffHandler))))
+	router.Handle(http.MethodPost, "/v1/handoffs/:id/release", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.releaseHandoffHandler))))
+	router.Handle(http.MethodPost, "/v1/handoffs/:id/transfer", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.transferHandoffHandler))))
+	router.Handle(http.MethodPost, "/v1/handoffs/:id/close", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.closeHandoffHandler))))
+
+	router.Handle(http.MethodGet, "/v1/collaborator/status", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.getAvailabilityHandler))))
+	router.Handle(http.MethodPut, "/v1/collaborator/status", app.jwtMiddleware(manageConversations(http.HandlerFunc(app.updateAvailabilityHandler))))
+
+	router.Handle(http.MethodGet, "/v1/faq/search", app.jwtMiddleware(readConversations(http.HandlerFunc(app.searchArticlesHandler))))
+	router.Handle(http.MethodGet, "/v1/faq/articles", app.jwtMiddleware(readConversations(http.HandlerFunc(app.listArticlesHandler))))
+	router.Handle(http.MethodGet, "/v1/faq/articles/:id", app.jwtMiddleware(readConversations(http.HandlerFunc(app.showArticleHandler))))
+
+	router.Handle(http.MethodPost, "/v1/flows/:id/start", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.startFlowHandler))))
+	router.Handle(http.MethodGet, "/v1/flow-session", app.jwtMiddleware(readConversations(http.HandlerFunc(app.showFlowSessionHandler))))
+	router.Handle(http.MethodPost, "/v1/flow-session/input", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.flowInputHandler))))
+	router.Handle(http.MethodDelete, "/v1/flow-session", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.cancelFlowSessionHandler))))
+
+	router.Handle(http.MethodPost, "/v1/bot/reply", app.jwtMiddleware(writeConversations(http.HandlerFunc(app.botReplyHandler))))
+
+	router.Handle(http.MethodGet, "/v1/admin/users", app.jwtMiddleware(readUsers(http.HandlerFunc(app.listUsersHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/users/:id", app.jwtMiddleware(readUsers(http.HandlerFunc(app.showUserHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/users/:id/disable", app.jwtMiddleware(manageUsers(http.HandlerFunc(app.disableUserHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/users/:id/enable", app.jwtMiddleware(manageUsers(http.HandlerFunc(app.enableUserHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/users/:id/force-password-reset", app.jwtMiddleware(manageUsers(http.HandlerFunc(app.forcePasswordResetHandler))))
+	router.Handle(http.MethodPut, "/v1/admin/users/:id/role", app.jwtMiddleware(manageUsers(http.HandlerFunc(app.changeUserRoleHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/faq/import", app.jwtMiddleware(admin(http.HandlerFunc(app.importArticlesHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/faq/export", app.jwtMiddleware(admin(http.HandlerFunc(app.exportArticlesHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/faq/articles", app.jwtMiddleware(admin(http.HandlerFunc(app.createArticleHandler))))
+	router.Handle(http.MethodPut, "/v1/admin/faq/articles/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.updateArticleHandler))))
+	router.Handle(http.MethodDelete, "/v1/admin/faq/articles/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.deleteArticleHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/flows", app.jwtMiddleware(admin(http.HandlerFunc(app.listFlowsHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/flows", app.jwtMiddleware(admin(http.HandlerFunc(app.createFlowHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/flows/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.showFlowHandler))))
+	router.Handle(http.MethodPut, "/v1/admin/flows/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.updateFlowHandler))))
+	router.Handle(http.MethodDelete, "/v1/admin/flows/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.deleteFlowHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/intents", app.jwtMiddleware(admin(http.HandlerFunc(app.listIntentsHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/intents", app.jwtMiddleware(admin(http.HandlerFunc(app.createIntentHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/intents/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.showIntentHandler))))
+	router.Handle(http.MethodPut, "/v1/admin/intents/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.updateIntentHandler))))
+	router.Handle(http.MethodDelete, "/v1/admin/intents/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.deleteIntentHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/nlu/train", app.jwtMiddleware(admin(http.HandlerFunc(app.trainIntentsHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/nlu/test", app.jwtMiddleware(admin(http.HandlerFunc(app.testIntentHandler))))
+
+	router.Handle(http.MethodGet, "/v1/admin/bot/versions", app.jwtMiddleware(admin(http.HandlerFunc(app.listVersionsHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/bot/versions", app.jwtMiddleware(admin(http.HandlerFunc(app.createVersionHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/bot/versions/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.showVersionHandler))))
+	router.Handle(http.MethodPut, "/v1/admin/bot/versions/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.updateVersionHandler))))
+	router.Handle(http.MethodDelete, "/v1/admin/bot/versions/:id", app.jwtMiddleware(admin(http.HandlerFunc(app.deleteVersionHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/bot/versions/:id/validate", app.jwtMiddleware(admin(http.HandlerFunc(app.validateVersionHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/bot/versions/:id/publish", app.jwtMiddleware(admin(http.HandlerFunc(app.publishVersionHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/bot/active", app.jwtMiddleware(admin(http.HandlerFunc(app.showActiveVersionHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/bot/rollback", app.jwtMiddleware(admin(http.HandlerFunc(app.rollbackVersionHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/bot/audit", app.jwtMiddleware(admin(http.HandlerFunc(app.versionAuditHandler))))
+	router.Handle(http.MethodGet, "/v1/admin/role-requests", app.jwtMiddleware(admin(http.HandlerFunc(app.listRoleRequestsHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/role-requests/:id/approve", app.jwtMiddleware(admin(http.HandlerFunc(app.approveRoleRequestHandler))))
+	router.Handle(http.MethodPost, "/v1/admin/role-requests/:id/reject", app.jwtMiddleware(admin(http.HandlerFunc(app.rejectRoleRequestHandler))))
+
+	// /metrics is normally served by serve on its own port, out of reach of
+	// API clients. Without -metrics-addr it is served here to admins only.
+	if app.config.metrics.enabled && app.config.metrics.addr == "" {
+		router.Handle(http.MethodGet, "/metrics", app.jwtMiddleware(admin(app.metrics.registry.Handler())))
+	}
+
+	return app.requestID(app.accessLog(app.instrument(router, app.recoverPanic(app.rateLimitIP(app.limits.ip, router)))))
 }
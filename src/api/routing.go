@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/collaborators"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/routing"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+// autoAssign routes a queued handoff to a collaborator chosen by the
+// configured strategy, skipping exclude (the collaborator who just released
+// it). It reports whether the handoff was assigned; a handoff nobody can take
+// simply stays in the queue.
+func (app *application) autoAssign(h *handoffs.Handoff, exclude int64) (bool, error) {
+	if app.routing == nil || h.Status != handoffs.StatusQueued {
+		return false, nil
+	}
+
+	candidates, err := app.models.Availability.Candidates()
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		eligible := make([]routing.Candidate, 0, len(candidates))
+		for _, c := range candidates {
+			if c.UserID != exclude {
+				eligible = append(eligible, c)
+			}
+		}
+
+		picked, ok := app.routing.Pick(eligible, routing.Request{Tags: h.Tags})
+		if !ok {
+			return false, nil
+		}
+
+		assigned, err := app.models.Handoffs.Assign(h.ID, picked.UserID, app.routing.Name())
+		switch {
+		case errors.Is(err, handoffs.ErrCapacityReached):
+			// The candidate list was stale: try the others.
+			exclude = picked.UserID
+			candidates = eligible
+			continue
+		case errors.Is(err, handoffs.ErrInvalidTransition):
+			// Someone claimed or closed it in the meantime.
+			return false, nil
+		case err != nil:
+			return false, err
+		}
+
+		agent, err := app.models.Users.Get(picked.UserID)
+		if err != nil {
+			return true, err
+		}
+
+		return true, app.notifyHandoff(assigned, conversations.EventAgentJoined, agent, agent.Name+" entrou na conversa.")
+	}
+}
+
+// drainQueue assigns queued handoffs, oldest first, until the queue is empty
+// or no online collaborator has capacity left.
+func (app *application) drainQueue() error {
+	if app.routing == nil {
+		return nil
+	}
+
+	queued, err := app.models.Handoffs.List(handoffs.StatusQueued, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range queued {
+		assigned, err := app.autoAssign(h, 0)
+		if err != nil {
+			return err
+		}
+		if !assigned {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (app *application) getAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	availability, err := app.models.Availability.Get(app.viewer(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, availability, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	payload := collaborators.UpdateAvailabilityDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	availability, err := app.models.Availability.Update(app.viewer(r).ID, payload)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if availability.Status == collaborators.StatusOnline {
+		if err := app.drainQueue(); err != nil {
+			app.logError(r, err)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, availability, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
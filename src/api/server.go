@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve runs the API until SIGINT or SIGTERM is received. In-flight requests
+// are then given the grace period to finish, WebSocket and SSE clients are
+// disconnected, and background goroutines are waited for within what is
+// left of it.
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.port),
+		Handler:      app.routes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	srv.RegisterOnShutdown(app.hub.Shutdown)
+	srv.RegisterOnShutdown(app.broker.Close)
+
+	var metricsSrv *http.Server
+	if app.config.metrics.enabled && app.config.metrics.addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", app.metrics.registry.Handler())
+
+		metricsSrv = &http.Server{
+			Addr:         app.config.metrics.addr,
+			Handler:      mux,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			app.logger.Info("Servindo métricas", "addr", metricsSrv.Addr)
+			err := metricsSrv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.Error(err.Error(), "addr", metricsSrv.Addr)
+			}
+		}()
+	}
+
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Info("Sinal recebido, encerrando servidor", "signal", s.String())
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.shutdownTimeout)
+		defer cancel()
+
+		if metricsSrv != nil {
+			metricsSrv.Shutdown(ctx)
+		}
+
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		app.logger.Info("Aguardando tarefas em segundo plano")
+
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			shutdownError <- nil
+		case <-ctx.Done():
+			shutdownError <- errors.New("Tarefas em segundo plano não terminaram dentro do tempo limite de encerramento")
+		}
+	}()
+
+	app.logger.Info("Inicializando servidor", "env", app.config.env, "addr", srv.Addr)
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Servidor encerrado")
+	return nil
+}
+
+// background runs fn in a goroutine that the shutdown waits for. A panic in
+// fn is logged instead of bringing the whole server down.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn()
+	}()
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const streamReplayBatch = 500
+
+func (app *application) streamConversationHandler(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			app.badRequestResponse(w, r, errors.New("Last-Event-ID inválido"))
+			return
+		}
+		lastID = id
+	}
+
+	// The server-wide WriteTimeout would cut every stream after a fixed
+	// time, so each write sets its own deadline instead.
+	rc := http.NewResponseController(w)
+	write := func(s string) error {
+		err := rc.SetWriteDeadline(time.Now().Add(app.config.sse.writeTimeout))
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write([]byte(s)); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := rc.SetWriteDeadline(time.Now().Add(app.config.sse.writeTimeout)); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Subscribe before replaying so no event published in between is lost.
+	sub := app.broker.Subscribe(conversation.ID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := write("retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	for {
+		events, err := app.models.Events.ListAfter(conversation.ID, lastID, streamReplayBatch)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+
+		for _, event := range events {
+			msg := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+			if err := write(msg); err != nil {
+				return
+			}
+			lastID = event.ID
+		}
+
+		if len(events) < streamReplayBatch {
+			break
+		}
+	}
+
+	heartbeat := time.NewTicker(app.config.sse.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+
+			msg := fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+			if err := write(msg); err != nil {
+				return
+			}
+			lastID = event.ID
+
+		case <-heartbeat.C:
+			if err := write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/events"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
+	"github.com/pedro-git-projects/chatbot-back/internal/realtime"
+)
+
+// fakeResult is what the fake database answers to a statement: rows for
+// queries, the number of affected rows for everything else.
+type fakeResult struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	Err          error
+}
+
+type fakeHandler struct {
+	pattern *regexp.Regexp
+	handle  func(args []driver.Value) fakeResult
+}
+
+// fakeDB is a database/sql driver answering statements with the first
+// registered handler whose pattern matches the SQL. Handlers see the
+// arguments, so a test can both answer and assert on what was written.
+// Statements no handler matches fail the test.
+type fakeDB struct {
+	t         *testing.T
+	mu        sync.Mutex
+	handlers  []fakeHandler
+	commits   int
+	rollbacks int
+}
+
+func newFakeDB(t *testing.T) (*fakeDB, *sql.DB) {
+	f := &fakeDB{t: t}
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+func (f *fakeDB) on(pattern string, handle func(args []driver.Value) fakeResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers = append(f.handlers, fakeHandler{regexp.MustCompile(pattern), handle})
+}
+
+func (f *fakeDB) run(query string, args []driver.NamedValue) fakeResult {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	handlers := append([]fakeHandler{}, f.handlers...)
+	f.mu.Unlock()
+
+	for _, h := range handlers {
+		if h.pattern.MatchString(query) {
+			return h.handle(values)
+		}
+	}
+
+	f.t.Errorf("unexpected query: %s", strings.Join(strings.Fields(query), " "))
+	return fakeResult{Err: fmt.Errorf("unexpected query")}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakeDB does not prepare statements")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.db}, nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &fakeRows{columns: result.Columns, rows: result.Rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+// CheckNamedValue accepts every argument as is, including pq.Array values.
+func (c fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		nv.Value = v
+		return err
+	}
+	return nil
+}
+
+// fakeTx counts how transactions end. Statements run in a transaction are
+// answered like any other, as they are sent.
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+// transactions returns how many transactions were committed and rolled back.
+func (f *fakeDB) transactions() (commits, rollbacks int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits, f.rollbacks
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+func row(values ...driver.Value) fakeResult {
+	columns := make([]string, len(values))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+	}
+	return fakeResult{Columns: columns, Rows: [][]driver.Value{values}}
+}
+
+func noRows(columns int) fakeResult {
+	return fakeResult{Columns: make([]string, columns)}
+}
+
+func newTestApp(t *testing.T, db *sql.DB) *application {
+	t.Helper()
+
+	hasher, err := password.New("bcrypt", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &application{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:  data.NewModels(db, hasher),
+		nlu:     &nlu.Store{},
+		wg:      &sync.WaitGroup{},
+		metrics: newMetrics(nil),
+	}
+	app.broker = events.NewBroker(16)
+	app.hub = realtime.NewHub(app.broker, app)
+	app.config.jwtSecret = "segredo-de-teste"
+	app.config.auth.accessTokenTTL = time.Minute
+	app.config.auth.refreshTokenTTL = time.Hour
+	app.config.llm.timeout = 5 * time.Second
+	return app
+}
+
+// allowSessions answers the queries the authentication middleware makes for
+// a user whose sessions were never revoked.
+func allowSessions(f *fakeDB) {
+	f.on(`FROM revoked_tokens`, func([]driver.Value) fakeResult {
+		return fakeResult{Columns: []string{"jti", "expires_at"}}
+	})
+	f.on(`disabled_at IS NOT NULL`, func([]driver.Value) fakeResult {
+		return row(time.Now().Add(-time.Hour), false, false)
+	})
+}
+
+func bearer(t *testing.T, app *application, userID int64, role users.UserRole) string {
+	t.Helper()
+
+	token, err := app.generateJWT(userID, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// do sends a request through the full router and decodes the JSON answer.
+func do(t *testing.T, h http.Handler, method, path, authorization string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = strings.NewReader(string(js))
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	response := map[string]any{}
+	if rr.Body.Len() > 0 {
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, path, rr.Body.String(), err)
+		}
+	}
+	return rr.Code, response
+}
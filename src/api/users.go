@@ -3,8 +3,10 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/password"
 	"github.com/pedro-git-projects/chatbot-back/internal/validator"
 )
 
@@ -22,7 +24,7 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		Password: payload.Password,
 		Name:     payload.Name,
 		ImageURL: payload.ImageURL,
-		Role:     payload.Role,
+		Role:     users.RoleUser,
 	}
 
 	v := validator.New()
@@ -41,17 +43,14 @@ func (app *application) createUserHandler(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	app.metrics.signups.Inc()
 
-	token, err := app.generateJWT(user.ID, user.Role)
+	response, err := app.issueTokens(user)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-
-	response := map[string]any{
-		"user":  user,
-		"token": token,
-	}
+	response["user"] = users.NewUserResponse(user, user.Self())
 
 	err = app.writeJSON(w, http.StatusCreated, response, nil)
 	if err != nil {
@@ -75,22 +74,54 @@ func (app *application) signinUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// The attempt counts as a failure until it succeeds.
+	key := signinKey(payload.Email)
+	var lock time.Duration
+	if app.limits.signin != nil {
+		var wait time.Duration
+		wait, lock = app.limits.signin.Attempt(key)
+		if wait > 0 {
+			app.metrics.failedLogins.Inc("locked")
+			app.rateLimitExceededResponse(w, r, wait)
+			return
+		}
+	}
+
 	user, err := app.models.Users.Authenticate(payload.Email, payload.Password)
 	if err != nil {
-		app.unauthorizedResponse(w, r, "Credenciais inválidas")
+		if errors.Is(err, users.ErrUserNotFound) || errors.Is(err, password.ErrMismatch) {
+			app.metrics.failedLogins.Inc("invalid_credentials")
+			if lock > 0 {
+				app.logger.WarnContext(r.Context(), "Login bloqueado após falhas seguidas", "email", key, "lock", lock.String())
+			}
+			app.unauthorizedResponse(w, r, "Credenciais inválidas")
+			return
+		}
+
+		// Only wrong credentials count towards the lockout.
+		if app.limits.signin != nil {
+			app.limits.signin.Undo(key)
+		}
+		if errors.Is(err, users.ErrUserDisabled) {
+			app.metrics.failedLogins.Inc("disabled")
+			app.unauthorizedResponse(w, r, err.Error())
+			return
+		}
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	token, err := app.generateJWT(user.ID, user.Role)
+	if app.limits.signin != nil {
+		app.limits.signin.Reset(key)
+	}
+	app.metrics.signins.Inc()
+
+	response, err := app.issueTokens(user)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-
-	response := map[string]interface{}{
-		"user":  user,
-		"token": token,
-	}
+	response["user"] = users.NewUserResponse(user, user.Self())
 
 	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -110,7 +141,8 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := app.writeJSON(w, http.StatusOK, user, nil); err != nil {
+	response := users.NewUserResponse(user, app.viewer(r))
+	if err := app.writeJSON(w, http.StatusOK, response, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
@@ -135,7 +167,6 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		Password: payload.Password,
 		Name:     payload.Name,
 		ImageURL: payload.ImageURL,
-		Role:     payload.Role,
 	}
 
 	v := validator.New()
@@ -155,7 +186,18 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, updatedUser, nil)
+	if payload.Password != "" {
+		err = app.models.RefreshTokens.RevokeAllForUser(userID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.models.Revocations.InvalidateUser(userID)
+		app.disconnect(userID)
+	}
+
+	response := users.NewUserResponse(updatedUser, app.viewer(r))
+	err = app.writeJSON(w, http.StatusCreated, response, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -171,7 +213,10 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 	err := app.models.Users.Delete(userID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
+	app.models.Revocations.InvalidateUser(userID)
+	app.disconnect(userID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
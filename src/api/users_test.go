@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+)
+
+// credentialKeys must never appear in a response, at any depth.
+var credentialKeys = []string{"password", "password_hash", "password_changed_at", "sessions_revoked_at", "token_hash"}
+
+func assertNoCredentials(t *testing.T, response any, secrets ...string) {
+	t.Helper()
+
+	switch value := response.(type) {
+	case map[string]any:
+		for key, child := range value {
+			for _, forbidden := range credentialKeys {
+				if strings.EqualFold(key, forbidden) {
+					t.Errorf("response contains %q", key)
+				}
+			}
+			assertNoCredentials(t, child, secrets...)
+		}
+	case []any:
+		for _, child := range value {
+			assertNoCredentials(t, child, secrets...)
+		}
+	case string:
+		for _, secret := range secrets {
+			if value == secret {
+				t.Errorf("response contains the secret %q", secret)
+			}
+		}
+	}
+}
+
+// assertUserFields checks that exactly the expected fields of a user are
+// present.
+func assertUserFields(t *testing.T, user any, present []string, absent []string) {
+	t.Helper()
+
+	fields, ok := user.(map[string]any)
+	if !ok {
+		t.Fatalf("user is %T, not an object", user)
+	}
+	for _, key := range present {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("user is missing %q: %v", key, fields)
+		}
+	}
+	for _, key := range absent {
+		if _, ok := fields[key]; ok {
+			t.Errorf("user should not have %q: %v", key, fields)
+		}
+	}
+}
+
+const testPassword = "senha-secreta-123"
+
+// userDB answers the user queries of the handlers with a single stored user.
+func userDB(t *testing.T, app *application, f *fakeDB, id int64, role users.UserRole) string {
+	t.Helper()
+
+	hash, err := app.models.Users.Hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	disabled := created.Add(time.Hour)
+	email := fmt.Sprintf("%s@example.com", role)
+
+	allowSessions(f)
+	f.on(`INSERT INTO users`, func([]driver.Value) fakeResult {
+		return row(id, created)
+	})
+	f.on(`INSERT INTO refresh_tokens`, func([]driver.Value) fakeResult {
+		return row(int64(1), created)
+	})
+	f.on(`SELECT id, email, password, name, role`, func([]driver.Value) fakeResult {
+		return row(id, email, hash, "Fulano", string(role), "", created, nil, false)
+	})
+	f.on(`UPDATE users\s+SET email`, func(args []driver.Value) fakeResult {
+		return row(id, args[0], args[2], string(role), "", created, nil, false)
+	})
+	f.on(`UPDATE refresh_tokens`, func([]driver.Value) fakeResult {
+		return fakeResult{RowsAffected: 1}
+	})
+	f.on(`count\(\*\) OVER\(\)`, func([]driver.Value) fakeResult {
+		return fakeResult{
+			Columns: make([]string, 9),
+			Rows: [][]driver.Value{
+				{int64(2), id, email, "Fulano", string(role), "", created, nil, false},
+				{int64(2), id + 1, "outro@example.com", "Outro", "user", "", created, disabled, true},
+			},
+		}
+	})
+	f.on(`SELECT id, email, name, role`, func([]driver.Value) fakeResult {
+		return row(id, email, "Fulano", string(role), "", created, nil, false)
+	})
+	return hash
+}
+
+func TestUserHandlersDoNotLeakCredentials(t *testing.T) {
+	ownFields := []string{"id", "name", "email", "role", "created_at"}
+
+	for _, role := range []users.UserRole{users.RoleUser, users.RoleCollaborator, users.RoleAdmin} {
+		t.Run(string(role), func(t *testing.T) {
+			f, db := newFakeDB(t)
+			app := newTestApp(t, db)
+			hash := userDB(t, app, f, 7, role)
+			h := app.routes()
+			auth := bearer(t, app, 7, role)
+
+			tests := []struct {
+				name   string
+				method string
+				path   string
+				auth   string
+				body   any
+				status int
+				user   func(map[string]any) any
+			}{
+				{
+					name:   "signup",
+					method: http.MethodPost,
+					path:   "/v1/auth/signup",
+					body:   map[string]string{"email": "novo@example.com", "password": testPassword, "name": "Fulano"},
+					status: http.StatusCreated,
+					user:   func(r map[string]any) any { return r["user"] },
+				},
+				{
+					name:   "signin",
+					method: http.MethodPost,
+					path:   "/v1/auth/signin",
+					body:   map[string]string{"email": string(role) + "@example.com", "password": testPassword},
+					status: http.StatusOK,
+					user:   func(r map[string]any) any { return r["user"] },
+				},
+				{
+					name:   "get user",
+					method: http.MethodGet,
+					path:   "/v1/user",
+					auth:   auth,
+					status: http.StatusOK,
+					user:   func(r map[string]any) any { return r },
+				},
+				{
+					name:   "update user",
+					method: http.MethodPut,
+					path:   "/v1/user",
+					auth:   auth,
+					body:   map[string]string{"name": "Beltrano", "password": "outra-senha-456"},
+					status: http.StatusCreated,
+					user:   func(r map[string]any) any { return r },
+				},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					status, response := do(t, h, tt.method, tt.path, tt.auth, tt.body)
+					if status != tt.status {
+						t.Fatalf("status = %d, want %d: %v", status, tt.status, response)
+					}
+
+					assertNoCredentials(t, response, hash, testPassword, "outra-senha-456")
+					assertUserFields(t, tt.user(response), ownFields, []string{"disabled_at"})
+				})
+			}
+		})
+	}
+}
+
+func TestAdminUserListingDoesNotLeakCredentials(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	hash := userDB(t, app, f, 1, users.RoleAdmin)
+	h := app.routes()
+
+	status, response := do(t, h, http.MethodGet, "/v1/admin/users", bearer(t, app, 1, users.RoleAdmin), nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, response)
+	}
+	assertNoCredentials(t, response, hash)
+
+	list, ok := response["users"].([]any)
+	if !ok || len(list) != 2 {
+		t.Fatalf("users = %v", response["users"])
+	}
+	assertUserFields(t, list[1], []string{"email", "role", "disabled_at", "must_reset_password"}, nil)
+
+	if status, _ := do(t, h, http.MethodGet, "/v1/admin/users", bearer(t, app, 1, users.RoleUser), nil); status != http.StatusForbidden {
+		t.Errorf("user listing users: status = %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/versions"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) versionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, versions.ErrVersionNotFound), errors.Is(err, versions.ErrNoActiveVersion):
+		app.errorResponse(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, versions.ErrNotEditable), errors.Is(err, versions.ErrNotValidated),
+		errors.Is(err, versions.ErrAlreadyActive), errors.Is(err, versions.ErrNothingToRestore),
+		errors.Is(err, versions.ErrEditConflict):
+		app.errorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.models.Versions.List()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"versions": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.models.Versions.Get(id)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createVersionHandler snapshots the current flows and intents as a new
+// draft.
+func (app *application) createVersionHandler(w http.ResponseWriter, r *http.Request) {
+	payload := versions.VersionDTO{}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	version, err := app.models.Versions.CreateDraft(app.viewer(r).ID, payload.Notes)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateVersionHandler refreshes an unpublished version with the current
+// flows and intents, which sends it back to draft.
+func (app *application) updateVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	payload := versions.VersionDTO{}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	payload.Validate(v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	version, err := app.models.Versions.UpdateDraft(id, app.viewer(r).ID, payload.Notes)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Versions.Delete(id)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateVersionHandler checks every flow of the version and trains its
+// intent classifier. The outcome is stored on the version either way; only
+// validated versions can be published.
+func (app *application) validateVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.models.Versions.Get(id)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+	if !version.Editable() {
+		app.versionErrorResponse(w, r, versions.ErrNotEditable)
+		return
+	}
+
+	v := validator.New()
+	version.Snapshot.Validate(v)
+
+	var model *nlu.Classifier
+	if v.Valid() && len(version.Snapshot.Intents) > 0 {
+		model, err = nlu.Train(version.Snapshot.Examples())
+		if err != nil {
+			v.AddError("intents", err.Error())
+		} else {
+			model.Responses = version.Snapshot.Responses()
+		}
+	}
+
+	err = app.models.Versions.SaveValidation(version, app.viewer(r).ID, v.Errors, model)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// activateVersion puts the classifier of a newly active version in use. A
+// version without intents has no classifier, so none stays in use: the
+// intents it does not define must not keep being recognized.
+func (app *application) activateVersion(version *versions.Version) {
+	app.nlu.Set(version.Model)
+}
+
+// publishVersionHandler makes the version the one new conversations start
+// on. Previously published versions can be published again to go back to
+// them.
+func (app *application) publishVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.models.Versions.Publish(id, app.viewer(r).ID)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+	app.activateVersion(version)
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rollbackVersionHandler goes back to the version that was active before
+// the current one was published.
+func (app *application) rollbackVersionHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := app.models.Versions.Rollback(app.viewer(r).ID)
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+	app.activateVersion(version)
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showActiveVersionHandler(w http.ResponseWriter, r *http.Request) {
+	version, err := app.models.Versions.Active()
+	if err != nil {
+		app.versionErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, version, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// versionAuditHandler lists who created, validated, published and rolled
+// back each version.
+func (app *application) versionAuditHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	versionID := app.readInt(qs, "version_id", 0, v)
+	limit := app.readInt(qs, "limit", 50, v)
+	v.Check(versionID >= 0, "version_id", "deve ser positivo")
+	v.Check(limit > 0 && limit <= 500, "limit", "deve estar entre 1 e 500")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, err := app.models.Versions.Events(int64(versionID), limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, map[string]any{"events": events}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/nlu"
+)
+
+func trainedClassifier(t *testing.T, responses map[string][]string) *nlu.Classifier {
+	t.Helper()
+
+	c, err := nlu.Train([]nlu.Example{
+		{Intent: "cancelar", Text: "quero cancelar meu pedido"},
+		{Intent: "cancelar", Text: "cancelamento da compra"},
+		{Intent: "senha", Text: "esqueci minha senha"},
+		{Intent: "senha", Text: "trocar a senha"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Responses = responses
+	return c
+}
+
+// versionDB answers the queries that publish version 2 or roll back to it,
+// storing model as its classifier.
+func versionDB(t *testing.T, f *fakeDB, model *nlu.Classifier) {
+	t.Helper()
+
+	var stored driver.Value
+	if model != nil {
+		js, err := json.Marshal(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = js
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	allowSessions(f)
+	f.on(`SELECT e.previous_version_id`, func([]driver.Value) fakeResult {
+		return row(int64(2))
+	})
+	f.on(`SELECT status, active FROM bot_versions`, func([]driver.Value) fakeResult {
+		return row("published", false)
+	})
+	f.on(`SET active = FALSE`, func([]driver.Value) fakeResult {
+		return row(int64(3))
+	})
+	f.on(`SET status = 'published'`, func([]driver.Value) fakeResult {
+		return row(int64(2), int64(2), "published", "", []byte(`{"flows":[],"intents":[]}`), stored, nil, true,
+			int64(1), created, created, created, int64(1), created)
+	})
+	f.on(`INSERT INTO bot_version_events`, func([]driver.Value) fakeResult {
+		return fakeResult{RowsAffected: 1}
+	})
+}
+
+func TestActivatingVersionReplacesClassifier(t *testing.T) {
+	paths := map[string]string{
+		"publish":  "/v1/admin/bot/versions/2/publish",
+		"rollback": "/v1/admin/bot/rollback",
+	}
+
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			t.Run("with a model", func(t *testing.T) {
+				f, db := newFakeDB(t)
+				app := newTestApp(t, db)
+				app.nlu.Set(trainedClassifier(t, nil))
+				versionDB(t, f, trainedClassifier(t, map[string][]string{"senha": {"Use o link de recuperação."}}))
+
+				status, response := do(t, app.routes(), http.MethodPost, path, bearer(t, app, 1, users.RoleAdmin), nil)
+				if status != http.StatusOK {
+					t.Fatalf("status = %d: %v", status, response)
+				}
+
+				c, err := app.nlu.Classifier()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(c.Responses["senha"]) != 1 {
+					t.Errorf("classifier in use is not the version's: responses = %v", c.Responses)
+				}
+			})
+
+			t.Run("without a model", func(t *testing.T) {
+				f, db := newFakeDB(t)
+				app := newTestApp(t, db)
+				app.nlu.Set(trainedClassifier(t, nil))
+				versionDB(t, f, nil)
+
+				status, response := do(t, app.routes(), http.MethodPost, path, bearer(t, app, 1, users.RoleAdmin), nil)
+				if status != http.StatusOK {
+					t.Fatalf("status = %d: %v", status, response)
+				}
+
+				if _, err := app.nlu.Classifier(); !errors.Is(err, nlu.ErrNotTrained) {
+					t.Errorf("previous classifier is still in use: err = %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestBotReplyAnswersFromIntent(t *testing.T) {
+	app, f := newBotTestApp(t)
+	app.config.bot.intents = true
+	app.config.bot.intentScore = 0.7
+	app.bot = app.intentResponder(app.bot)
+	store := newConversationDB(f, 42, 7, conversations.StatusOpen)
+
+	answer := "Use o link de recuperação na tela de entrada."
+	app.nlu.Set(trainedClassifier(t, map[string][]string{"senha": {answer}}))
+
+	tests := []struct {
+		message string
+		reply   string
+	}{
+		{"esqueci a senha", answer},
+		{"quero cancelar", "quero cancelar"},
+		{"bom dia", "bom dia"},
+	}
+
+	for _, tt := range tests {
+		body := map[string]any{"conversationId": "42", "message": tt.message}
+		status, response := do(t, app.routes(), http.MethodPost, "/v1/bot/reply", bearer(t, app, 7, users.RoleUser), body)
+		if status != http.StatusCreated {
+			t.Fatalf("%q: status = %d: %v", tt.message, status, response)
+		}
+
+		replies, _ := response["replies"].([]any)
+		if len(replies) != 1 || replies[0].(map[string]any)["content"] != tt.reply {
+			t.Errorf("%q: replies = %v, want %q", tt.message, replies, tt.reply)
+		}
+	}
+
+	if len(store.contents) != 2*len(tests) {
+		t.Errorf("stored %d messages, want %d", len(store.contents), 2*len(tests))
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/conversations"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/handoffs"
+	"github.com/pedro-git-projects/chatbot-back/internal/realtime"
+	"github.com/pedro-git-projects/chatbot-back/internal/validator"
+)
+
+func (app *application) upgrader() *websocket.Upgrader {
+	upgrader := &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+
+	if len(app.config.ws.allowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return validator.In(r.Header.Get("Origin"), app.config.ws.allowedOrigins...)
+		}
+	}
+
+	return upgrader
+}
+
+// websocketHandler is tracked by the application WaitGroup because hijacked
+// connections are not waited for by http.Server.Shutdown. The counter is
+// taken before the upgrade, while the request still holds the shutdown.
+func (app *application) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	app.wg.Add(1)
+	defer app.wg.Done()
+
+	conversation, ok := app.conversationFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(*Claims)
+	if !ok {
+		app.unauthorizedResponse(w, r, "Alegações do token não foram encontradas no contexto da requisição")
+		return
+	}
+
+	var resume *realtime.Resume
+	if lastEventID := r.URL.Query().Get("last_event_id"); lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			app.badRequestResponse(w, r, errors.New("last_event_id inválido"))
+			return
+		}
+		resume = &realtime.Resume{
+			LastEventID: id,
+			List: func(afterID int64) ([]*conversations.Event, error) {
+				return app.models.Events.ListAfter(conversation.ID, afterID, streamReplayBatch)
+			},
+		}
+	}
+
+	conn, err := app.upgrader().Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		app.logError(r, err)
+		return
+	}
+
+	// The connection outlives the token check made on the upgrade request,
+	// so the hub checks the session again while it is open.
+	valid := func() bool {
+		session, reason, err := app.sessionEnded(claims)
+		if err != nil {
+			app.logger.Error(err.Error(), "conversation_id", conversation.ID, "user_id", claims.UserID)
+			return true
+		}
+		return reason == "" && !session.MustResetPassword
+	}
+
+	app.hub.Serve(conn, conversation.ID, claims.UserID, resume, valid)
+}
+
+// disconnect closes the user's WebSocket connections once their sessions
+// have been revoked here. Other instances notice on their next session check.
+func (app *application) disconnect(userID int64) {
+	app.hub.CloseUser(userID)
+}
+
+// HandleInbound implements realtime.Handler.
+func (app *application) HandleInbound(c *realtime.Client, msg realtime.Inbound) {
+	switch msg.Type {
+	case realtime.TypeMessageSend:
+		app.handleSocketMessage(c, msg)
+
+	case realtime.TypeTyping:
+		typing := msg.Typing
+		c.Broadcast(realtime.Outbound{Type: realtime.TypeTyping, UserID: c.UserID, Typing: &typing})
+
+	case realtime.TypeRead:
+		if msg.MessageID < 1 {
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "messageId é obrigatório"})
+			return
+		}
+
+		_, err := app.models.Messages.Get(c.ConversationID, msg.MessageID)
+		if err != nil {
+			if errors.Is(err, conversations.ErrMessageNotFound) {
+				c.Send(realtime.Outbound{Type: realtime.TypeError, Error: err.Error()})
+				return
+			}
+			app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Não foi possível registrar a leitura"})
+			return
+		}
+
+		data := map[string]string{
+			"user_id":    strconv.FormatInt(c.UserID, 10),
+			"message_id": strconv.FormatInt(msg.MessageID, 10),
+		}
+		err = app.publish(c.ConversationID, conversations.EventRead, data)
+		if err != nil {
+			app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Não foi possível registrar a leitura"})
+		}
+
+	default:
+		c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Tipo de mensagem desconhecido: " + msg.Type})
+	}
+}
+
+func (app *application) handleSocketMessage(c *realtime.Client, msg realtime.Inbound) {
+	v := validator.New()
+	conversations.CreateMessageDTO{Content: msg.Content}.Validate(v)
+	if !v.Valid() {
+		c.Send(realtime.Outbound{Type: realtime.TypeError, Error: v.Errors["content"]})
+		return
+	}
+
+	conversation, err := app.models.Conversations.Get(c.ConversationID)
+	if err != nil {
+		app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+		c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Conversa não encontrada"})
+		return
+	}
+
+	if conversation.Status == conversations.StatusClosed {
+		c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "A conversa está encerrada"})
+		return
+	}
+
+	userID := c.UserID
+	sender := conversations.SenderUser
+	if conversation.UserID != userID {
+		sender = conversations.SenderAgent
+
+		user, err := app.models.Users.Get(userID)
+		if err != nil {
+			app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Não foi possível enviar a mensagem"})
+			return
+		}
+
+		allowed, err := app.canSendAsAgent(conversation, user.Self())
+		if err != nil {
+			app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Não foi possível enviar a mensagem"})
+			return
+		}
+		if !allowed {
+			c.Send(realtime.Outbound{Type: realtime.TypeError, Error: handoffs.ErrNotAssignedToAgent.Error()})
+			return
+		}
+	}
+
+	message := &conversations.Message{
+		ConversationID: conversation.ID,
+		Sender:         sender,
+		UserID:         &userID,
+		Content:        msg.Content,
+	}
+
+	err = app.models.Messages.Insert(message)
+	if err != nil {
+		app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+		c.Send(realtime.Outbound{Type: realtime.TypeError, Error: "Não foi possível enviar a mensagem"})
+		return
+	}
+
+	// The message reaches every connection, including this one, through the
+	// conversation's event stream.
+	err = app.publish(conversation.ID, conversations.EventMessage, message)
+	if err != nil {
+		app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+		return
+	}
+
+	if sender != conversations.SenderUser {
+		return
+	}
+
+	app.background(func() {
+		_, err := app.reply(context.Background(), conversation, message)
+		if err != nil {
+			app.logger.Error(err.Error(), "conversation_id", c.ConversationID)
+		}
+	})
+}
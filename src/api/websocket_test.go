@@ -0,0 +1,215 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pedro-git-projects/chatbot-back/internal/data/users"
+	"github.com/pedro-git-projects/chatbot-back/internal/realtime"
+)
+
+// dialConversation opens a WebSocket connection to the conversation as the
+// user.
+func dialConversation(t *testing.T, server *httptest.Server, app *application, conversationID string, userID int64) *websocket.Conn {
+	t.Helper()
+	return dialConversationQuery(t, server, app, conversationID, userID, "")
+}
+
+// dialConversationQuery is dialConversation with extra query parameters.
+func dialConversationQuery(t *testing.T, server *httptest.Server, app *application, conversationID string, userID int64, query string) *websocket.Conn {
+	t.Helper()
+
+	token := strings.TrimPrefix(bearer(t, app, userID, users.RoleUser), "Bearer ")
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/conversations/" + conversationID + "/ws?access_token=" + token + query
+
+	conn, response, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		status := 0
+		if response != nil {
+			status = response.StatusCode
+		}
+		t.Fatalf("dial: %v (status %d)", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestReadReceiptRequiresMessageOfConversation(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	store := newConversationDB(f, 42, 7, "open")
+	f.on(`FROM messages\s+WHERE conversation_id = \$1 AND id = \$2`, func(args []driver.Value) fakeResult {
+		if args[1] != int64(5) {
+			return noRows(6)
+		}
+		return row(int64(5), int64(42), "user", int64(7), "oi", time.Now())
+	})
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	conn := dialConversation(t, server, app, "42", 7)
+
+	// A message of another conversation is refused.
+	if err := conn.WriteJSON(realtime.Inbound{Type: realtime.TypeRead, MessageID: 99}); err != nil {
+		t.Fatal(err)
+	}
+	frame := realtime.Outbound{}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != realtime.TypeError || frame.Error != "Mensagem não encontrada" {
+		t.Fatalf("frame = %+v, want a not found error", frame)
+	}
+
+	// A message of the conversation is acknowledged through its event.
+	if err := conn.WriteJSON(realtime.Inbound{Type: realtime.TypeRead, MessageID: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "read" || !strings.Contains(string(frame.Data), `"message_id":"5"`) {
+		t.Fatalf("frame = %+v, want the read event of message 5", frame)
+	}
+	if len(store.events) != 1 {
+		t.Errorf("events = %v, want only the valid read receipt", store.events)
+	}
+}
+
+func TestLogoutAllClosesWebSockets(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	newConversationDB(f, 42, 7, "open")
+	f.on(`UPDATE refresh_tokens`, func([]driver.Value) fakeResult { return fakeResult{RowsAffected: 1} })
+	f.on(`SET sessions_revoked_at`, func([]driver.Value) fakeResult { return fakeResult{RowsAffected: 1} })
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	conn := dialConversation(t, server, app, "42", 7)
+
+	status, response := do(t, app.routes(), http.MethodPost, "/v1/auth/logout-all", bearer(t, app, 7, users.RoleUser), nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("logout-all status = %d: %v", status, response)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("read error = %v, want the connection closed with %d", err, websocket.ClosePolicyViolation)
+	}
+}
+
+// eventLog keeps the conversation_events table of a test in memory.
+type eventLog struct {
+	mu     sync.Mutex
+	events [][]driver.Value
+
+	// listed is called after each read of the log, before it is answered.
+	listed func()
+}
+
+func newEventLog(f *fakeDB, conversationID int64, types ...string) *eventLog {
+	l := &eventLog{}
+	for _, typ := range types {
+		l.append(conversationID, typ, []byte(`{}`))
+	}
+
+	f.on(`INSERT INTO conversation_events`, func(args []driver.Value) fakeResult {
+		id := l.append(conversationID, fmt.Sprint(args[1]), args[2].([]byte))
+		return row(id, time.Now())
+	})
+	f.on(`FROM conversation_events\s+WHERE conversation_id = \$1 AND id > \$2`, func(args []driver.Value) fakeResult {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		var after int64
+		var limit int
+		fmt.Sscan(fmt.Sprint(args[1], " ", args[2]), &after, &limit)
+
+		result := fakeResult{Columns: make([]string, 5)}
+		for _, event := range l.events {
+			if event[0].(int64) > after && len(result.Rows) < limit {
+				result.Rows = append(result.Rows, event)
+			}
+		}
+		listed := l.listed
+		l.mu.Unlock()
+
+		if listed != nil {
+			listed()
+		}
+		l.mu.Lock()
+		return result
+	})
+	return l
+}
+
+func (l *eventLog) append(conversationID int64, typ string, data []byte) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := int64(len(l.events) + 1)
+	l.events = append(l.events, []driver.Value{id, conversationID, typ, data, time.Now()})
+	return id
+}
+
+func TestWebSocketResumesFromLastEventID(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	log := newEventLog(f, 42, "message", "message", "read")
+	newConversationDB(f, 42, 7, "open")
+
+	// Event 4 is published while the first batch is read: the room delivers
+	// it live and the next batch replays it, but the client gets it once.
+	var once sync.Once
+	log.listed = func() {
+		once.Do(func() {
+			if err := app.publish(42, "message", map[string]string{}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+	conn := dialConversationQuery(t, server, app, "42", 7, "&last_event_id=1")
+
+	if err := app.publish(42, "read", map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, want := range []int64{2, 3, 4, 5} {
+		frame := realtime.Outbound{}
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("waiting for event %d: %v", want, err)
+		}
+		if frame.ID != want {
+			t.Fatalf("frame = %+v, want event %d", frame, want)
+		}
+	}
+}
+
+func TestWebSocketRefusesInvalidLastEventID(t *testing.T) {
+	f, db := newFakeDB(t)
+	app := newTestApp(t, db)
+	newConversationDB(f, 42, 7, "open")
+
+	server := httptest.NewServer(app.routes())
+	defer server.Close()
+
+	token := strings.TrimPrefix(bearer(t, app, 7, users.RoleUser), "Bearer ")
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/conversations/42/ws?access_token=" + token + "&last_event_id=abc"
+	_, response, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || response == nil || response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial: err = %v, response = %v, want %d", err, response, http.StatusBadRequest)
+	}
+}